@@ -0,0 +1,790 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtp
+
+import (
+	"fmt"
+	"time"
+
+	v1ie "github.com/wmnsk/go-gtp/gtpv1/ie"
+	v2ie "github.com/wmnsk/go-gtp/gtpv2/ie"
+)
+
+func buildV1IE(path string, v interface{}) (*v1ie.IE, error) {
+	o, err := newObject(path, v)
+	if err != nil {
+		return nil, err
+	}
+	o.ignore("field", "length", "error")
+
+	itype, err := o.typ(func(t uint8) string { return v1ie.New(t, nil).Name() })
+	if err != nil {
+		return nil, err
+	}
+
+	var i *v1ie.IE
+	if v, ok := o.get("value"); ok {
+		i, err = buildV1Value(o.pathTo("value"), itype, v)
+	} else {
+		var payload []byte
+		payload, err = o.optHex("payload")
+		i = v1ie.New(itype, payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if i == nil {
+		return nil, fmt.Errorf("%s: invalid value", o.pathTo("value"))
+	}
+	return i, o.done()
+}
+
+func buildV1Value(path string, itype uint8, v interface{}) (*v1ie.IE, error) {
+	switch itype {
+	case v1ie.Cause, v1ie.MAPCause, v1ie.RANAPCause, v1ie.Recovery, v1ie.SelectionMode,
+		v1ie.NSAPI, v1ie.RATType, v1ie.APNRestriction, v1ie.CommonFlags:
+		n, err := toUint(path, v, 8)
+		if err != nil {
+			return nil, err
+		}
+		switch itype {
+		case v1ie.Cause:
+			return v1ie.NewCause(uint8(n)), nil
+		case v1ie.MAPCause:
+			return v1ie.NewMAPCause(uint8(n)), nil
+		case v1ie.RANAPCause:
+			return v1ie.NewRANAPCause(uint8(n)), nil
+		case v1ie.Recovery:
+			return v1ie.NewRecovery(uint8(n)), nil
+		case v1ie.SelectionMode:
+			return v1ie.NewSelectionMode(uint8(n)), nil
+		case v1ie.NSAPI:
+			return v1ie.NewNSAPI(uint8(n)), nil
+		case v1ie.RATType:
+			return v1ie.NewRATType(uint8(n)), nil
+		case v1ie.APNRestriction:
+			return v1ie.NewAPNRestriction(uint8(n)), nil
+		default:
+			return v1ie.New(itype, []byte{uint8(n)}), nil
+		}
+	case v1ie.PacketTMSI, v1ie.PTMSISignature, v1ie.ChargingID,
+		v1ie.TEIDDataI, v1ie.TEIDCPlane, v1ie.TEIDDataII:
+		n, err := toUint(path, v, 32)
+		if err != nil {
+			return nil, err
+		}
+		switch itype {
+		case v1ie.PacketTMSI:
+			return v1ie.NewPacketTMSI(uint32(n)), nil
+		case v1ie.PTMSISignature:
+			return v1ie.NewPTMSISignature(uint32(n)), nil
+		case v1ie.ChargingID:
+			return v1ie.NewChargingID(uint32(n)), nil
+		case v1ie.TEIDDataI:
+			return v1ie.NewTEIDDataI(uint32(n)), nil
+		case v1ie.TEIDCPlane:
+			return v1ie.NewTEIDCPlane(uint32(n)), nil
+		default:
+			return v1ie.NewTEIDDataII(uint32(n)), nil
+		}
+	case v1ie.IMSI, v1ie.AccessPointName, v1ie.MSISDN, v1ie.IMEISV, v1ie.GSNAddress:
+		s, err := toString(path, v)
+		if err != nil {
+			return nil, err
+		}
+		switch itype {
+		case v1ie.IMSI:
+			return v1ie.NewIMSI(s), nil
+		case v1ie.AccessPointName:
+			return v1ie.NewAccessPointName(s), nil
+		case v1ie.MSISDN:
+			return v1ie.NewMSISDN(s), nil
+		case v1ie.IMEISV:
+			return v1ie.NewIMEISV(s), nil
+		default:
+			return v1ie.NewGSNAddress(s), nil
+		}
+	case v1ie.ReorderingRequired, v1ie.MSValidated, v1ie.TeardownInd:
+		b, err := toBool(path, v)
+		if err != nil {
+			return nil, err
+		}
+		switch itype {
+		case v1ie.ReorderingRequired:
+			return v1ie.NewReorderingRequired(b), nil
+		case v1ie.MSValidated:
+			return v1ie.NewMSValidated(b), nil
+		default:
+			return v1ie.NewTeardownInd(b), nil
+		}
+	case v1ie.RouteingAreaIdentity, v1ie.UserLocationInformation:
+		return buildV1Location(path, itype, v)
+	case v1ie.EndUserAddress:
+		o, err := newObject(path, v)
+		if err != nil {
+			return nil, err
+		}
+		o.ignore("PDPTypeOrganization", "PDPTypeNumber")
+		ip, err := o.optString("IPAddress")
+		if err != nil {
+			return nil, err
+		}
+		if ip == "" {
+			return v1ie.NewEndUserAddressPPP(), o.done()
+		}
+		return v1ie.NewEndUserAddress(ip), o.done()
+	case v1ie.MSTimeZone:
+		tz, ds, err := buildTimeZone(path, v)
+		if err != nil {
+			return nil, err
+		}
+		return v1ie.NewMSTimeZone(tz, ds), nil
+	case v1ie.ULITimestamp:
+		s, err := toString(path, v)
+		if err != nil {
+			return nil, err
+		}
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return v1ie.NewULITimestamp(ts), nil
+	case v1ie.ExtensionHeaderTypeList:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: got %T, want list", path, v)
+		}
+		types := make([]uint8, len(list))
+		for i, v := range list {
+			n, err := toUint(fmt.Sprintf("%s[%d]", path, i), v, 8)
+			if err != nil {
+				return nil, err
+			}
+			types[i] = uint8(n)
+		}
+		return v1ie.NewExtensionHeaderTypeList(types...), nil
+	default:
+		return nil, fmt.Errorf("%s: cannot build %s from value, use payload instead", path, v1ie.New(itype, nil).Name())
+	}
+}
+
+func buildV1Location(path string, itype uint8, v interface{}) (*v1ie.IE, error) {
+	o, err := newObject(path, v)
+	if err != nil {
+		return nil, err
+	}
+	mcc, err := o.string("MCC")
+	if err != nil {
+		return nil, err
+	}
+	mnc, err := o.string("MNC")
+	if err != nil {
+		return nil, err
+	}
+	lac, err := o.uint("LAC", 16)
+	if err != nil {
+		return nil, err
+	}
+
+	var i *v1ie.IE
+	switch _, hasRAC := o.get("RAC"); {
+	case itype == v1ie.RouteingAreaIdentity, hasRAC:
+		rac, err := o.uint("RAC", 8)
+		if err != nil {
+			return nil, err
+		}
+		if itype == v1ie.RouteingAreaIdentity {
+			i = v1ie.NewRouteingAreaIdentity(mcc, mnc, uint16(lac), uint8(rac))
+		} else {
+			i = v1ie.NewUserLocationInformationWithRAI(mcc, mnc, uint16(lac), uint8(rac))
+		}
+	default:
+		if _, ok := o.get("SAC"); ok {
+			sac, err := o.uint("SAC", 16)
+			if err != nil {
+				return nil, err
+			}
+			i = v1ie.NewUserLocationInformationWithSAI(mcc, mnc, uint16(lac), uint16(sac))
+			break
+		}
+		ci, err := o.uint("CI", 16)
+		if err != nil {
+			return nil, err
+		}
+		i = v1ie.NewUserLocationInformationWithCGI(mcc, mnc, uint16(lac), uint16(ci))
+	}
+	return i, o.done()
+}
+
+func buildV2IE(path string, v interface{}) (*v2ie.IE, error) {
+	o, err := newObject(path, v)
+	if err != nil {
+		return nil, err
+	}
+	o.ignore("field", "length", "error")
+
+	itype, err := o.typ(func(t uint8) string { return v2ie.New(t, 0, nil).Name() })
+	if err != nil {
+		return nil, err
+	}
+	instance, err := o.optUint("instance", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	var i *v2ie.IE
+	if v, ok := o.get("value"); ok {
+		i, err = buildV2Value(o.pathTo("value"), itype, v)
+		if err != nil {
+			return nil, err
+		}
+		if i == nil {
+			return nil, fmt.Errorf("%s: invalid value", o.pathTo("value"))
+		}
+	} else if _, ok := o.get("childIEs"); ok {
+		var children []*v2ie.IE
+		if err := o.each("childIEs", func(path string, v interface{}) error {
+			child, err := buildV2IE(path, v)
+			if err != nil {
+				return err
+			}
+			children = append(children, child)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		i = v2ie.New(itype, 0, nil)
+		if !i.IsGrouped() {
+			return nil, fmt.Errorf("%s: %s is not a grouped IE", o.pathTo("childIEs"), i.Name())
+		}
+		i.Add(children...)
+	} else {
+		payload, err := o.optHex("payload")
+		if err != nil {
+			return nil, err
+		}
+		i = v2ie.New(itype, 0, payload)
+	}
+
+	i.SetInstance(uint8(instance))
+	return i, o.done()
+}
+
+func buildV2Value(path string, itype uint8, v interface{}) (*v2ie.IE, error) {
+	switch itype {
+	case v2ie.Recovery, v2ie.EPSBearerID, v2ie.RATType, v2ie.PDNType, v2ie.SelectionMode,
+		v2ie.APNRestriction, v2ie.ProcedureTransactionID, v2ie.HopCounter, v2ie.NodeType,
+		v2ie.DetachType, v2ie.ServiceIndicator, v2ie.RFSPIndex, v2ie.CSGMembershipIndication,
+		v2ie.NodeFeatures, v2ie.AllocationRetensionPriority, v2ie.BearerFlags, v2ie.MBMSFlags:
+		n, err := toUint(path, v, 8)
+		if err != nil {
+			return nil, err
+		}
+		return buildV2Uint8(itype, uint8(n)), nil
+	case v2ie.ChargingID, v2ie.PacketTMSI, v2ie.PTMSISignature, v2ie.TMSI, v2ie.CSGID:
+		n, err := toUint(path, v, 32)
+		if err != nil {
+			return nil, err
+		}
+		switch itype {
+		case v2ie.ChargingID:
+			return v2ie.NewChargingID(uint32(n)), nil
+		case v2ie.PacketTMSI:
+			return v2ie.NewPacketTMSI(uint32(n)), nil
+		case v2ie.PTMSISignature:
+			return v2ie.NewPTMSISignature(uint32(n)), nil
+		case v2ie.TMSI:
+			return v2ie.NewTMSI(uint32(n)), nil
+		default:
+			return v2ie.NewCSGID(uint32(n)), nil
+		}
+	case v2ie.ChargingCharacteristics, v2ie.PortNumber, v2ie.IntegerNumber:
+		n, err := toUint(path, v, 16)
+		if err != nil {
+			return nil, err
+		}
+		switch itype {
+		case v2ie.ChargingCharacteristics:
+			return v2ie.NewChargingCharacteristics(uint16(n)), nil
+		case v2ie.PortNumber:
+			return v2ie.NewPortNumber(uint16(n)), nil
+		default:
+			return v2ie.NewIntegerNumber(uint16(n)), nil
+		}
+	case v2ie.IMSI, v2ie.MSISDN, v2ie.MobileEquipmentIdentity, v2ie.AccessPointName,
+		v2ie.FullyQualifiedDomainName, v2ie.LocalDistinguishedName, v2ie.IPAddress:
+		s, err := toString(path, v)
+		if err != nil {
+			return nil, err
+		}
+		switch itype {
+		case v2ie.IMSI:
+			return v2ie.NewIMSI(s), nil
+		case v2ie.MSISDN:
+			return v2ie.NewMSISDN(s), nil
+		case v2ie.MobileEquipmentIdentity:
+			return v2ie.NewMobileEquipmentIdentity(s), nil
+		case v2ie.AccessPointName:
+			return v2ie.NewAccessPointName(s), nil
+		case v2ie.FullyQualifiedDomainName:
+			return v2ie.NewFullyQualifiedDomainName(s), nil
+		case v2ie.LocalDistinguishedName:
+			return v2ie.NewLocalDistinguishedName(s), nil
+		default:
+			return v2ie.NewIPAddress(s), nil
+		}
+	case v2ie.ServingNetwork, v2ie.PLMNID:
+		mcc, mnc, err := buildPLMN(path, v)
+		if err != nil {
+			return nil, err
+		}
+		if itype == v2ie.ServingNetwork {
+			return v2ie.NewServingNetwork(mcc, mnc), nil
+		}
+		return v2ie.NewPLMNID(mcc, mnc), nil
+	case v2ie.DelayValue, v2ie.EPCTimer:
+		s, err := toString(path, v)
+		if err != nil {
+			return nil, err
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if itype == v2ie.DelayValue {
+			return v2ie.NewDelayValue(d), nil
+		}
+		return v2ie.NewEPCTimer(d), nil
+	case v2ie.UETimeZone:
+		tz, ds, err := buildTimeZone(path, v)
+		if err != nil {
+			return nil, err
+		}
+		return v2ie.NewUETimeZone(tz, ds), nil
+	case v2ie.Cause:
+		return buildV2Cause(path, v)
+	case v2ie.Indication:
+		return buildV2Indication(path, v)
+	case v2ie.UserLocationInformation:
+		return buildV2ULI(path, v)
+	}
+
+	o, err := newObject(path, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var i *v2ie.IE
+	switch itype {
+	case v2ie.AggregateMaximumBitRate:
+		i, err = buildV2AMBR(o)
+	case v2ie.BearerQoS, v2ie.FlowQoS:
+		i, err = buildV2QoS(o, itype)
+	case v2ie.FullyQualifiedTEID:
+		i, err = buildV2FTEID(o)
+	case v2ie.PDNAddressAllocation:
+		i, err = buildV2PAA(o)
+	case v2ie.GUTI:
+		i, err = buildV2GUTI(o)
+	default:
+		return nil, fmt.Errorf("%s: cannot build %s from value, use payload instead", path, v2ie.New(itype, 0, nil).Name())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i, o.done()
+}
+
+func buildV2Uint8(itype, n uint8) *v2ie.IE {
+	switch itype {
+	case v2ie.Recovery:
+		return v2ie.NewRecovery(n)
+	case v2ie.EPSBearerID:
+		return v2ie.NewEPSBearerID(n)
+	case v2ie.RATType:
+		return v2ie.NewRATType(n)
+	case v2ie.PDNType:
+		return v2ie.NewPDNType(n)
+	case v2ie.SelectionMode:
+		return v2ie.NewSelectionMode(n)
+	case v2ie.APNRestriction:
+		return v2ie.NewAPNRestriction(n)
+	case v2ie.ProcedureTransactionID:
+		return v2ie.NewProcedureTransactionID(n)
+	case v2ie.HopCounter:
+		return v2ie.NewHopCounter(n)
+	case v2ie.NodeType:
+		return v2ie.NewNodeType(n)
+	case v2ie.DetachType:
+		return v2ie.NewDetachType(n)
+	case v2ie.ServiceIndicator:
+		return v2ie.NewServiceIndicator(n)
+	case v2ie.RFSPIndex:
+		return v2ie.NewRFSPIndex(n)
+	case v2ie.CSGMembershipIndication:
+		return v2ie.NewCSGMembershipIndication(n)
+	case v2ie.NodeFeatures:
+		return v2ie.NewNodeFeatures(n)
+	case v2ie.AllocationRetensionPriority:
+		return v2ie.NewAllocationRetensionPriority(n>>6&0x01, n>>2&0x0f, n&0x01)
+	case v2ie.BearerFlags:
+		return v2ie.NewBearerFlags(n>>3&0x01, n>>2&0x01, n>>1&0x01, n&0x01)
+	default:
+		return v2ie.NewMBMSFlags(n>>1&0x01, n&0x01)
+	}
+}
+
+func buildV2Cause(path string, v interface{}) (*v2ie.IE, error) {
+	if _, err := newObject(path, v); err != nil {
+		n, err := toUint(path, v, 8)
+		if err != nil {
+			return nil, err
+		}
+		return v2ie.NewCause(uint8(n), 0, 0, 0, nil), nil
+	}
+
+	o, _ := newObject(path, v)
+	cause, err := o.uint("Cause", 8)
+	if err != nil {
+		return nil, err
+	}
+	var flags [3]uint8
+	for n, key := range []string{"PCE", "BCE", "CS"} {
+		b, err := o.optBool(key)
+		if err != nil {
+			return nil, err
+		}
+		if b {
+			flags[n] = 1
+		}
+	}
+
+	var offending *v2ie.IE
+	if oo, err := o.object("OffendingIE"); err != nil {
+		return nil, err
+	} else if oo != nil {
+		// Name is ignored as it is only informative in the rendered Cause.
+		oo.ignore("Name")
+		itype, err := oo.uint("Type", 8)
+		if err != nil {
+			return nil, err
+		}
+		instance, err := oo.optUint("Instance", 4)
+		if err != nil {
+			return nil, err
+		}
+		if err := oo.done(); err != nil {
+			return nil, err
+		}
+		offending = v2ie.New(uint8(itype), uint8(instance), nil)
+	}
+
+	return v2ie.NewCause(uint8(cause), flags[0], flags[1], flags[2], offending), o.done()
+}
+
+// indicationBits is the octet and bit of each Indication flag, derived from the
+// flags that (*ie.IE).Value decodes.
+var indicationBits = func() map[string][2]int {
+	m := map[string][2]int{}
+	for n := 0; n < 9*8; n++ {
+		octs := make([]uint8, n/8+1)
+		octs[n/8] = 1 << (n % 8)
+
+		v, err := v2ie.NewIndicationFromOctets(octs...).Value()
+		if err != nil {
+			continue
+		}
+		for _, name := range v.([]string) {
+			m[name] = [2]int{n / 8, n % 8}
+		}
+	}
+	return m
+}()
+
+func buildV2Indication(path string, v interface{}) (*v2ie.IE, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: got %T, want list of flags", path, v)
+	}
+
+	var octs []uint8
+	for i, v := range list {
+		name, err := toString(fmt.Sprintf("%s[%d]", path, i), v)
+		if err != nil {
+			return nil, err
+		}
+		bit, ok := indicationBits[name]
+		if !ok {
+			return nil, fmt.Errorf("%s[%d]: unknown flag %q", path, i, name)
+		}
+		for len(octs) <= bit[0] {
+			octs = append(octs, 0)
+		}
+		octs[bit[0]] |= 1 << bit[1]
+	}
+	if len(octs) == 0 {
+		octs = []uint8{0}
+	}
+	return v2ie.NewIndicationFromOctets(octs...), nil
+}
+
+func buildV2ULI(path string, v interface{}) (*v2ie.IE, error) {
+	o, err := newObject(path, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		cgi    *v2ie.CGI
+		sai    *v2ie.SAI
+		rai    *v2ie.RAI
+		tai    *v2ie.TAI
+		ecgi   *v2ie.ECGI
+		lai    *v2ie.LAI
+		menbi  *v2ie.MENBI
+		emenbi *v2ie.EMENBI
+	)
+	for _, key := range []string{"CGI", "SAI", "RAI", "TAI", "ECGI", "LAI", "MENBI", "EMENBI"} {
+		lo, err := o.object(key)
+		if err != nil {
+			return nil, err
+		}
+		if lo == nil {
+			continue
+		}
+		mcc, err := lo.string("MCC")
+		if err != nil {
+			return nil, err
+		}
+		mnc, err := lo.string("MNC")
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case "CGI":
+			lac, ci, err := lo.uint16Pair("LAC", "CI")
+			if err != nil {
+				return nil, err
+			}
+			cgi = v2ie.NewCGI(mcc, mnc, lac, ci)
+		case "SAI":
+			lac, sac, err := lo.uint16Pair("LAC", "SAC")
+			if err != nil {
+				return nil, err
+			}
+			sai = v2ie.NewSAI(mcc, mnc, lac, sac)
+		case "RAI":
+			lac, rac, err := lo.uint16Pair("LAC", "RAC")
+			if err != nil {
+				return nil, err
+			}
+			rai = v2ie.NewRAI(mcc, mnc, lac, rac)
+		case "TAI":
+			tac, err := lo.uint("TAC", 16)
+			if err != nil {
+				return nil, err
+			}
+			tai = v2ie.NewTAI(mcc, mnc, uint16(tac))
+		case "ECGI":
+			eci, err := lo.uint("ECI", 28)
+			if err != nil {
+				return nil, err
+			}
+			ecgi = v2ie.NewECGI(mcc, mnc, uint32(eci))
+		case "LAI":
+			lac, err := lo.uint("LAC", 16)
+			if err != nil {
+				return nil, err
+			}
+			lai = v2ie.NewLAI(mcc, mnc, uint16(lac))
+		case "MENBI":
+			id, err := lo.uint("MENBI", 20)
+			if err != nil {
+				return nil, err
+			}
+			menbi = v2ie.NewMENBI(mcc, mnc, uint32(id))
+		case "EMENBI":
+			id, err := lo.uint("EMENBI", 21)
+			if err != nil {
+				return nil, err
+			}
+			emenbi = v2ie.NewEMENBI(mcc, mnc, uint32(id))
+		}
+		if err := lo.done(); err != nil {
+			return nil, err
+		}
+	}
+
+	return v2ie.NewUserLocationInformationStruct(cgi, sai, rai, tai, ecgi, lai, menbi, emenbi), o.done()
+}
+
+func (o *object) uint16Pair(key1, key2 string) (uint16, uint16, error) {
+	v1, err := o.uint(key1, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	v2, err := o.uint(key2, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint16(v1), uint16(v2), nil
+}
+
+func buildV2AMBR(o *object) (*v2ie.IE, error) {
+	up, err := o.uint("APNAMBRForUplink", 32)
+	if err != nil {
+		return nil, err
+	}
+	down, err := o.uint("APNAMBRForDownlink", 32)
+	if err != nil {
+		return nil, err
+	}
+	return v2ie.NewAggregateMaximumBitRate(uint32(up), uint32(down)), nil
+}
+
+func buildV2QoS(o *object, itype uint8) (*v2ie.IE, error) {
+	qci, err := o.uint("QCI", 8)
+	if err != nil {
+		return nil, err
+	}
+	var rates [4]uint64
+	for n, key := range []string{
+		"MaximumBitRateForUplink", "MaximumBitRateForDownlink",
+		"GuaranteedBitRateForUplink", "GuaranteedBitRateForDownlink",
+	} {
+		if rates[n], err = o.optUint(key, 40); err != nil {
+			return nil, err
+		}
+	}
+
+	if itype == v2ie.FlowQoS {
+		return v2ie.NewFlowQoS(uint8(qci), rates[0], rates[1], rates[2], rates[3]), nil
+	}
+
+	arp, err := o.uint("ARP", 8)
+	if err != nil {
+		return nil, err
+	}
+	return v2ie.NewBearerQoS(
+		uint8(arp>>6&0x01), uint8(arp>>2&0x0f), uint8(arp&0x01), uint8(qci),
+		rates[0], rates[1], rates[2], rates[3],
+	), nil
+}
+
+func buildV2FTEID(o *object) (*v2ie.IE, error) {
+	o.ignore("Flags")
+	it, err := o.uint("InterfaceType", 6)
+	if err != nil {
+		return nil, err
+	}
+	teid, err := o.uint("TEIDGREKey", 32)
+	if err != nil {
+		return nil, err
+	}
+	v4, err := o.optString("IPv4Address")
+	if err != nil {
+		return nil, err
+	}
+	v6, err := o.optString("IPv6Address")
+	if err != nil {
+		return nil, err
+	}
+	return v2ie.NewFullyQualifiedTEID(uint8(it), uint32(teid), v4, v6), nil
+}
+
+func buildV2PAA(o *object) (*v2ie.IE, error) {
+	o.ignore("PDNType")
+	v4, err := o.optString("IPv4Address")
+	if err != nil {
+		return nil, err
+	}
+	v6, err := o.optString("IPv6Address")
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := o.optUint("IPv6PrefixLength", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case v4 != "" && v6 != "":
+		return v2ie.NewPDNAddressAllocationDual(v4, v6, uint8(prefix)), nil
+	case v6 != "":
+		return v2ie.NewPDNAddressAllocationIPv6(v6, uint8(prefix)), nil
+	default:
+		return v2ie.NewPDNAddressAllocation(v4), nil
+	}
+}
+
+func buildV2GUTI(o *object) (*v2ie.IE, error) {
+	mcc, err := o.string("MCC")
+	if err != nil {
+		return nil, err
+	}
+	mnc, err := o.string("MNC")
+	if err != nil {
+		return nil, err
+	}
+	groupID, err := o.uint("MMEGroupID", 16)
+	if err != nil {
+		return nil, err
+	}
+	code, err := o.uint("MMECode", 8)
+	if err != nil {
+		return nil, err
+	}
+	mTMSI, err := o.uint("MTMSI", 32)
+	if err != nil {
+		return nil, err
+	}
+	return v2ie.NewGUTI(mcc, mnc, uint16(groupID), uint8(code), uint32(mTMSI)), nil
+}
+
+// buildPLMN returns MCC and MNC given as object or as string of digits.
+func buildPLMN(path string, v interface{}) (string, string, error) {
+	if s, ok := v.(string); ok {
+		if len(s) != 5 && len(s) != 6 {
+			return "", "", fmt.Errorf("%s: %q is not MCC and MNC", path, s)
+		}
+		return s[:3], s[3:], nil
+	}
+
+	o, err := newObject(path, v)
+	if err != nil {
+		return "", "", err
+	}
+	mcc, err := o.string("MCC")
+	if err != nil {
+		return "", "", err
+	}
+	mnc, err := o.string("MNC")
+	if err != nil {
+		return "", "", err
+	}
+	return mcc, mnc, o.done()
+}
+
+func buildTimeZone(path string, v interface{}) (time.Duration, uint8, error) {
+	o, err := newObject(path, v)
+	if err != nil {
+		return 0, 0, err
+	}
+	s, err := o.string("TimeZone")
+	if err != nil {
+		return 0, 0, err
+	}
+	tz, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", o.pathTo("TimeZone"), err)
+	}
+	ds, err := o.optUint("DaylightSaving", 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	return tz, uint8(ds), o.done()
+}
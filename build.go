@@ -0,0 +1,433 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1ie "github.com/wmnsk/go-gtp/gtpv1/ie"
+	v1msg "github.com/wmnsk/go-gtp/gtpv1/message"
+	v2ie "github.com/wmnsk/go-gtp/gtpv2/ie"
+	v2msg "github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// BuildMessage builds a GTPv1 or GTPv2 Message from the description in JSON.
+//
+// The description is in the same form as the output of RenderJSON, which looks like
+// below. The message type and IE type can be specified either by number("type") or
+// by name("name"), and the IE is built from "value" with the constructor of its type,
+// from "childIEs" if it is grouped, or from the hex-encoded "payload" otherwise.
+// The numbers can also be given in string, e.g., "0xdeadbeef".
+//
+//	{
+//	  "version": 2,
+//	  "name": "Create Session Request",
+//	  "teid": 0,
+//	  "sequence": 1,
+//	  "ies": [
+//	    {"name": "IMSI", "value": "123451234567890"},
+//	    {"name": "FullyQualifiedTEID", "instance": 1, "value": {
+//	      "InterfaceType": 7, "TEIDGREKey": "0xdeadbeef", "IPv4Address": "127.0.0.1"
+//	    }},
+//	    {"name": "BearerContext", "childIEs": [
+//	      {"name": "EPSBearerID", "value": 5}
+//	    ]},
+//	    {"type": 250, "payload": "0102"}
+//	  ]
+//	}
+//
+// The unknown fields in the description are reported as error with the path to it.
+func BuildMessage(b []byte) (Message, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return BuildMessageFromValue(v)
+}
+
+// BuildMessageFromValue builds a Message from the description already decoded into
+// maps, slices and scalar values, e.g., by the YAML decoder of your choice.
+// See BuildMessage for the form of the description.
+func BuildMessageFromValue(v interface{}) (Message, error) {
+	o, err := newObject("", v)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := o.uint("version", 8)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case 1:
+		return buildV1Message(o)
+	case 2:
+		return buildV2Message(o)
+	default:
+		return nil, fmt.Errorf("version: %w", ErrInvalidVersion)
+	}
+}
+
+func buildV1Message(o *object) (Message, error) {
+	msgType, err := o.typ(v1MessageTypeName)
+	if err != nil {
+		return nil, err
+	}
+	teid, err := o.optUint("teid", 32)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := o.optUint("sequence", 16)
+	if err != nil {
+		return nil, err
+	}
+
+	if msgType == v1msg.MsgTypeTPDU {
+		payload, err := o.optHex("payload")
+		if err != nil {
+			return nil, err
+		}
+		if err := o.done(); err != nil {
+			return nil, err
+		}
+		return v1msg.NewTPDU(uint32(teid), payload), nil
+	}
+
+	var ies []*v1ie.IE
+	if err := o.each("ies", func(path string, v interface{}) error {
+		i, err := buildV1IE(path, v)
+		if err != nil {
+			return err
+		}
+		ies = append(ies, i)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := o.done(); err != nil {
+		return nil, err
+	}
+
+	b, err := v1msg.NewGeneric(msgType, uint32(teid), uint16(seq), ies...).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return v1msg.Parse(b)
+}
+
+func buildV2Message(o *object) (Message, error) {
+	msgType, err := o.typ(v2MessageTypeName)
+	if err != nil {
+		return nil, err
+	}
+	teid, err := o.optUint("teid", 32)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := o.optUint("sequence", 24)
+	if err != nil {
+		return nil, err
+	}
+
+	var ies []*v2ie.IE
+	if err := o.each("ies", func(path string, v interface{}) error {
+		i, err := buildV2IE(path, v)
+		if err != nil {
+			return err
+		}
+		ies = append(ies, i)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := o.done(); err != nil {
+		return nil, err
+	}
+
+	var g *v2msg.Generic
+	switch msgType {
+	case v2msg.MsgTypeEchoRequest, v2msg.MsgTypeEchoResponse, v2msg.MsgTypeVersionNotSupportedIndication:
+		g = v2msg.NewGenericWithoutTEID(msgType, uint32(teid), uint32(seq), ies...)
+	default:
+		g = v2msg.NewGeneric(msgType, uint32(teid), uint32(seq), ies...)
+	}
+
+	b, err := g.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return v2msg.Parse(b)
+}
+
+// v1MessageTypeName returns the name of the message type, by decoding the empty
+// message of the type with the parser.
+func v1MessageTypeName(t uint8) string {
+	g := v1msg.NewGeneric(t, 0, 0)
+	b, err := g.Marshal()
+	if err != nil {
+		return g.MessageTypeName()
+	}
+	m, err := v1msg.Parse(b)
+	if err != nil {
+		return g.MessageTypeName()
+	}
+	return m.MessageTypeName()
+}
+
+// v2MessageTypeName is the GTPv2 version of v1MessageTypeName.
+func v2MessageTypeName(t uint8) string {
+	g := v2msg.NewGeneric(t, 0, 0)
+	b, err := g.Marshal()
+	if err != nil {
+		return g.MessageTypeName()
+	}
+	m, err := v2msg.Parse(b)
+	if err != nil {
+		return g.MessageTypeName()
+	}
+	return m.MessageTypeName()
+}
+
+// object is a map in the description. It keeps track of the keys looked up to
+// detect the unknown ones.
+type object struct {
+	path string
+	m    map[string]interface{}
+	used map[string]bool
+}
+
+func newObject(path string, v interface{}) (*object, error) {
+	o := &object{path: path, m: map[string]interface{}{}, used: map[string]bool{}}
+	switch m := v.(type) {
+	case map[string]interface{}:
+		o.m = m
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			o.m[fmt.Sprint(k)] = v
+		}
+	default:
+		return nil, fmt.Errorf("%s: got %T, want object", o.pathOrRoot(), v)
+	}
+	return o, nil
+}
+
+func (o *object) pathOrRoot() string {
+	if o.path == "" {
+		return "(root)"
+	}
+	return o.path
+}
+
+func (o *object) pathTo(key string) string {
+	if o.path == "" {
+		return key
+	}
+	return o.path + "." + key
+}
+
+func (o *object) get(key string) (interface{}, bool) {
+	o.used[key] = true
+	v, ok := o.m[key]
+	return v, ok && v != nil
+}
+
+// ignore marks keys as known without using them.
+func (o *object) ignore(keys ...string) {
+	for _, k := range keys {
+		o.used[k] = true
+	}
+}
+
+// done returns error if o has the keys that are not looked up.
+func (o *object) done() error {
+	var unknown []string
+	for k := range o.m {
+		if !o.used[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("%s: unknown field(s): %s", o.pathOrRoot(), strings.Join(unknown, ", "))
+}
+
+func (o *object) uint(key string, bits int) (uint64, error) {
+	v, ok := o.get(key)
+	if !ok {
+		return 0, fmt.Errorf("%s: missing", o.pathTo(key))
+	}
+	return toUint(o.pathTo(key), v, bits)
+}
+
+func (o *object) optUint(key string, bits int) (uint64, error) {
+	v, ok := o.get(key)
+	if !ok {
+		return 0, nil
+	}
+	return toUint(o.pathTo(key), v, bits)
+}
+
+func (o *object) string(key string) (string, error) {
+	v, ok := o.get(key)
+	if !ok {
+		return "", fmt.Errorf("%s: missing", o.pathTo(key))
+	}
+	return toString(o.pathTo(key), v)
+}
+
+func (o *object) optString(key string) (string, error) {
+	v, ok := o.get(key)
+	if !ok {
+		return "", nil
+	}
+	return toString(o.pathTo(key), v)
+}
+
+func (o *object) optBool(key string) (bool, error) {
+	v, ok := o.get(key)
+	if !ok {
+		return false, nil
+	}
+	return toBool(o.pathTo(key), v)
+}
+
+func (o *object) optHex(key string) ([]byte, error) {
+	s, err := o.optString(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", o.pathTo(key), err)
+	}
+	return b, nil
+}
+
+func (o *object) object(key string) (*object, error) {
+	v, ok := o.get(key)
+	if !ok {
+		return nil, nil
+	}
+	return newObject(o.pathTo(key), v)
+}
+
+// each calls fn with each element of the list in key.
+func (o *object) each(key string, fn func(path string, v interface{}) error) error {
+	v, ok := o.get(key)
+	if !ok {
+		return nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s: got %T, want list", o.pathTo(key), v)
+	}
+	for i, v := range list {
+		if err := fn(fmt.Sprintf("%s[%d]", o.pathTo(key), i), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typ returns the type given by number in "type" or by name in "name". The name
+// is looked up by comparing with the ones returned by nameOf.
+func (o *object) typ(nameOf func(uint8) string) (uint8, error) {
+	if _, ok := o.get("type"); ok {
+		t, err := o.uint("type", 8)
+		if err != nil {
+			return 0, err
+		}
+		// the name given with type is checked only if it is the one of another type,
+		// as the rendered name of unknown type is not the one to be looked up.
+		name, err := o.optString("name")
+		if err != nil {
+			return 0, err
+		}
+		if named, ok := lookupType(name, nameOf); ok && named != uint8(t) {
+			return 0, fmt.Errorf("%s: %q does not match type %d", o.pathTo("name"), name, t)
+		}
+		return uint8(t), nil
+	}
+
+	name, err := o.string("name")
+	if err != nil {
+		return 0, fmt.Errorf("%s: either type or name is required", o.pathOrRoot())
+	}
+	t, ok := lookupType(name, nameOf)
+	if !ok {
+		return 0, fmt.Errorf("%s: unknown name %q", o.pathTo("name"), name)
+	}
+	return t, nil
+}
+
+func lookupType(name string, nameOf func(uint8) string) (uint8, bool) {
+	if name == "" {
+		return 0, false
+	}
+	for t := 0; t <= math.MaxUint8; t++ {
+		if nameOf(uint8(t)) == name {
+			return uint8(t), true
+		}
+	}
+	return 0, false
+}
+
+func toUint(path string, v interface{}, bits int) (uint64, error) {
+	var (
+		n   uint64
+		err error
+	)
+	switch v := v.(type) {
+	case json.Number:
+		n, err = strconv.ParseUint(v.String(), 10, bits)
+	case string:
+		n, err = strconv.ParseUint(v, 0, bits)
+	case float64:
+		if v < 0 || v != math.Trunc(v) {
+			return 0, fmt.Errorf("%s: %v is not an unsigned integer", path, v)
+		}
+		n, err = strconv.ParseUint(strconv.FormatFloat(v, 'f', 0, 64), 10, bits)
+	case int:
+		n, err = strconv.ParseUint(strconv.Itoa(v), 10, bits)
+	case int64:
+		n, err = strconv.ParseUint(strconv.FormatInt(v, 10), 10, bits)
+	case uint64:
+		n, err = strconv.ParseUint(strconv.FormatUint(v, 10), 10, bits)
+	default:
+		return 0, fmt.Errorf("%s: got %T, want number", path, v)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return n, nil
+}
+
+func toString(path string, v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s: got %T, want string", path, v)
+	}
+	return s, nil
+}
+
+func toBool(path string, v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s: got %T, want bool", path, v)
+	}
+	return b, nil
+}
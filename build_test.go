@@ -0,0 +1,218 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	v1ie "github.com/wmnsk/go-gtp/gtpv1/ie"
+	v1msg "github.com/wmnsk/go-gtp/gtpv1/message"
+	v2ie "github.com/wmnsk/go-gtp/gtpv2/ie"
+	v2msg "github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func TestBuildMessage(t *testing.T) {
+	cases := []struct {
+		description string
+		msg         Message
+	}{
+		{
+			"GTPv1 Create PDP Context Request",
+			v1msg.NewCreatePDPContextRequest(
+				0, 1,
+				v1ie.NewIMSI("123451234567890"),
+				v1ie.NewRouteingAreaIdentity("123", "45", 0x1111, 0x22),
+				v1ie.NewSelectionMode(1),
+				v1ie.NewTEIDDataI(0xdeadbeef),
+				v1ie.NewTEIDCPlane(0xdeadbeef),
+				v1ie.NewNSAPI(5),
+				v1ie.NewEndUserAddress("10.0.0.1"),
+				v1ie.NewAccessPointName("some.apn.example"),
+				v1ie.NewGSNAddress("1.1.1.1"),
+				v1ie.NewMSISDN("819012345678"),
+				v1ie.NewUserLocationInformationWithSAI("123", "45", 0x1111, 0x3333),
+				v1ie.NewMSTimeZone(9*time.Hour, 0),
+			),
+		}, {
+			"GTPv1 T-PDU",
+			v1msg.NewTPDU(0x11223344, []byte{0xde, 0xad, 0xbe, 0xef}),
+		}, {
+			"GTPv2 Create Session Request",
+			v2msg.NewCreateSessionRequest(
+				0, 1,
+				v2ie.NewIMSI("123451234567890"),
+				v2ie.NewMSISDN("123450123456789"),
+				v2ie.NewUserLocationInformationStruct(
+					nil, nil, nil, v2ie.NewTAI("123", "45", 0x0001),
+					v2ie.NewECGI("123", "45", 0x00000101), nil, nil, nil,
+				),
+				v2ie.NewServingNetwork("123", "45"),
+				v2ie.NewRATType(6),
+				v2ie.NewIndicationFromOctets(0xa1, 0x08, 0x15, 0x10, 0x88, 0x81, 0x40),
+				v2ie.NewFullyQualifiedTEID(10, 0xffffffff, "1.1.1.1", "").WithInstance(0),
+				v2ie.NewFullyQualifiedTEID(7, 0xffffffff, "1.1.1.2", "").WithInstance(1),
+				v2ie.NewAccessPointName("some.apn.example"),
+				v2ie.NewSelectionMode(0),
+				v2ie.NewPDNType(1),
+				v2ie.NewPDNAddressAllocation("2.2.2.2"),
+				v2ie.NewAPNRestriction(0),
+				v2ie.NewAggregateMaximumBitRate(0x11111111, 0x22222222),
+				v2ie.NewBearerContext(
+					v2ie.NewEPSBearerID(0x05),
+					v2ie.NewFullyQualifiedTEID(0, 0xffffffff, "1.1.1.3", "").WithInstance(0),
+					v2ie.NewBearerQoS(1, 2, 1, 0xff, 0x1111111111, 0x2222222222, 0x1111111111, 0x2222222222),
+				),
+				v2ie.NewUETimeZone(9*time.Hour, 0),
+				v2ie.New(0xfa, 0, []byte{0x01, 0x02}),
+			),
+		}, {
+			"GTPv2 Delete Session Response",
+			v2msg.NewDeleteSessionResponse(
+				0x11223344, 1,
+				v2ie.NewCause(70, 0, 0, 1, v2ie.New(v2ie.EPSBearerID, 1, nil)),
+			),
+		}, {
+			"GTPv2 Echo Request",
+			v2msg.NewEchoRequest(1, v2ie.NewRecovery(0x80)),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			j, err := RenderJSON(c.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			built, err := BuildMessage(j)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := Marshal(c.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Marshal(built)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestBuildMessageFromValue(t *testing.T) {
+	// as decoded by YAML decoders that use map[interface{}]interface{}.
+	v := map[interface{}]interface{}{
+		"version":  2,
+		"name":     "Modify Bearer Request",
+		"teid":     "0x11223344",
+		"sequence": 1,
+		"ies": []interface{}{
+			map[interface{}]interface{}{
+				"name": "FullyQualifiedTEID",
+				"value": map[interface{}]interface{}{
+					"InterfaceType": 10,
+					"TEIDGREKey":    "0xdeadbeef",
+					"IPv4Address":   "1.1.1.1",
+				},
+			},
+			map[interface{}]interface{}{
+				"type": 93,
+				"childIEs": []interface{}{
+					map[interface{}]interface{}{"name": "EPSBearerID", "value": 5},
+					map[interface{}]interface{}{"name": "FullyQualifiedTEID", "instance": 1, "payload": "80deadbeef01010101"},
+				},
+			},
+		},
+	}
+
+	got, err := BuildMessageFromValue(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := v2msg.NewModifyBearerRequest(
+		0x11223344, 1,
+		v2ie.NewFullyQualifiedTEID(10, 0xdeadbeef, "1.1.1.1", ""),
+		v2ie.NewBearerContext(
+			v2ie.NewEPSBearerID(5),
+			v2ie.New(v2ie.FullyQualifiedTEID, 1, []byte{0x80, 0xde, 0xad, 0xbe, 0xef, 0x01, 0x01, 0x01, 0x01}),
+		),
+	)
+	if diff := cmp.Diff(mustMarshal(t, got), mustMarshal(t, want)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestBuildMessageErrors(t *testing.T) {
+	cases := []struct {
+		description string
+		json        string
+		err         string
+	}{
+		{
+			"unknown version",
+			`{"version":3,"type":1}`,
+			"version: " + ErrInvalidVersion.Error(),
+		}, {
+			"unknown field in message",
+			`{"version":2,"type":1,"foo":1,"bar":2}`,
+			"(root): unknown field(s): bar, foo",
+		}, {
+			"unknown message name",
+			`{"version":2,"name":"Foo Request"}`,
+			`name: unknown name "Foo Request"`,
+		}, {
+			"mismatched type and name",
+			`{"version":2,"type":1,"name":"Echo Response"}`,
+			`name: "Echo Response" does not match type 1`,
+		}, {
+			"unknown field in IE value",
+			`{"version":2,"type":32,"ies":[{"name":"BearerContext","childIEs":[` +
+				`{"name":"FullyQualifiedTEID","value":{"InterfaceType":1,"TEIDGREKey":1,"IPv4":"1.1.1.1"}}]}]}`,
+			"ies[0].childIEs[0].value: unknown field(s): IPv4",
+		}, {
+			"value out of range",
+			`{"version":2,"type":32,"ies":[{"name":"EPSBearerID","value":256}]}`,
+			`ies[0].value: strconv.ParseUint: parsing "256": value out of range`,
+		}, {
+			"unknown indication flag",
+			`{"version":2,"type":32,"ies":[{"name":"Indication","value":["FOO"]}]}`,
+			`ies[0].value[0]: unknown flag "FOO"`,
+		}, {
+			"unsupported value",
+			`{"version":1,"type":16,"ies":[{"name":"QoSProfile","value":1}]}`,
+			"ies[0].value: cannot build QoSProfile from value, use payload instead",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			_, err := BuildMessage([]byte(c.json))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if diff := cmp.Diff(err.Error(), c.err); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, m Message) []byte {
+	t.Helper()
+	b, err := Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
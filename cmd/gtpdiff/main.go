@@ -0,0 +1,167 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Command gtpdiff compares the GTP messages in two inputs semantically with gtp.Diff,
+// and prints the differences found.
+//
+// Each input is either a hex stream of a message given as argument, or a file that
+// contains a hex stream or pcap/pcapng capture. The messages in the captures are
+// compared one by one in the order of appearance.
+//
+//	gtpdiff -ignore-seq 48200018... 48200018...
+//	gtpdiff -ignore-teid -ignore-recovery got.pcap want.pcapng
+//
+// It exits with status 1 if any difference is found, and 2 on errors.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/wmnsk/go-gtp"
+	"github.com/wmnsk/go-gtp/pcap"
+)
+
+// command-line flags.
+var (
+	ignoreSeq      = flag.Bool("ignore-seq", false, "ignore the sequence numbers in the headers.")
+	ignoreTEID     = flag.Bool("ignore-teid", false, "ignore the TEIDs in the headers and IEs.")
+	ignoreRecovery = flag.Bool("ignore-recovery", false, "ignore the Recovery IEs.")
+	ignorePaths    = flag.String("ignore", "", "comma-separated paths to ignore, e.g., BearerContext[0],Cause[0].")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <hex|file> <hex|file>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("gtpdiff: ")
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	a, err := readMessages(flag.Arg(0))
+	if err != nil {
+		fatalf("failed to read %s: %v", flag.Arg(0), err)
+	}
+	b, err := readMessages(flag.Arg(1))
+	if err != nil {
+		fatalf("failed to read %s: %v", flag.Arg(1), err)
+	}
+
+	var opts []gtp.DiffOption
+	if *ignoreSeq {
+		opts = append(opts, gtp.IgnoreSequence())
+	}
+	if *ignoreTEID {
+		opts = append(opts, gtp.IgnoreTEID())
+	}
+	if *ignoreRecovery {
+		opts = append(opts, gtp.IgnoreRecovery())
+	}
+	if *ignorePaths != "" {
+		opts = append(opts, gtp.IgnorePaths(strings.Split(*ignorePaths, ",")...))
+	}
+
+	var found bool
+	if len(a) != len(b) {
+		fmt.Printf("number of messages: %d != %d\n", len(a), len(b))
+		found = true
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		diffs, err := gtp.Diff(a[i], b[i], opts...)
+		if err != nil {
+			fatalf("failed to compare message #%d: %v", i, err)
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		found = true
+		fmt.Printf("message #%d: %s / %s\n%s", i, a[i].MessageTypeName(), b[i].MessageTypeName(), diffs)
+	}
+
+	if found {
+		os.Exit(1)
+	}
+}
+
+// fatalf prints the error and exits with status 2, as status 1 means the differences.
+func fatalf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(2)
+}
+
+// readMessages reads the messages from arg, which is a hex stream or a file that
+// contains a hex stream or pcap/pcapng capture.
+func readMessages(arg string) ([]gtp.Message, error) {
+	// the argument itself is the hex stream if it is not a file. Stat fails also
+	// with the hex stream too long to be a file name.
+	if _, err := os.Stat(arg); err != nil {
+		msgs, herr := readHex([]byte(arg))
+		if herr != nil {
+			return nil, fmt.Errorf("neither a file nor a hex stream: %w", herr)
+		}
+		return msgs, nil
+	}
+
+	raw, err := ioutil.ReadFile(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := pcap.NewReader(bytes.NewReader(raw))
+	switch {
+	case err == nil:
+		return readPackets(r)
+	case errors.Is(err, pcap.ErrInvalidFormat):
+		return readHex(raw)
+	default:
+		return nil, err
+	}
+}
+
+func readPackets(r *pcap.Reader) ([]gtp.Message, error) {
+	var msgs []gtp.Message
+	for {
+		pkt, err := r.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return msgs, nil
+			}
+			return nil, err
+		}
+		if pkt.Err != nil {
+			log.Printf("skipping undecodable packet: %s", pkt)
+			continue
+		}
+		msgs = append(msgs, pkt.Message)
+	}
+}
+
+func readHex(raw []byte) ([]gtp.Message, error) {
+	s := strings.Join(strings.Fields(string(raw)), "")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := gtp.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	return []gtp.Message{msg}, nil
+}
@@ -0,0 +1,302 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Difference is a difference found by Diff between two messages.
+//
+// Path is the location of the difference; the field in the header(e.g., "Sequence"),
+// the IE(e.g., "BearerContext[0]/FullyQualifiedTEID[1]"), or the field in the value
+// of IE(e.g., "BearerContext[0]/FullyQualifiedTEID[1].TEIDGREKey"). The IEs in
+// GTPv2 are specified with the instance in brackets, and the n-th(from 0) IE of
+// the same type and instance is specified by appending ":n" to it, the same way as
+// the path in gtpv2/message package. The IEs in GTPv0 and GTPv1 that have no
+// instance are specified with "[:n]" only when n is not 0.
+//
+// A and B are the values in each message, and nil if the IE is missing in it.
+type Difference struct {
+	Path string
+	A, B interface{}
+}
+
+// String returns the Difference in human readable form.
+func (d *Difference) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Path, diffValue(d.A), diffValue(d.B))
+}
+
+func diffValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "<missing>"
+	case *RenderedIE:
+		return strings.TrimSuffix(v.String(), "\n")
+	case string:
+		return v
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// Differences is the list of Difference.
+type Differences []*Difference
+
+// String returns the Differences in human readable form, one per line.
+func (ds Differences) String() string {
+	var b strings.Builder
+	for _, d := range ds {
+		b.WriteString(d.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// DiffOption is an option to ignore some differences in Diff.
+type DiffOption func(*diffConfig)
+
+type diffConfig struct {
+	ignoreSequence bool
+	ignoreTEID     bool
+	ignoreRecovery bool
+	ignorePaths    []string
+}
+
+// IgnoreSequence makes Diff ignore the sequence numbers in the headers.
+func IgnoreSequence() DiffOption {
+	return func(c *diffConfig) {
+		c.ignoreSequence = true
+	}
+}
+
+// IgnoreTEID makes Diff ignore the TEIDs in the headers and IEs, e.g., the TEID
+// in F-TEID, while the other fields in the IEs are compared.
+func IgnoreTEID() DiffOption {
+	return func(c *diffConfig) {
+		c.ignoreTEID = true
+	}
+}
+
+// IgnoreRecovery makes Diff ignore the Recovery IEs.
+func IgnoreRecovery() DiffOption {
+	return func(c *diffConfig) {
+		c.ignoreRecovery = true
+	}
+}
+
+// IgnorePaths makes Diff ignore the differences at the paths and below them, e.g.,
+// "BearerContext[0]" ignores all the differences in the Bearer Context.
+func IgnorePaths(paths ...string) DiffOption {
+	return func(c *diffConfig) {
+		c.ignorePaths = append(c.ignorePaths, paths...)
+	}
+}
+
+func (c *diffConfig) ignores(path string) bool {
+	for _, p := range c.ignorePaths {
+		if path == p || strings.HasPrefix(path, p+"/") || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff compares two messages of any version semantically, and returns the
+// Differences found in the header, IEs and the children of grouped IEs.
+//
+// The IEs are matched by type, instance and the order of appearance among the
+// ones with the same type and instance, not by the position in the message. The
+// values of the IEs are decoded the same way as Render, and compared field by
+// field so that the Difference shows which field differs. The payloads are compared
+// instead if the IEs cannot be decoded. The lengths are not compared, as they
+// are derived from the values.
+//
+// It returns nil if there is no difference.
+func Diff(a, b Message, opts ...DiffOption) (Differences, error) {
+	c := &diffConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	ra, err := Render(a)
+	if err != nil {
+		return nil, err
+	}
+	rb, err := Render(b)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &differ{diffConfig: c}
+	d.add("Version", ra.Version, rb.Version)
+	d.add("Type", ra.Name, rb.Name)
+	if !c.ignoreTEID {
+		d.add("TEID", ra.TEID, rb.TEID)
+	}
+	d.add("TID", ra.TID, rb.TID)
+	if !c.ignoreSequence {
+		d.add("Sequence", ra.Sequence, rb.Sequence)
+	}
+	d.add("Payload", ra.Payload, rb.Payload)
+	d.diffIEs("", ra.IEs, rb.IEs, ra.Version == 2)
+
+	return d.diffs, nil
+}
+
+type differ struct {
+	*diffConfig
+	diffs Differences
+}
+
+func (d *differ) add(path string, a, b interface{}) {
+	if reflect.DeepEqual(a, b) || d.ignores(path) {
+		return
+	}
+	d.diffs = append(d.diffs, &Difference{Path: path, A: a, B: b})
+}
+
+// keyedIE is a RenderedIE with the path element to it.
+type keyedIE struct {
+	key string
+	ie  *RenderedIE
+}
+
+func keyIEs(ies []*RenderedIE, withInstance bool) []*keyedIE {
+	seen := map[[2]uint8]int{}
+	keyed := make([]*keyedIE, len(ies))
+	for i, ri := range ies {
+		k := [2]uint8{ri.Type, ri.Instance}
+		n := seen[k]
+		seen[k]++
+
+		key := ri.Name
+		switch {
+		case withInstance && n == 0:
+			key += fmt.Sprintf("[%d]", ri.Instance)
+		case withInstance:
+			key += fmt.Sprintf("[%d:%d]", ri.Instance, n)
+		case n != 0:
+			key += fmt.Sprintf("[:%d]", n)
+		}
+		keyed[i] = &keyedIE{key: key, ie: ri}
+	}
+	return keyed
+}
+
+func (d *differ) diffIEs(prefix string, a, b []*RenderedIE, withInstance bool) {
+	ka, kb := keyIEs(a, withInstance), keyIEs(b, withInstance)
+	inB := map[string]*RenderedIE{}
+	for _, k := range kb {
+		inB[k.key] = k.ie
+	}
+
+	seen := map[string]bool{}
+	for _, k := range ka {
+		seen[k.key] = true
+		d.diffIE(prefix+k.key, k.ie, inB[k.key], withInstance)
+	}
+	for _, k := range kb {
+		if !seen[k.key] {
+			d.diffIE(prefix+k.key, nil, k.ie, withInstance)
+		}
+	}
+}
+
+func (d *differ) diffIE(path string, a, b *RenderedIE, withInstance bool) {
+	if d.ignores(path) {
+		return
+	}
+
+	ri := a
+	if ri == nil {
+		ri = b
+	}
+	if d.ignoreRecovery && ri.Name == "Recovery" {
+		return
+	}
+	if d.ignoreTEID && strings.HasPrefix(ri.Name, "TEID") {
+		return
+	}
+	switch {
+	case a == nil:
+		d.add(path, nil, b)
+		return
+	case b == nil:
+		d.add(path, a, nil)
+		return
+	}
+
+	if len(a.ChildIEs) > 0 || len(b.ChildIEs) > 0 {
+		d.diffIEs(path+"/", a.ChildIEs, b.ChildIEs, withInstance)
+		return
+	}
+
+	if a.Value == nil || b.Value == nil || a.Error != "" || b.Error != "" {
+		d.add(path, a.Payload, b.Payload)
+		return
+	}
+
+	va, erra := normalize(a.Value)
+	vb, errb := normalize(b.Value)
+	if erra != nil || errb != nil {
+		d.add(path, a.Payload, b.Payload)
+		return
+	}
+	d.diffValues(path, va, vb)
+}
+
+// normalize converts the value of IE into the form decoded from JSON, so that the
+// values of different types can be compared field by field.
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var n interface{}
+	if err := dec.Decode(&n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (d *differ) diffValues(path string, a, b interface{}) {
+	ma, oka := a.(map[string]interface{})
+	mb, okb := b.(map[string]interface{})
+	if !oka || !okb {
+		d.add(path, a, b)
+		return
+	}
+
+	keys := make([]string, 0, len(ma)+len(mb))
+	for k := range ma {
+		keys = append(keys, k)
+	}
+	for k := range mb {
+		if _, ok := ma[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if d.ignoreTEID && strings.Contains(k, "TEID") {
+			continue
+		}
+		d.diffValues(path+"."+k, ma[k], mb[k])
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	v0msg "github.com/wmnsk/go-gtp/gtpv0/message"
+	v1ie "github.com/wmnsk/go-gtp/gtpv1/ie"
+	v1msg "github.com/wmnsk/go-gtp/gtpv1/message"
+	v2ie "github.com/wmnsk/go-gtp/gtpv2/ie"
+	v2msg "github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func TestDiff(t *testing.T) {
+	csReqA := v2msg.NewCreateSessionRequest(
+		0x11111111, 1,
+		v2ie.NewIMSI("123451234567890"),
+		v2ie.NewMSISDN("123450123456789"),
+		v2ie.NewFullyQualifiedTEID(10, 0x11111111, "1.1.1.1", ""),
+		v2ie.NewRecovery(1),
+		v2ie.NewBearerContext(v2ie.NewEPSBearerID(5)),
+	)
+	csReqB := v2msg.NewCreateSessionRequest(
+		0x22222222, 2,
+		v2ie.NewIMSI("123451234567890"),
+		v2ie.NewFullyQualifiedTEID(10, 0x22222222, "1.1.1.2", ""),
+		v2ie.NewRecovery(2),
+		v2ie.NewBearerContext(v2ie.NewEPSBearerID(6)),
+	)
+
+	cases := []struct {
+		description string
+		a, b        Message
+		opts        []DiffOption
+		want        string
+	}{
+		{
+			"GTPv2 same",
+			csReqA, csReqA, nil,
+			"",
+		}, {
+			"GTPv2",
+			csReqA, csReqB, nil,
+			"TEID: 286331153 != 572662306\n" +
+				"Sequence: 1 != 2\n" +
+				"MSISDN[0]: MSISDN (Type: 76, Instance: 0, Length: 8): \"123450123456789\" != <missing>\n" +
+				"FullyQualifiedTEID[0].IPv4Address: 1.1.1.1 != 1.1.1.2\n" +
+				"FullyQualifiedTEID[0].TEIDGREKey: 286331153 != 572662306\n" +
+				"BearerContext[0]/EPSBearerID[0]: 5 != 6\n" +
+				"Recovery[0]: 1 != 2\n",
+		}, {
+			"GTPv2 ignoring volatile fields",
+			csReqA, csReqB,
+			[]DiffOption{IgnoreSequence(), IgnoreTEID(), IgnoreRecovery(), IgnorePaths("BearerContext[0]", "MSISDN[0]")},
+			"FullyQualifiedTEID[0].IPv4Address: 1.1.1.1 != 1.1.1.2\n",
+		}, {
+			"GTPv1",
+			v1msg.NewCreatePDPContextRequest(0, 1, v1ie.NewTEIDCPlane(1), v1ie.NewNSAPI(5)),
+			v1msg.NewCreatePDPContextRequest(0, 1, v1ie.NewTEIDCPlane(2), v1ie.NewNSAPI(5), v1ie.NewNSAPI(6)),
+			nil,
+			"TEIDCPlane: 1 != 2\n" +
+				"NSAPI[:1]: <missing> != LinkedNSAPI: NSAPI (Type: 20, Instance: 0, Length: 0): 6\n",
+		}, {
+			"GTPv1 ignoring TEID",
+			v1msg.NewCreatePDPContextRequest(0, 1, v1ie.NewTEIDCPlane(1), v1ie.NewNSAPI(5)),
+			v1msg.NewCreatePDPContextRequest(0, 1, v1ie.NewTEIDCPlane(2), v1ie.NewNSAPI(5)),
+			[]DiffOption{IgnoreTEID()},
+			"",
+		}, {
+			"GTPv0 and GTPv2",
+			v0msg.NewEchoRequest(v0flow.seq, v0flow.label, v0flow.tid),
+			v2msg.NewEchoRequest(1),
+			nil,
+			"Version: 0 != 2\n" +
+				"TID: 1234567890123455 != \n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			got, err := Diff(c.a, c.b, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got.String(), c.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package ie_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wmnsk/go-gtp/gtpv0/ie"
+)
+
+// The seed corpus of FuzzParse is in testdata/fuzz, which is generated from the
+// test vectors in ie_test.go.
+
+func FuzzParse(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		i, err := ie.Parse(b)
+		if err != nil {
+			return
+		}
+		_ = i.Name()
+		_, _ = i.Value()
+
+		// IE should be serialized as it is.
+		got, err := i.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", i, err)
+		}
+		if want := b[:i.MarshalLen()]; !bytes.Equal(got, want) {
+			t.Errorf("not identical:\n got: %x\nwant: %x", got, want)
+		}
+	})
+}
+
+func FuzzParseMultiIEs(f *testing.F) {
+	f.Add([]byte{0x01, 0x80, 0x0e, 0x01, 0x85, 0x00, 0x04, 0x01, 0x01, 0x01, 0x01})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		ies, err := ie.ParseMultiIEs(b)
+		if err != nil {
+			return
+		}
+
+		var got []byte
+		for _, i := range ies {
+			s, err := i.Marshal()
+			if err != nil {
+				t.Fatalf("failed to marshal %v: %s", i, err)
+			}
+			got = append(got, s...)
+		}
+		if !bytes.Equal(got, b) {
+			t.Errorf("not identical:\n got: %x\nwant: %x", got, b)
+		}
+	})
+}
@@ -100,7 +100,7 @@ func Parse(b []byte) (*IE, error) {
 
 // UnmarshalBinary sets the values retrieved from byte sequence in GTPv0 IE.
 func (i *IE) UnmarshalBinary(b []byte) error {
-	if len(b) < 2 {
+	if len(b) < 1 {
 		return ErrTooShortToParse
 	}
 
@@ -108,19 +108,26 @@ func (i *IE) UnmarshalBinary(b []byte) error {
 	if i.IsTV() {
 		return parseTVFromBytes(i, b)
 	}
+	if len(b) < 2 {
+		return ErrTooShortToParse
+	}
 	return parseTLVFromBytes(i, b)
 }
 
 func parseTVFromBytes(i *IE, b []byte) error {
+	// the length of TV IE is determined by the type. The unknown ones are
+	// considered to have no payload.
+	i.Payload = nil
 	l := len(b)
-	if l < 2 {
+	n := i.MarshalLen()
+	if l < 2 && n > 1 {
 		return ErrTooShortToParse
 	}
-	if i.MarshalLen() > l {
+	if n > l {
 		return ErrInvalidLength
 	}
 	i.Length = 0
-	i.Payload = b[1:i.MarshalLen()]
+	i.Payload = b[1:n]
 
 	return nil
 }
@@ -0,0 +1,45 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ie
+
+import "fmt"
+
+var ieNames = map[uint8]string{
+	Cause:                        "Cause",
+	IMSI:                         "IMSI",
+	RouteingAreaIdentity:         "RouteingAreaIdentity",
+	TemporaryLogicalLinkIdentity: "TemporaryLogicalLinkIdentity",
+	PacketTMSI:                   "PacketTMSI",
+	QualityOfServiceProfile:      "QualityOfServiceProfile",
+	ReorderingRequired:           "ReorderingRequired",
+	AuthenticationTriplet:        "AuthenticationTriplet",
+	MAPCause:                     "MAPCause",
+	PTMSISignature:               "PTMSISignature",
+	MSValidated:                  "MSValidated",
+	Recovery:                     "Recovery",
+	SelectionMode:                "SelectionMode",
+	FlowLabelDataI:               "FlowLabelDataI",
+	FlowLabelSignalling:          "FlowLabelSignalling",
+	FlowLabelDataII:              "FlowLabelDataII",
+	MSNotReachableReason:         "MSNotReachableReason",
+	ChargingID:                   "ChargingID",
+	EndUserAddress:               "EndUserAddress",
+	MMContext:                    "MMContext",
+	PDPContext:                   "PDPContext",
+	AccessPointName:              "AccessPointName",
+	ProtocolConfigurationOptions: "ProtocolConfigurationOptions",
+	GSNAddress:                   "GSNAddress",
+	MSISDN:                       "MSISDN",
+	ChargingGatewayAddress:       "ChargingGatewayAddress",
+	PrivateExtension:             "PrivateExtension",
+}
+
+// Name returns the name of the type of IE, e.g., "IMSI".
+func (i *IE) Name() string {
+	if n, ok := ieNames[i.Type]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", i.Type)
+}
@@ -0,0 +1,137 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ie
+
+// Value returns the value of IE decoded with the accessor of its type, which is
+// meant to be used for rendering IE in human readable form.
+//
+// The type of returned value varies by the type of IE; e.g., string for IMSI,
+// uint16 for Flow Label, and the MCC, MNC, LAC and RAC for Routeing Area Identity.
+// It returns nil without error for the IEs that have no accessor.
+func (i *IE) Value() (interface{}, error) {
+	switch i.Type {
+	case Cause:
+		return i.Cause()
+	case IMSI:
+		return i.IMSI()
+	case RouteingAreaIdentity:
+		return raiValue(i)
+	case TemporaryLogicalLinkIdentity:
+		return i.TemporaryLogicalLinkIdentity()
+	case PacketTMSI:
+		return i.PacketTMSI()
+	case QualityOfServiceProfile:
+		return qosValue(i)
+	case ReorderingRequired:
+		return i.ReorderingRequired(), nil
+	case PTMSISignature:
+		return i.PTMSISignature()
+	case Recovery:
+		return i.Recovery()
+	case SelectionMode:
+		return i.SelectionMode()
+	case FlowLabelDataI, FlowLabelSignalling:
+		return i.FlowLabelData()
+	case FlowLabelDataII:
+		return flowLabelDataIIValue(i)
+	case MSNotReachableReason:
+		return i.MSNotReachableReason()
+	case ChargingID:
+		return i.ChargingID()
+	case EndUserAddress:
+		return endUserAddressValue(i)
+	case AccessPointName:
+		return i.AccessPointName()
+	case GSNAddress:
+		return i.GSNAddress()
+	case MSISDN:
+		return i.MSISDN()
+	case ChargingGatewayAddress:
+		return i.ChargingGatewayAddress()
+	case PrivateExtension:
+		return i.PrivateExtension()
+	default:
+		return nil, nil
+	}
+}
+
+func raiValue(i *IE) (interface{}, error) {
+	mcc, err := i.MCC()
+	if err != nil {
+		return nil, err
+	}
+	mnc, err := i.MNC()
+	if err != nil {
+		return nil, err
+	}
+	lac, err := i.LAC()
+	if err != nil {
+		return nil, err
+	}
+	rac, err := i.RAC()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"MCC": mcc, "MNC": mnc, "LAC": lac, "RAC": rac}, nil
+}
+
+func qosValue(i *IE) (interface{}, error) {
+	delay, err := i.QoSDelay()
+	if err != nil {
+		return nil, err
+	}
+	reliability, err := i.QoSReliability()
+	if err != nil {
+		return nil, err
+	}
+	peak, err := i.QoSPeak()
+	if err != nil {
+		return nil, err
+	}
+	precedence, err := i.QoSPrecedence()
+	if err != nil {
+		return nil, err
+	}
+	mean, err := i.QoSMean()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"Delay":       delay,
+		"Reliability": reliability,
+		"Peak":        peak,
+		"Precedence":  precedence,
+		"Mean":        mean,
+	}, nil
+}
+
+func flowLabelDataIIValue(i *IE) (interface{}, error) {
+	nsapi, err := i.NSAPI()
+	if err != nil {
+		return nil, err
+	}
+	label, err := i.FlowLabelData()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"NSAPI": nsapi, "FlowLabel": label}, nil
+}
+
+func endUserAddressValue(i *IE) (interface{}, error) {
+	org, err := i.PDPTypeOrganization()
+	if err != nil {
+		return nil, err
+	}
+	num, err := i.PDPTypeNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	v := map[string]interface{}{"PDPTypeOrganization": org, "PDPTypeNumber": num}
+	if ip, err := i.IPAddress(); err == nil {
+		v["IPAddress"] = ip
+	}
+	return v, nil
+}
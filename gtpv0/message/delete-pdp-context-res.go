@@ -56,6 +56,9 @@ func (d *DeletePDPContextResponse) Marshal() ([]byte, error) {
 func (d *DeletePDPContextResponse) MarshalTo(b []byte) error {
 	// XXX - add validation!
 
+	if d.Header.Payload != nil {
+		d.Header.Payload = nil
+	}
 	d.Header.Payload = make([]byte, d.MarshalLen()-d.Header.MarshalLen())
 
 	offset := 0
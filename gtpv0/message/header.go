@@ -85,7 +85,7 @@ func ParseHeader(b []byte) (*Header, error) {
 // UnmarshalBinary sets the values retrieved from byte sequence in GTPv1 header.
 func (h *Header) UnmarshalBinary(b []byte) error {
 	l := len(b)
-	if l < 11 {
+	if l < 20 {
 		return ErrTooShortToParse
 	}
 	h.Flags = b[0]
@@ -93,7 +93,7 @@ func (h *Header) UnmarshalBinary(b []byte) error {
 	h.Length = binary.BigEndian.Uint16(b[2:4])
 	h.SequenceNumber = binary.BigEndian.Uint16(b[4:6])
 	h.FlowLabel = binary.BigEndian.Uint16(b[6:8])
-	h.SndcpNumber = b[9]
+	h.SndcpNumber = b[8]
 	h.TID = binary.BigEndian.Uint64(b[12:20])
 
 	if int(h.Length)+20 != l {
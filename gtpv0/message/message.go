@@ -100,6 +100,10 @@ func Marshal(g Message) ([]byte, error) {
 
 // Parse Parses the given bytes as Message.
 func Parse(b []byte) (Message, error) {
+	if len(b) < 20 {
+		return nil, ErrTooShortToParse
+	}
+
 	var g Message
 
 	switch b[1] {
@@ -0,0 +1,172 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+type tpduBuffer struct {
+	size    int
+	queue   [][]byte
+	dropped int
+	onFirst func(teidIn uint32)
+
+	// flushing is true while FlushBufferTo is sending the queued packets, during
+	// which the new packets are still queued to keep the order.
+	flushing bool
+}
+
+// StartBuffering makes UPlaneConn buffer the T-PDU with teidIn instead of relaying it,
+// which is typically used by S-GW to keep the downlink packets for the UE in idle mode
+// after S1-U bearer is released by Release Access Bearers procedure.
+//
+// At most size packets are kept in the buffer, and the ones exceeding it are discarded.
+// onFirst is called when the first packet is buffered, which is expected to be used to
+// trigger Downlink Data Notification on the C-Plane. It is safe to give nil to onFirst.
+//
+// Use FlushBufferTo to send the buffered packets to the new peer and start relaying, or
+// DiscardBuffer to stop buffering without sending them.
+func (u *UPlaneConn) StartBuffering(teidIn uint32, size int, onFirst func(teidIn uint32)) error {
+	if u.KernelGTP.enabled {
+		return errors.New("cannot call StartBuffering when using Kernel GTP-U")
+	}
+	if size <= 0 {
+		return fmt.Errorf("invalid buffer size: %d", size)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.bufferMap == nil {
+		u.bufferMap = map[uint32]*tpduBuffer{}
+	}
+	if _, ok := u.bufferMap[teidIn]; ok {
+		return fmt.Errorf("already buffering for TEID: %#x", teidIn)
+	}
+	u.bufferMap[teidIn] = &tpduBuffer{size: size, onFirst: onFirst}
+	return nil
+}
+
+// IsBuffering reports whether the T-PDU with teidIn is being buffered.
+func (u *UPlaneConn) IsBuffering(teidIn uint32) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	_, ok := u.bufferMap[teidIn]
+	return ok
+}
+
+// FlushBufferTo sends the T-PDUs buffered for teidIn to the peer specified by raddr
+// with teidOut, via the UPlaneConn given as c. It stops buffering and starts relaying
+// the T-PDU with teidIn to the peer, just like RelayTo does.
+//
+// The T-PDUs received while flushing are also buffered and sent, and the relaying
+// starts only after the buffer is drained, so that the packets are sent in the order
+// of arrival.
+//
+// This is typically used by S-GW when the eNB F-TEID is restored by Modify Bearer Request.
+// It returns the number of packets discarded due to the buffer overflow.
+func (u *UPlaneConn) FlushBufferTo(c *UPlaneConn, teidIn, teidOut uint32, raddr net.Addr) (int, error) {
+	if u.KernelGTP.enabled {
+		return 0, errors.New("cannot call FlushBufferTo when using Kernel GTP-U")
+	}
+
+	u.mu.Lock()
+	buf, ok := u.bufferMap[teidIn]
+	if !ok {
+		u.mu.Unlock()
+		return 0, fmt.Errorf("not buffering for TEID: %#x", teidIn)
+	}
+	if buf.flushing {
+		u.mu.Unlock()
+		return 0, fmt.Errorf("already flushing buffer for TEID: %#x", teidIn)
+	}
+	buf.flushing = true
+
+	for {
+		if len(buf.queue) == 0 {
+			// switch to relaying with the lock held, so that no packet is
+			// relayed before the buffered ones.
+			delete(u.bufferMap, teidIn)
+			if u.relayMap == nil {
+				u.relayMap = map[uint32]*peer{}
+			}
+			u.relayMap[teidIn] = &peer{teid: teidOut, addr: raddr, srcConn: c}
+			u.mu.Unlock()
+			return buf.dropped, nil
+		}
+
+		queue := buf.queue
+		buf.queue = nil
+		u.mu.Unlock()
+
+		for i, pkt := range queue {
+			binary.BigEndian.PutUint32(pkt[4:8], teidOut)
+			if _, err := c.WriteTo(pkt, raddr); err != nil {
+				// keep buffering with the packets not sent yet.
+				u.mu.Lock()
+				buf.queue = append(queue[i:], buf.queue...)
+				buf.flushing = false
+				dropped := buf.dropped
+				u.mu.Unlock()
+				return dropped, fmt.Errorf("failed to flush buffer to %s: %w", raddr, err)
+			}
+		}
+
+		u.mu.Lock()
+		if u.bufferMap[teidIn] != buf {
+			dropped := buf.dropped
+			u.mu.Unlock()
+			return dropped, fmt.Errorf("buffer for TEID %#x is discarded while flushing", teidIn)
+		}
+	}
+}
+
+// DiscardBuffer stops buffering the T-PDU with teidIn and discards the packets
+// buffered so far. It returns the number of packets discarded, including the ones
+// discarded due to the buffer overflow.
+func (u *UPlaneConn) DiscardBuffer(teidIn uint32) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	buf, ok := u.bufferMap[teidIn]
+	if !ok {
+		return 0, fmt.Errorf("not buffering for TEID: %#x", teidIn)
+	}
+	delete(u.bufferMap, teidIn)
+
+	return len(buf.queue) + buf.dropped, nil
+}
+
+// bufferTPDU queues the raw T-PDU if buffering is enabled for its TEID.
+// It reports whether the packet is consumed(=buffered or discarded) or not.
+func (u *UPlaneConn) bufferTPDU(raw []byte) bool {
+	teid := binary.BigEndian.Uint32(raw[4:8])
+
+	u.mu.Lock()
+	buf, ok := u.bufferMap[teid]
+	if !ok {
+		u.mu.Unlock()
+		return false
+	}
+	if len(buf.queue) >= buf.size {
+		buf.dropped++
+		u.mu.Unlock()
+		return true
+	}
+
+	buf.queue = append(buf.queue, raw)
+	first := len(buf.queue) == 1 && buf.dropped == 0 && !buf.flushing
+	fn := buf.onFirst
+	u.mu.Unlock()
+
+	if first && fn != nil {
+		fn(teid)
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/gtpv1"
+)
+
+func TestBuffering(t *testing.T) {
+	s5Addr, err := net.ResolveUDPAddr("udp", "127.0.0.41:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1Addr, err := net.ResolveUDPAddr("udp", "127.0.0.42:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s5Conn := gtpv1.NewUPlaneConn(s5Addr)
+	serveUPlane(ctx, t, s5Conn, s5Addr)
+	s1Conn := gtpv1.NewUPlaneConn(s1Addr)
+	serveUPlane(ctx, t, s1Conn, s1Addr)
+
+	enb, err := net.ListenPacket("udp", "127.0.0.43:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enb.Close()
+	pgw, err := net.ListenPacket("udp", "127.0.0.44:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pgw.Close()
+
+	notifiedCh := make(chan uint32, 3)
+	if err := s5Conn.StartBuffering(0x11111111, 2, func(teid uint32) {
+		notifiedCh <- teid
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !s5Conn.IsBuffering(0x11111111) {
+		t.Fatal("IsBuffering should be true after StartBuffering")
+	}
+
+	sendTPDU := func(payload byte) {
+		t.Helper()
+
+		b, err := gtpv1.Encapsulate(0x11111111, []byte{payload}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pgw.WriteTo(b, s5Addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sendTPDU(0x01)
+	select {
+	case teid := <-notifiedCh:
+		if diff := cmp.Diff(teid, uint32(0x11111111)); diff != "" {
+			t.Error(diff)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out while waiting for the first packet to be buffered")
+	}
+	sendTPDU(0x02)
+	sendTPDU(0x03)
+	// XXX - waiting for packets to be buffered, should consider better way.
+	time.Sleep(100 * time.Millisecond)
+
+	dropped, err := s5Conn.FlushBufferTo(s1Conn, 0x11111111, 0x22222222, enb.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(dropped, 1); diff != "" {
+		t.Error(diff)
+	}
+	if s5Conn.IsBuffering(0x11111111) {
+		t.Error("IsBuffering should be false after FlushBufferTo")
+	}
+	if len(notifiedCh) != 0 {
+		t.Error("onFirst should be called only once")
+	}
+
+	// 2 buffered packets and the one relayed after flushing should arrive.
+	sendTPDU(0x04)
+	buf := make([]byte, 1500)
+	for i := 0; i < 3; i++ {
+		if err := enb.SetReadDeadline(time.Now().Add(3 * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		n, _, err := enb.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		teid, _, err := gtpv1.Decapsulate(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(teid, uint32(0x22222222)); diff != "" {
+			t.Error(diff)
+		}
+	}
+
+	if _, err := s5Conn.DiscardBuffer(0x11111111); err == nil {
+		t.Error("DiscardBuffer should fail when not buffering")
+	}
+}
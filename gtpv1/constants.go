@@ -10,6 +10,19 @@ const (
 	GTPUPort = ":2152"
 )
 
+// Extension Header Type definitions.
+const (
+	ExtHeaderTypeNoMoreExtensionHeaders uint8 = 0x00
+	ExtHeaderTypeServiceClassIndicator  uint8 = 0x20
+	ExtHeaderTypeUDPPort                uint8 = 0x40
+	ExtHeaderTypeRANContainer           uint8 = 0x81
+	ExtHeaderTypeLongPDCPPDUNumber      uint8 = 0x82
+	ExtHeaderTypeXwRANContainer         uint8 = 0x83
+	ExtHeaderTypeNRRANContainer         uint8 = 0x84
+	ExtHeaderTypePDUSessionContainer    uint8 = 0x85
+	ExtHeaderTypePDCPPDUNumber          uint8 = 0xc0
+)
+
 // Cause definitions.
 const (
 	ReqCauseRequestIMSI uint8 = iota
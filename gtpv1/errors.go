@@ -20,6 +20,9 @@ var (
 	// ErrConnNotOpened indicates that some operation is failed due to the status of
 	// Conn is not valid.
 	ErrConnNotOpened = errors.New("connection is not opened")
+
+	// ErrRequiredIEMissing indicates that the IE required to handle the message is missing.
+	ErrRequiredIEMissing = errors.New("required IE is missing")
 )
 
 // ErrorIndicatedError indicates that Error Indication message is received on U-Plane Connection.
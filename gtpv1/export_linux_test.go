@@ -0,0 +1,10 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1
+
+// ParseTunnels exports parseTunnels for testing.
+func ParseTunnels(msgs [][]byte, link int) ([]*Tunnel, error) {
+	return parseTunnels(msgs, link)
+}
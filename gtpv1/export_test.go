@@ -0,0 +1,116 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/vishvananda/netlink"
+)
+
+// fakePDPHandler is an in-memory replacement of the netlink layer used in tests.
+//
+// The tunnels are kept per link index, as Kernel keeps them per GTP device.
+type fakePDPHandler struct {
+	mu      sync.Mutex
+	tunnels map[int][]*Tunnel
+}
+
+func (f *fakePDPHandler) add(link netlink.Link, t *Tunnel, replace bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx := link.Attrs().Index
+	for i, tt := range f.tunnels[idx] {
+		if tt.AddressFamily() != t.AddressFamily() {
+			continue
+		}
+		if !tt.MSAddress.Equal(t.MSAddress) && tt.ITEI != t.ITEI {
+			continue
+		}
+		if !replace {
+			return errors.New("file exists")
+		}
+		c := *t
+		f.tunnels[idx][i] = &c
+		return nil
+	}
+
+	c := *t
+	f.tunnels[idx] = append(f.tunnels[idx], &c)
+	return nil
+}
+
+func (f *fakePDPHandler) del(link netlink.Link, t *Tunnel) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx := link.Attrs().Index
+	for i, tt := range f.tunnels[idx] {
+		if tt.AddressFamily() == t.AddressFamily() && tt.ITEI == t.ITEI {
+			f.tunnels[idx] = append(f.tunnels[idx][:i], f.tunnels[idx][i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("no such file or directory")
+}
+
+func (f *fakePDPHandler) list(link netlink.Link) ([]*Tunnel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tunnels := make([]*Tunnel, len(f.tunnels[link.Attrs().Index]))
+	for i, t := range f.tunnels[link.Attrs().Index] {
+		c := *t
+		tunnels[i] = &c
+	}
+	return tunnels, nil
+}
+
+// getByITEI looks up the tunnel in the family only, as Kernel does.
+func (f *fakePDPHandler) getByITEI(link netlink.Link, itei uint32, family uint8) (*Tunnel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, t := range f.tunnels[link.Attrs().Index] {
+		if t.AddressFamily() == family && t.ITEI == itei {
+			c := *t
+			return &c, nil
+		}
+	}
+	return nil, errors.New("no such file or directory")
+}
+
+func (f *fakePDPHandler) getByMSAddress(link netlink.Link, msIP net.IP) (*Tunnel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, t := range f.tunnels[link.Attrs().Index] {
+		if t.MSAddress.Equal(msIP) {
+			c := *t
+			return &c, nil
+		}
+	}
+	return nil, errors.New("no such file or directory")
+}
+
+// EnableFakeKernelGTP lets u behave as if Kernel GTP-U is enabled, with the
+// tunnels kept in memory instead of Kernel.
+func EnableFakeKernelGTP(u *UPlaneConn) {
+	u.KernelGTP.Link = &netlink.GTP{LinkAttrs: netlink.LinkAttrs{Name: "gtp-fake", Index: 1}}
+	u.KernelGTP.pdp = &fakePDPHandler{tunnels: map[int][]*Tunnel{}}
+	u.KernelGTP.enabled = true
+}
+
+// EnableFakeKernelGTPWith lets u behave as if it uses another GTP device in the
+// same Kernel as the one of other, which should be enabled with EnableFakeKernelGTP.
+func EnableFakeKernelGTPWith(u, other *UPlaneConn) {
+	idx := other.KernelGTP.Link.Attrs().Index + 1
+	u.KernelGTP.Link = &netlink.GTP{LinkAttrs: netlink.LinkAttrs{Name: "gtp-fake", Index: idx}}
+	u.KernelGTP.pdp = other.KernelGTP.pdp
+	u.KernelGTP.enabled = true
+}
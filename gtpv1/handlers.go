@@ -49,6 +49,9 @@ func newDefaultMsgHandlerMap() *msgHandlerMap {
 			message.MsgTypeEchoRequest:     handleEchoRequest,
 			message.MsgTypeEchoResponse:    handleEchoResponse,
 			message.MsgTypeErrorIndication: handleErrorIndication,
+			message.MsgTypeEndMarker:       handleEndMarker,
+
+			message.MsgTypeSupportedExtensionHeadersNotification: handleSupportedExtensionHeadersNotification,
 		},
 	)
 }
@@ -112,11 +115,15 @@ func handleEchoRequest(c Conn, senderAddr net.Addr, msg message.Message) error {
 func handleEchoResponse(c Conn, senderAddr net.Addr, msg message.Message) error {
 	// this should never happen, as the type should have been assured by
 	// msgHandlerMap before this function is called.
-	if _, ok := msg.(*message.EchoResponse); !ok {
+	res, ok := msg.(*message.EchoResponse)
+	if !ok {
 		return ErrUnexpectedType
 	}
 
-	// do nothing.
+	// pass it to path supervision if the path is monitored.
+	if u, ok := c.(*UPlaneConn); ok {
+		u.handleEchoResponseForPath(senderAddr, res)
+	}
 	return nil
 }
 
@@ -128,10 +135,72 @@ func handleErrorIndication(c Conn, senderAddr net.Addr, msg message.Message) err
 		return ErrUnexpectedType
 	}
 
+	if ind.TEIDDataI == nil {
+		return ErrRequiredIEMissing
+	}
+	teid, err := ind.TEIDDataI.TEID()
+	if err != nil {
+		return err
+	}
+
+	// use GTP-U Peer Address IE to identify the peer if available, as the
+	// sender can be different from the one that the T-PDU was sent to.
+	peer := senderAddr
+	if ind.GTPUPeerAddress != nil {
+		if ip, err := ind.GTPUPeerAddress.IP(); err == nil {
+			peer = &net.UDPAddr{IP: ip, Port: 2152}
+		}
+	}
+
+	u, ok := c.(*UPlaneConn)
+	if ok {
+		u.mu.Lock()
+		fn := u.errIndHandler
+		u.mu.Unlock()
+
+		if fn != nil {
+			fn(teid, peer)
+			return nil
+		}
+	}
+
 	// just log and return
 	logf("Ignored Error Indication: %v", &ErrorIndicatedError{
-		TEID: ind.TEIDDataI.MustTEID(),
-		Peer: ind.GTPUPeerAddress.MustIPAddress(),
+		TEID: teid,
+		Peer: peer.String(),
 	})
 	return nil
 }
+
+// handleEndMarker just discards End Marker, as there's nothing to do for it on
+// the node terminating the tunnel. End Markers on the tunnel relayed by RelayTo
+// are forwarded to the peer without being passed to this handler.
+func handleEndMarker(c Conn, senderAddr net.Addr, msg message.Message) error {
+	// this should never happen, as the type should have been assured by
+	// msgHandlerMap before this function is called.
+	if _, ok := msg.(*message.EndMarker); !ok {
+		return ErrUnexpectedType
+	}
+
+	// do nothing.
+	return nil
+}
+
+func handleSupportedExtensionHeadersNotification(c Conn, senderAddr net.Addr, msg message.Message) error {
+	// this should never happen, as the type should have been assured by
+	// msgHandlerMap before this function is called.
+	notif, ok := msg.(*message.SupportedExtensionHeadersNotification)
+	if !ok {
+		return ErrUnexpectedType
+	}
+
+	if notif.ExtensionHeaderTypeList == nil {
+		return ErrRequiredIEMissing
+	}
+
+	// just log and return
+	logf("Ignored Supported Extension Headers Notification from %s: %x",
+		senderAddr, notif.ExtensionHeaderTypeList.MustExtensionHeaderTypeList(),
+	)
+	return nil
+}
@@ -211,7 +211,10 @@ func (i *IE) MarshalTo(b []byte) error {
 
 	var offset = 1
 	b[0] = i.Type
-	if !i.IsTV() {
+	if i.hasOneOctetLength() {
+		b[1] = uint8(i.Length)
+		offset++
+	} else if !i.IsTV() {
 		binary.BigEndian.PutUint16(b[1:3], i.Length)
 		offset += 2
 	}
@@ -230,7 +233,7 @@ func Parse(b []byte) (*IE, error) {
 
 // UnmarshalBinary sets the values retrieved from byte sequence in GTPv1 IE.
 func (i *IE) UnmarshalBinary(b []byte) error {
-	if len(b) < 2 {
+	if len(b) < 1 {
 		return ErrTooShortToParse
 	}
 
@@ -238,20 +241,36 @@ func (i *IE) UnmarshalBinary(b []byte) error {
 	if i.IsTV() {
 		return decodeTVFromBytes(i, b)
 	}
+	if len(b) < 2 {
+		return ErrTooShortToParse
+	}
+	if i.hasOneOctetLength() {
+		i.Length = uint16(b[1])
+		if int(i.Length)+2 > len(b) {
+			return ErrInvalidLength
+		}
+
+		i.Payload = b[2 : 2+int(i.Length)]
+		return nil
+	}
 	return decodeTLVFromBytes(i, b)
 }
 
 func decodeTVFromBytes(i *IE, b []byte) error {
+	// the length of TV IE is determined by the type. The unknown ones are
+	// considered to have no payload.
+	i.Payload = nil
 	l := len(b)
-	if l < 2 {
+	n := i.MarshalLen()
+	if l < 2 && n > 1 {
 		return ErrTooShortToParse
 	}
-	if i.MarshalLen() > l {
+	if n > l {
 		return ErrInvalidLength
 	}
 
 	i.Length = 0
-	i.Payload = b[1:i.MarshalLen()]
+	i.Payload = b[1:n]
 
 	return nil
 }
@@ -307,6 +326,12 @@ func (i *IE) IsTV() bool {
 	return int(i.Type) < 0x80
 }
 
+// hasOneOctetLength checks if a IE has 1-octet Length field instead of 2-octet one,
+// which is the exception defined in TS 29.281 and 29.060.
+func (i *IE) hasOneOctetLength() bool {
+	return i.Type == ExtensionHeaderTypeList
+}
+
 // MarshalLen returns the serial length of IE.
 func (i *IE) MarshalLen() int {
 	if l, ok := tvLengthMap[int(i.Type)]; ok {
@@ -315,6 +340,9 @@ func (i *IE) MarshalLen() int {
 	if i.Type < 128 {
 		return 1 + len(i.Payload)
 	}
+	if i.hasOneOctetLength() {
+		return 2 + len(i.Payload)
+	}
 
 	return 3 + len(i.Payload)
 }
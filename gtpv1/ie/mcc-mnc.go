@@ -12,20 +12,8 @@ import (
 
 // MCC returns MCC value if type matches.
 func (i *IE) MCC() (string, error) {
-	switch i.Type {
-	case RouteingAreaIdentity:
-		if len(i.Payload) < 2 {
-			return "", io.ErrUnexpectedEOF
-		}
-		return utils.SwappedBytesToStr(i.Payload[0:2], false), nil
-	case UserLocationInformation:
-		if len(i.Payload) < 3 {
-			return "", io.ErrUnexpectedEOF
-		}
-		return utils.SwappedBytesToStr(i.Payload[1:3], false), nil
-	default:
-		return "", &InvalidTypeError{Type: i.Type}
-	}
+	mcc, _, err := i.plmn()
+	return mcc, err
 }
 
 // MustMCC returns MCC in string if type matches.
@@ -37,20 +25,8 @@ func (i *IE) MustMCC() string {
 
 // MNC returns MNC value if type matches.
 func (i *IE) MNC() (string, error) {
-	switch i.Type {
-	case RouteingAreaIdentity:
-		if len(i.Payload) < 2 {
-			return "", io.ErrUnexpectedEOF
-		}
-		return utils.SwappedBytesToStr(i.Payload[1:2], true), nil
-	case UserLocationInformation:
-		if len(i.Payload) < 3 {
-			return "", io.ErrUnexpectedEOF
-		}
-		return utils.SwappedBytesToStr(i.Payload[2:3], true), nil
-	default:
-		return "", &InvalidTypeError{Type: i.Type}
-	}
+	_, mnc, err := i.plmn()
+	return mnc, err
 }
 
 // MustMNC returns MNC in string if type matches.
@@ -59,3 +35,22 @@ func (i *IE) MustMNC() string {
 	v, _ := i.MNC()
 	return v
 }
+
+// plmn decodes the BCD-encoded MCC and MNC, which is in the first 3 octets of
+// RAI, or in the 3 octets after Geographic Location Type of ULI.
+func (i *IE) plmn() (mcc, mnc string, err error) {
+	switch i.Type {
+	case RouteingAreaIdentity:
+		if len(i.Payload) < 3 {
+			return "", "", io.ErrUnexpectedEOF
+		}
+		return utils.DecodePLMN(i.Payload[0:3])
+	case UserLocationInformation:
+		if len(i.Payload) < 4 {
+			return "", "", io.ErrUnexpectedEOF
+		}
+		return utils.DecodePLMN(i.Payload[1:4])
+	default:
+		return "", "", &InvalidTypeError{Type: i.Type}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ie
+
+import "fmt"
+
+var ieNames = map[uint8]string{
+	Cause:                                 "Cause",
+	IMSI:                                  "IMSI",
+	RouteingAreaIdentity:                  "RouteingAreaIdentity",
+	TemporaryLogicalLinkIdentity:          "TemporaryLogicalLinkIdentity",
+	PacketTMSI:                            "PacketTMSI",
+	ReorderingRequired:                    "ReorderingRequired",
+	AuthenticationTriplet:                 "AuthenticationTriplet",
+	MAPCause:                              "MAPCause",
+	PTMSISignature:                        "PTMSISignature",
+	MSValidated:                           "MSValidated",
+	Recovery:                              "Recovery",
+	SelectionMode:                         "SelectionMode",
+	TEIDDataI:                             "TEIDDataI",
+	TEIDCPlane:                            "TEIDCPlane",
+	TEIDDataII:                            "TEIDDataII",
+	TeardownInd:                           "TeardownInd",
+	NSAPI:                                 "NSAPI",
+	RANAPCause:                            "RANAPCause",
+	RABContext:                            "RABContext",
+	RadioPrioritySMS:                      "RadioPrioritySMS",
+	RadioPriority:                         "RadioPriority",
+	PacketFlowID:                          "PacketFlowID",
+	ChargingCharacteristics:               "ChargingCharacteristics",
+	TraceReference:                        "TraceReference",
+	TraceType:                             "TraceType",
+	MSNotReachableReason:                  "MSNotReachableReason",
+	ChargingID:                            "ChargingID",
+	EndUserAddress:                        "EndUserAddress",
+	MMContext:                             "MMContext",
+	PDPContext:                            "PDPContext",
+	AccessPointName:                       "AccessPointName",
+	ProtocolConfigurationOptions:          "ProtocolConfigurationOptions",
+	GSNAddress:                            "GSNAddress",
+	MSISDN:                                "MSISDN",
+	QoSProfile:                            "QoSProfile",
+	AuthenticationQuintuplet:              "AuthenticationQuintuplet",
+	TrafficFlowTemplate:                   "TrafficFlowTemplate",
+	TargetIdentification:                  "TargetIdentification",
+	UTRANTransparentContainer:             "UTRANTransparentContainer",
+	RABSetupInformation:                   "RABSetupInformation",
+	ExtensionHeaderTypeList:               "ExtensionHeaderTypeList",
+	TriggerID:                             "TriggerID",
+	OMCIdentity:                           "OMCIdentity",
+	RANTransparentContainer:               "RANTransparentContainer",
+	PDPContextPrioritization:              "PDPContextPrioritization",
+	AdditionalRABSetupInformation:         "AdditionalRABSetupInformation",
+	SGSNNumber:                            "SGSNNumber",
+	CommonFlags:                           "CommonFlags",
+	APNRestriction:                        "APNRestriction",
+	RadioPriorityLCS:                      "RadioPriorityLCS",
+	RATType:                               "RATType",
+	UserLocationInformation:               "UserLocationInformation",
+	MSTimeZone:                            "MSTimeZone",
+	IMEISV:                                "IMEISV",
+	CAMELChargingInformationContainer:     "CAMELChargingInformationContainer",
+	MBMSUEContext:                         "MBMSUEContext",
+	TemporaryMobileGroupIdentity:          "TemporaryMobileGroupIdentity",
+	RIMRoutingAddress:                     "RIMRoutingAddress",
+	MBMSProtocolConfigurationOptions:      "MBMSProtocolConfigurationOptions",
+	MBMSServiceArea:                       "MBMSServiceArea",
+	SourceRNCPDCPContextInfo:              "SourceRNCPDCPContextInfo",
+	AdditionalTraceInfo:                   "AdditionalTraceInfo",
+	HopCounter:                            "HopCounter",
+	SelectedPLMNID:                        "SelectedPLMNID",
+	MBMSSessionIdentifier:                 "MBMSSessionIdentifier",
+	MBMS2G3GIndicator:                     "MBMS2G3GIndicator",
+	EnhancedNSAPI:                         "EnhancedNSAPI",
+	MBMSSessionDuration:                   "MBMSSessionDuration",
+	AdditionalMBMSTraceInfo:               "AdditionalMBMSTraceInfo",
+	MBMSSessionRepetitionNumber:           "MBMSSessionRepetitionNumber",
+	MBMSTimeToDataTransfer:                "MBMSTimeToDataTransfer",
+	BSSContainer:                          "BSSContainer",
+	CellIdentification:                    "CellIdentification",
+	PDUNumbers:                            "PDUNumbers",
+	BSSGPCause:                            "BSSGPCause",
+	RequiredMBMSBearerCapabilities:        "RequiredMBMSBearerCapabilities",
+	RIMRoutingAddressDiscriminator:        "RIMRoutingAddressDiscriminator",
+	ListOfSetupPFCs:                       "ListOfSetupPFCs",
+	PSHandoverXIDParameters:               "PSHandoverXIDParameters",
+	MSInfoChangeReportingAction:           "MSInfoChangeReportingAction",
+	DirectTunnelFlags:                     "DirectTunnelFlags",
+	CorrelationID:                         "CorrelationID",
+	BearerControlMode:                     "BearerControlMode",
+	MBMSFlowIdentifier:                    "MBMSFlowIdentifier",
+	MBMSIPMulticastDistribution:           "MBMSIPMulticastDistribution",
+	MBMSDistributionAcknowledgement:       "MBMSDistributionAcknowledgement",
+	ReliableInterRATHandoverInfo:          "ReliableInterRATHandoverInfo",
+	RFSPIndex:                             "RFSPIndex",
+	FullyQualifiedDomainName:              "FullyQualifiedDomainName",
+	EvolvedAllocationRetentionPriorityI:   "EvolvedAllocationRetentionPriorityI",
+	EvolvedAllocationRetentionPriorityII:  "EvolvedAllocationRetentionPriorityII",
+	ExtendedCommonFlags:                   "ExtendedCommonFlags",
+	UserCSGInformation:                    "UserCSGInformation",
+	CSGInformationReportingAction:         "CSGInformationReportingAction",
+	CSGID:                                 "CSGID",
+	CSGMembershipIndication:               "CSGMembershipIndication",
+	AggregateMaximumBitRate:               "AggregateMaximumBitRate",
+	UENetworkCapability:                   "UENetworkCapability",
+	UEAMBR:                                "UEAMBR",
+	APNAMBRWithNSAPI:                      "APNAMBRWithNSAPI",
+	GGSNBackOffTime:                       "GGSNBackOffTime",
+	SignallingPriorityIndication:          "SignallingPriorityIndication",
+	SignallingPriorityIndicationWithNSAPI: "SignallingPriorityIndicationWithNSAPI",
+	HigherBitratesThan16MbpsFlag:          "HigherBitratesThan16MbpsFlag",
+	AdditionalMMContextForSRVCC:           "AdditionalMMContextForSRVCC",
+	AdditionalFlagsForSRVCC:               "AdditionalFlagsForSRVCC",
+	STNSR:                                 "STNSR",
+	CMSISDN:                               "CMSISDN",
+	ExtendedRANAPCause:                    "ExtendedRANAPCause",
+	ENodeBID:                              "ENodeBID",
+	SelectionModeWithNSAPI:                "SelectionModeWithNSAPI",
+	ULITimestamp:                          "ULITimestamp",
+	LHNIDWithNSAPI:                        "LHNIDWithNSAPI",
+	CNOperatorSelectionEntity:             "CNOperatorSelectionEntity",
+	UEUsageType:                           "UEUsageType",
+	ExtendedCommonFlagsII:                 "ExtendedCommonFlagsII",
+	NodeIdentifier:                        "NodeIdentifier",
+	CIoTOptimizationsSupportIndication:    "CIoTOptimizationsSupportIndication",
+	SCEFPDNConnection:                     "SCEFPDNConnection",
+	IOVUpdatesCounter:                     "IOVUpdatesCounter",
+	MappedUEUsageType:                     "MappedUEUsageType",
+	UPFunctionSelectionIndicationFlags:    "UPFunctionSelectionIndicationFlags",
+	SpecialIETypeForIETypeExtension:       "SpecialIETypeForIETypeExtension",
+	ChargingGatewayAddress:                "ChargingGatewayAddress",
+	PrivateExtension:                      "PrivateExtension",
+}
+
+// Name returns the name of the type of IE, e.g., "IMSI".
+func (i *IE) Name() string {
+	if n, ok := ieNames[i.Type]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", i.Type)
+}
@@ -0,0 +1,143 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ie
+
+import (
+	"time"
+)
+
+// Value returns the value of IE decoded with the accessor of its type, which is
+// meant to be used for rendering IE in human readable form.
+//
+// The type of returned value varies by the type of IE; e.g., string for IMSI,
+// uint32 for TEID, and the MCC, MNC, LAC and RAC for Routeing Area Identity.
+// It returns nil without error for the IEs that have no accessor.
+func (i *IE) Value() (interface{}, error) {
+	switch i.Type {
+	case Cause:
+		return i.Cause()
+	case IMSI:
+		return i.IMSI()
+	case RouteingAreaIdentity, UserLocationInformation:
+		return locationValue(i)
+	case PacketTMSI:
+		return i.PacketTMSI()
+	case ReorderingRequired:
+		return i.ReorderingRequired(), nil
+	case MAPCause:
+		return i.MAPCause()
+	case PTMSISignature:
+		return i.PTMSISignature()
+	case MSValidated:
+		return i.MSValidated(), nil
+	case Recovery:
+		return i.Recovery()
+	case SelectionMode:
+		return i.SelectionMode()
+	case TEIDDataI, TEIDCPlane, TEIDDataII:
+		return i.TEID()
+	case TeardownInd:
+		return i.TeardownInd(), nil
+	case NSAPI:
+		return i.NSAPI()
+	case RANAPCause:
+		return i.RANAPCause()
+	case ChargingID:
+		return i.ChargingID()
+	case EndUserAddress:
+		return endUserAddressValue(i)
+	case AccessPointName:
+		return i.AccessPointName()
+	case ProtocolConfigurationOptions:
+		return i.ProtocolConfigurationOptions()
+	case GSNAddress:
+		return i.IPAddress()
+	case MSISDN:
+		return i.MSISDN()
+	case CommonFlags:
+		return i.CommonFlags()
+	case APNRestriction:
+		return i.APNRestriction()
+	case RATType:
+		return i.RATType()
+	case MSTimeZone:
+		return timeZoneValue(i)
+	case IMEISV:
+		return i.IMEISV()
+	case ULITimestamp:
+		return timestampValue(i)
+	case ExtensionHeaderTypeList:
+		return i.ExtensionHeaderTypeList()
+	case PrivateExtension:
+		return i.PrivateExtension()
+	default:
+		return nil, nil
+	}
+}
+
+// locationValue returns the location identities in RAI or ULI IE.
+func locationValue(i *IE) (interface{}, error) {
+	mcc, err := i.MCC()
+	if err != nil {
+		return nil, err
+	}
+	mnc, err := i.MNC()
+	if err != nil {
+		return nil, err
+	}
+	lac, err := i.LAC()
+	if err != nil {
+		return nil, err
+	}
+
+	v := map[string]interface{}{"MCC": mcc, "MNC": mnc, "LAC": lac}
+	if rac, err := i.RAC(); err == nil {
+		v["RAC"] = rac
+	}
+	if cgi, err := i.CGI(); err == nil {
+		v["CI"] = cgi
+	}
+	if sac, err := i.SAC(); err == nil {
+		v["SAC"] = sac
+	}
+	return v, nil
+}
+
+func endUserAddressValue(i *IE) (interface{}, error) {
+	org, err := i.PDPTypeOrganization()
+	if err != nil {
+		return nil, err
+	}
+	num, err := i.PDPTypeNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	v := map[string]interface{}{"PDPTypeOrganization": org, "PDPTypeNumber": num}
+	if ip, err := i.IPAddress(); err == nil {
+		v["IPAddress"] = ip
+	}
+	return v, nil
+}
+
+func timeZoneValue(i *IE) (interface{}, error) {
+	tz, err := i.TimeZone()
+	if err != nil {
+		return nil, err
+	}
+	ds, err := i.DaylightSaving()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"TimeZone": tz.String(), "DaylightSaving": ds}, nil
+}
+
+func timestampValue(i *IE) (interface{}, error) {
+	ts, err := i.Timestamp()
+	if err != nil {
+		return nil, err
+	}
+	return ts.UTC().Format(time.RFC3339), nil
+}
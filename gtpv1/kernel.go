@@ -0,0 +1,77 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Tunnel is a GTP-U tunnel (PDP context) handled by Linux Kernel GTP-U.
+type Tunnel struct {
+	// Version is the version of GTP used for the tunnel, 0 or 1.
+	// GTPv1 is used if not specified by AddTunnel and AddTunnelOverride.
+	Version uint32
+
+	// PeerAddress is the address of the peer GSN, either IPv4 or IPv6.
+	PeerAddress net.IP
+
+	// MSAddress is the address of the subscriber, either IPv4 or IPv6.
+	MSAddress net.IP
+
+	// Family is the address family of the tunnel(syscall.AF_INET or syscall.AF_INET6).
+	// If zero, it is derived from MSAddress.
+	Family uint8
+
+	// ITEI and OTEI are the incoming and outgoing TEIDs, used only with GTPv1.
+	ITEI, OTEI uint32
+
+	// TID and Flow are the Tunnel ID and the Flow Label, used only with GTPv0.
+	TID  uint64
+	Flow uint16
+}
+
+// AddressFamily returns the address family of the tunnel.
+//
+// If Family is not set explicitly, it returns syscall.AF_INET6 if MSAddress is
+// an IPv6 address, and syscall.AF_INET otherwise.
+func (t *Tunnel) AddressFamily() uint8 {
+	if t.Family != 0 {
+		return t.Family
+	}
+	if t.MSAddress != nil && t.MSAddress.To4() == nil {
+		return syscall.AF_INET6
+	}
+	return syscall.AF_INET
+}
+
+// String returns the Tunnel in human-readable format.
+func (t *Tunnel) String() string {
+	if t.Version == 0 {
+		return fmt.Sprintf(
+			"{Version: 0, TID: %d, Flow: %d, MS-Address: %s, Peer-Address: %s}",
+			t.TID, t.Flow, t.MSAddress, t.PeerAddress,
+		)
+	}
+	return fmt.Sprintf(
+		"{Version: %d, TEI: %#x/%#x, MS-Address: %s, Peer-Address: %s}",
+		t.Version, t.ITEI, t.OTEI, t.MSAddress, t.PeerAddress,
+	)
+}
+
+// pdpHandler is the set of operations on the tunnels in Kernel GTP-U.
+//
+// The default one talks to Kernel via generic netlink, and it can be
+// replaced with a fake one for testing.
+type pdpHandler interface {
+	add(link netlink.Link, t *Tunnel, replace bool) error
+	del(link netlink.Link, t *Tunnel) error
+	list(link netlink.Link) ([]*Tunnel, error)
+	getByITEI(link netlink.Link, itei uint32, family uint8) (*Tunnel, error)
+	getByMSAddress(link netlink.Link, msIP net.IP) (*Tunnel, error)
+}
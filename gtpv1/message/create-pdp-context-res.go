@@ -134,6 +134,9 @@ func (c *CreatePDPContextResponse) MarshalTo(b []byte) error {
 	if len(b) < c.MarshalLen() {
 		return ErrTooShortToMarshal
 	}
+	if c.Header.Payload != nil {
+		c.Header.Payload = nil
+	}
 	c.Header.Payload = make([]byte, c.MarshalLen()-c.Header.MarshalLen())
 
 	offset := 0
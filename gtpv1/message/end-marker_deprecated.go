@@ -0,0 +1,23 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message
+
+import "log"
+
+// SerializeTo serializes EndMarker into bytes given as b.
+//
+// Deprecated: use EndMarker.MarshalTo instead.
+func (e *EndMarker) SerializeTo(b []byte) error {
+	log.Println("EndMarker.SerializeTo is deprecated. use EndMarker.MarshalTo instead")
+	return e.MarshalTo(b)
+}
+
+// DecodeFromBytes decodes bytes as EndMarker.
+//
+// Deprecated: use EndMarker.UnmarshalBinary instead.
+func (e *EndMarker) DecodeFromBytes(b []byte) error {
+	log.Println("EndMarker.DecodeFromBytes is deprecated. use EndMarker.UnmarshalBinary instead")
+	return e.UnmarshalBinary(b)
+}
@@ -0,0 +1,97 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package message_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wmnsk/go-gtp/gtpv1/message"
+)
+
+// The seed corpus is in testdata/fuzz, which is generated from the test vectors
+// in the other tests in this package. Parse calls all the other ParseXxx for the
+// typed messages by the message type in the input.
+
+func FuzzParse(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		m, err := message.Parse(b)
+		if err != nil {
+			return
+		}
+		_ = m.MessageTypeName()
+
+		// once serialized, the message should be decoded and serialized into
+		// the same bytes again.
+		b1, err := message.Marshal(m)
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", m, err)
+		}
+		m2, err := message.Parse(b1)
+		if err != nil {
+			t.Fatalf("failed to parse %x: %s", b1, err)
+		}
+		b2, err := message.Marshal(m2)
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", m2, err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("round trip mismatch:\n got: %x\nwant: %x", b2, b1)
+		}
+	})
+}
+
+func FuzzParseHeader(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		h, err := message.ParseHeader(b)
+		if err != nil {
+			return
+		}
+
+		b1, err := h.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", h, err)
+		}
+		h2, err := message.ParseHeader(b1)
+		if err != nil {
+			t.Fatalf("failed to parse %x: %s", b1, err)
+		}
+		b2, err := h2.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", h2, err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("round trip mismatch:\n got: %x\nwant: %x", b2, b1)
+		}
+	})
+}
+
+func FuzzParseGeneric(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		g, err := message.ParseGeneric(b)
+		if err != nil {
+			return
+		}
+
+		b1, err := g.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", g, err)
+		}
+		g2, err := message.ParseGeneric(b1)
+		if err != nil {
+			t.Fatalf("failed to parse %x: %s", b1, err)
+		}
+		b2, err := g2.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", g2, err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("round trip mismatch:\n got: %x\nwant: %x", b2, b1)
+		}
+	})
+}
@@ -91,7 +91,7 @@ func ParseHeader(b []byte) (*Header, error) {
 // UnmarshalBinary sets the values retrieved from byte sequence in GTPv1 header.
 func (h *Header) UnmarshalBinary(b []byte) error {
 	l := len(b)
-	if l < 11 {
+	if l < fixedHeaderSize {
 		return ErrTooShortToParse
 	}
 	var offset = 4
@@ -102,7 +102,7 @@ func (h *Header) UnmarshalBinary(b []byte) error {
 	h.TEID = binary.BigEndian.Uint32(b[4:8])
 	offset += 4
 	if h.HasSequence() {
-		if h.Length < seqSize {
+		if h.Length < seqSize || l < fixedHeaderSize+seqSize {
 			return ErrTooShortToParse
 		}
 		h.SequenceNumber = binary.BigEndian.Uint16(b[offset : offset+2])
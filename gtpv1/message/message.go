@@ -40,7 +40,7 @@ const (
 	MsgTypePDUNotificationResponse
 	MsgTypePDUNotificationRejectRequest
 	MsgTypePDUNotificationRejectResponse
-	_
+	MsgTypeSupportedExtensionHeadersNotification
 	MsgTypeSendRoutingInfoRequest
 	MsgTypeSendRoutingInfoResponse
 	MsgTypeFailureReportRequest
@@ -72,6 +72,7 @@ const (
 	MsgTypeSGSNContextAcknowledge
 	MsgTypeDataRecordTransferRequest  uint8 = 240
 	MsgTypeDataRecordTransferResponse uint8 = 241
+	MsgTypeEndMarker                  uint8 = 254
 	MsgTypeTPDU                       uint8 = 255
 )
 
@@ -106,6 +107,10 @@ func Marshal(g Message) ([]byte, error) {
 
 // Parse decodes the given bytes as Message.
 func Parse(b []byte) (Message, error) {
+	if len(b) < fixedHeaderSize {
+		return nil, ErrTooShortToParse
+	}
+
 	var m Message
 
 	switch b[1] {
@@ -156,6 +161,10 @@ func Parse(b []byte) (Message, error) {
 		m = &PduNotificationRejectReq{}
 	case MsgTypePduNotificationRejectResponse:
 		m = &PduNotificationRejectRes{}
+	*/
+	case MsgTypeSupportedExtensionHeadersNotification:
+		m = &SupportedExtensionHeadersNotification{}
+	/* XXX - Implement!
 	case MsgTypeSendRoutingInfoRequest:
 		m = &SendRoutingInfoReq{}
 	case MsgTypeSendRoutingInfoResponse:
@@ -183,6 +192,8 @@ func Parse(b []byte) (Message, error) {
 	case MsgTypeDataRecordTransferResponse:
 		m = &DataRecordTransferRes{}
 	*/
+	case MsgTypeEndMarker:
+		m = &EndMarker{}
 	case MsgTypeTPDU:
 		m = &TPDU{}
 	default:
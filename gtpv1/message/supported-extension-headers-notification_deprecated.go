@@ -0,0 +1,23 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message
+
+import "log"
+
+// SerializeTo serializes SupportedExtensionHeadersNotification into bytes given as b.
+//
+// Deprecated: use SupportedExtensionHeadersNotification.MarshalTo instead.
+func (s *SupportedExtensionHeadersNotification) SerializeTo(b []byte) error {
+	log.Println("SupportedExtensionHeadersNotification.SerializeTo is deprecated. use SupportedExtensionHeadersNotification.MarshalTo instead")
+	return s.MarshalTo(b)
+}
+
+// DecodeFromBytes decodes bytes as SupportedExtensionHeadersNotification.
+//
+// Deprecated: use SupportedExtensionHeadersNotification.UnmarshalBinary instead.
+func (s *SupportedExtensionHeadersNotification) DecodeFromBytes(b []byte) error {
+	log.Println("SupportedExtensionHeadersNotification.DecodeFromBytes is deprecated. use SupportedExtensionHeadersNotification.UnmarshalBinary instead")
+	return s.UnmarshalBinary(b)
+}
@@ -0,0 +1,42 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message_test
+
+import (
+	"testing"
+
+	"github.com/wmnsk/go-gtp/gtpv1"
+	"github.com/wmnsk/go-gtp/gtpv1/ie"
+	"github.com/wmnsk/go-gtp/gtpv1/message"
+	"github.com/wmnsk/go-gtp/gtpv1/testutils"
+)
+
+func TestSupportedExtensionHeadersNotification(t *testing.T) {
+	cases := []testutils.TestCase{
+		{
+			Description: "Normal",
+			Structured: message.NewSupportedExtensionHeadersNotification(
+				0, testutils.TestBearerInfo.Seq,
+				ie.NewExtensionHeaderTypeList(gtpv1.ExtHeaderTypePDUSessionContainer, gtpv1.ExtHeaderTypeUDPPort),
+			),
+			Serialized: []byte{
+				// Header
+				0x32, 0x1f, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x01, 0x00, 0x00,
+				// Extension Header Type List
+				0x8d, 0x02, 0x85, 0x40,
+			},
+		},
+	}
+
+	testutils.Run(t, cases, func(b []byte) (testutils.Serializable, error) {
+		v, err := message.ParseSupportedExtensionHeadersNotification(b)
+		if err != nil {
+			return nil, err
+		}
+		v.Payload = nil
+		return v, nil
+	})
+}
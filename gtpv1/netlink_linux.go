@@ -0,0 +1,235 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// GTP netlink attributes added to Kernel after the ones known by netlink package.
+const (
+	gtpAttrPeerAddr6 = nl.GENL_GTP_ATTR_PAD + 1 + iota
+	gtpAttrMSAddr6
+	gtpAttrFamily
+)
+
+// genlPDPHandler manages the tunnels in Kernel GTP-U via generic netlink.
+//
+// This is used instead of netlink.GTPPDP* funcs to support IPv6 addresses,
+// the address family, and in-place modification of the existing tunnels.
+type genlPDPHandler struct{}
+
+func (genlPDPHandler) newRequest(cmd uint8, flags int) (*nl.NetlinkRequest, error) {
+	f, err := netlink.GenlFamilyGet(nl.GENL_GTP_NAME)
+	if err != nil {
+		return nil, err
+	}
+
+	req := nl.NewNetlinkRequest(int(f.ID), flags)
+	req.AddData(&nl.Genlmsg{
+		Command: cmd,
+		Version: nl.GENL_GTP_VERSION,
+	})
+	return req, nil
+}
+
+func (h genlPDPHandler) add(link netlink.Link, t *Tunnel, replace bool) error {
+	flags := syscall.NLM_F_ACK
+	if !replace {
+		flags |= syscall.NLM_F_EXCL
+	}
+	req, err := h.newRequest(nl.GENL_GTP_CMD_NEWPDP, flags)
+	if err != nil {
+		return err
+	}
+
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_VERSION, nl.Uint32Attr(t.Version)))
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_LINK, nl.Uint32Attr(uint32(link.Attrs().Index))))
+	addAddrAttrs(req, t)
+
+	switch t.Version {
+	case 0:
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_TID, nl.Uint64Attr(t.TID)))
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_FLOW, nl.Uint16Attr(t.Flow)))
+	case 1:
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_I_TEI, nl.Uint32Attr(t.ITEI)))
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_O_TEI, nl.Uint32Attr(t.OTEI)))
+	default:
+		return fmt.Errorf("unsupported GTP version: %d", t.Version)
+	}
+
+	_, err = req.Execute(syscall.NETLINK_GENERIC, 0)
+	return err
+}
+
+func (h genlPDPHandler) del(link netlink.Link, t *Tunnel) error {
+	req, err := h.newRequest(nl.GENL_GTP_CMD_DELPDP, syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	if err != nil {
+		return err
+	}
+
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_VERSION, nl.Uint32Attr(t.Version)))
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_LINK, nl.Uint32Attr(uint32(link.Attrs().Index))))
+	if t.AddressFamily() == syscall.AF_INET6 {
+		req.AddData(nl.NewRtAttr(gtpAttrFamily, nl.Uint8Attr(syscall.AF_INET6)))
+	}
+
+	switch t.Version {
+	case 0:
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_TID, nl.Uint64Attr(t.TID)))
+	case 1:
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_I_TEI, nl.Uint32Attr(t.ITEI)))
+	default:
+		return fmt.Errorf("unsupported GTP version: %d", t.Version)
+	}
+
+	_, err = req.Execute(syscall.NETLINK_GENERIC, 0)
+	return err
+}
+
+// list returns the tunnels on link. Kernel dumps the tunnels on all the GTP devices,
+// so the ones on the other links are filtered out here.
+func (h genlPDPHandler) list(link netlink.Link) ([]*Tunnel, error) {
+	req, err := h.newRequest(nl.GENL_GTP_CMD_GETPDP, syscall.NLM_F_DUMP)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := req.Execute(syscall.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parseTunnels(msgs, link.Attrs().Index)
+}
+
+// getByITEI returns the tunnel with itei in the address family. Kernel looks up
+// the tunnels in AF_INET if the family is not given.
+func (h genlPDPHandler) getByITEI(link netlink.Link, itei uint32, family uint8) (*Tunnel, error) {
+	req, err := h.newRequest(nl.GENL_GTP_CMD_GETPDP, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_VERSION, nl.Uint32Attr(1)))
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_LINK, nl.Uint32Attr(uint32(link.Attrs().Index))))
+	if family == syscall.AF_INET6 {
+		req.AddData(nl.NewRtAttr(gtpAttrFamily, nl.Uint8Attr(syscall.AF_INET6)))
+	}
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_I_TEI, nl.Uint32Attr(itei)))
+	return getTunnel(req)
+}
+
+func (h genlPDPHandler) getByMSAddress(link netlink.Link, msIP net.IP) (*Tunnel, error) {
+	req, err := h.newRequest(nl.GENL_GTP_CMD_GETPDP, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_VERSION, nl.Uint32Attr(0)))
+	req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_LINK, nl.Uint32Attr(uint32(link.Attrs().Index))))
+	if v4 := msIP.To4(); v4 != nil {
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_MS_ADDRESS, []byte(v4)))
+	} else {
+		req.AddData(nl.NewRtAttr(gtpAttrFamily, nl.Uint8Attr(syscall.AF_INET6)))
+		req.AddData(nl.NewRtAttr(gtpAttrMSAddr6, []byte(msIP.To16())))
+	}
+	return getTunnel(req)
+}
+
+// addAddrAttrs adds the peer and MS address attributes to req.
+//
+// Family and IPv6 attributes are added only when needed so that the
+// requests for IPv4 tunnels work with older Kernel as well.
+func addAddrAttrs(req *nl.NetlinkRequest, t *Tunnel) {
+	family := t.AddressFamily()
+	v4Peer := t.PeerAddress.To4()
+	if family == syscall.AF_INET6 || v4Peer == nil {
+		req.AddData(nl.NewRtAttr(gtpAttrFamily, nl.Uint8Attr(family)))
+	}
+
+	if v4Peer != nil {
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_PEER_ADDRESS, []byte(v4Peer)))
+	} else {
+		req.AddData(nl.NewRtAttr(gtpAttrPeerAddr6, []byte(t.PeerAddress.To16())))
+	}
+
+	if family == syscall.AF_INET6 {
+		req.AddData(nl.NewRtAttr(gtpAttrMSAddr6, []byte(t.MSAddress.To16())))
+	} else {
+		req.AddData(nl.NewRtAttr(nl.GENL_GTP_ATTR_MS_ADDRESS, []byte(t.MSAddress.To4())))
+	}
+}
+
+func getTunnel(req *nl.NetlinkRequest) (*Tunnel, error) {
+	msgs, err := req.Execute(syscall.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnels, err := parseTunnels(msgs, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(tunnels) != 1 {
+		return nil, errors.New("invalid response for GTP GETPDP command")
+	}
+	return tunnels[0], nil
+}
+
+// parseTunnels decodes the tunnels in msgs. If link is not zero, the tunnels on the
+// other links are skipped.
+func parseTunnels(msgs [][]byte, link int) ([]*Tunnel, error) {
+	native := nl.NativeEndian()
+
+	tunnels := make([]*Tunnel, 0, len(msgs))
+	for _, m := range msgs {
+		if len(m) < nl.SizeofGenlmsg {
+			return nil, errors.New("too short message for GTP PDP")
+		}
+		attrs, err := nl.ParseRouteAttr(m[nl.SizeofGenlmsg:])
+		if err != nil {
+			return nil, err
+		}
+
+		// the tunnel without the link is kept, as it cannot be told.
+		t := &Tunnel{}
+		linkIndex := link
+		for _, a := range attrs {
+			switch int(a.Attr.Type) {
+			case nl.GENL_GTP_ATTR_LINK:
+				linkIndex = int(native.Uint32(a.Value))
+			case nl.GENL_GTP_ATTR_VERSION:
+				t.Version = native.Uint32(a.Value)
+			case nl.GENL_GTP_ATTR_TID:
+				t.TID = native.Uint64(a.Value)
+			case nl.GENL_GTP_ATTR_PEER_ADDRESS, gtpAttrPeerAddr6:
+				t.PeerAddress = net.IP(a.Value)
+			case nl.GENL_GTP_ATTR_MS_ADDRESS, gtpAttrMSAddr6:
+				t.MSAddress = net.IP(a.Value)
+			case nl.GENL_GTP_ATTR_FLOW:
+				t.Flow = native.Uint16(a.Value)
+			case nl.GENL_GTP_ATTR_I_TEI:
+				t.ITEI = native.Uint32(a.Value)
+			case nl.GENL_GTP_ATTR_O_TEI:
+				t.OTEI = native.Uint32(a.Value)
+			case gtpAttrFamily:
+				if len(a.Value) > 0 {
+					t.Family = a.Value[0]
+				}
+			}
+		}
+		if link != 0 && linkIndex != link {
+			continue
+		}
+		tunnels = append(tunnels, t)
+	}
+	return tunnels, nil
+}
@@ -0,0 +1,300 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/wmnsk/go-gtp/gtpv1/ie"
+	"github.com/wmnsk/go-gtp/gtpv1/message"
+)
+
+// Default values used in path supervision.
+const (
+	DefaultEchoInterval = 60 * time.Second
+	DefaultT3Response   = 3 * time.Second
+	DefaultN3Requests   = 5
+)
+
+// PathMonitorConfig is a configuration of the supervision of GTP-U path.
+type PathMonitorConfig struct {
+	// Interval is the interval of sending Echo Request to the peer.
+	// TS 29.281 recommends not to send it more often than every 60 seconds.
+	Interval time.Duration
+
+	// T3 is the time to wait for Echo Response before retransmitting Echo Request.
+	T3 time.Duration
+
+	// N3 is the maximum number of retransmissions of Echo Request. The path is
+	// considered to be down if no response comes after N3 times of retransmission.
+	N3 int
+}
+
+func (p *PathMonitorConfig) withDefaults() *PathMonitorConfig {
+	cfg := &PathMonitorConfig{
+		Interval: DefaultEchoInterval,
+		T3:       DefaultT3Response,
+		N3:       DefaultN3Requests,
+	}
+	if p == nil {
+		return cfg
+	}
+
+	if p.Interval > 0 {
+		cfg.Interval = p.Interval
+	}
+	if p.T3 > 0 {
+		cfg.T3 = p.T3
+	}
+	if p.N3 > 0 {
+		cfg.N3 = p.N3
+	}
+	return cfg
+}
+
+type echoResult struct {
+	seq      uint16
+	recovery *ie.IE
+}
+
+type path struct {
+	raddr  net.Addr
+	cfg    *PathMonitorConfig
+	stopCh chan struct{}
+	respCh chan *echoResult
+
+	mu             sync.Mutex
+	isActive       bool
+	restartCounter uint8
+	counterKnown   bool
+}
+
+// StartPathMonitoring starts the supervision of the GTP-U path to raddr by sending
+// Echo Request periodically, in the background.
+//
+// If the Echo Response does not come after retransmitting Echo Request N3 times with
+// T3 interval, the path is considered to be down and the func registered with
+// OnPathFailure is called. The Restart Counter in the Recovery IE of Echo Response is
+// also tracked, and the func registered with OnPeerRestart is called if it changes.
+// The zero values in cfg are replaced with the default ones, and nil cfg means all the
+// values are the default.
+//
+// The monitoring continues until StopPathMonitoring is called or UPlaneConn is closed.
+func (u *UPlaneConn) StartPathMonitoring(raddr net.Addr, cfg *PathMonitorConfig) error {
+	if u.pktConn == nil {
+		return ErrConnNotOpened
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.pathMap == nil {
+		u.pathMap = map[string]*path{}
+	}
+	if _, ok := u.pathMap[raddr.String()]; ok {
+		return errors.New("path monitoring is already started for " + raddr.String())
+	}
+
+	p := &path{
+		raddr:    raddr,
+		cfg:      cfg.withDefaults(),
+		stopCh:   make(chan struct{}),
+		respCh:   make(chan *echoResult, 1),
+		isActive: true,
+	}
+	u.pathMap[raddr.String()] = p
+
+	go u.monitorPath(p)
+	return nil
+}
+
+// StopPathMonitoring stops the supervision of the GTP-U path to raddr.
+func (u *UPlaneConn) StopPathMonitoring(raddr net.Addr) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	p, ok := u.pathMap[raddr.String()]
+	if !ok {
+		return
+	}
+	close(p.stopCh)
+	delete(u.pathMap, raddr.String())
+}
+
+// IsPathActive reports whether the GTP-U path to raddr is active or not.
+// It returns error if the path is not monitored.
+func (u *UPlaneConn) IsPathActive(raddr net.Addr) (bool, error) {
+	u.mu.Lock()
+	p, ok := u.pathMap[raddr.String()]
+	u.mu.Unlock()
+	if !ok {
+		return false, errors.New("path is not monitored: " + raddr.String())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.isActive, nil
+}
+
+// OnPathFailure registers fn to be called when the GTP-U path monitored by
+// StartPathMonitoring is considered to be down.
+func (u *UPlaneConn) OnPathFailure(fn func(peer net.Addr)) {
+	u.mu.Lock()
+	u.pathFailureHandler = fn
+	u.mu.Unlock()
+}
+
+// OnPeerRestart registers fn to be called when the Restart Counter in the Recovery IE
+// of Echo Response from the peer monitored by StartPathMonitoring is changed.
+//
+// Note that the Restart Counter is always zero in GTP-U as defined in TS 29.281,
+// while some GTPv1 nodes set the actual value.
+func (u *UPlaneConn) OnPeerRestart(fn func(peer net.Addr, restartCounter uint8)) {
+	u.mu.Lock()
+	u.peerRestartHandler = fn
+	u.mu.Unlock()
+}
+
+func (u *UPlaneConn) monitorPath(p *path) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		res, ok := u.probePath(p)
+		if ok {
+			u.pathActivated(p, res)
+		} else {
+			u.pathFailed(p)
+		}
+
+		select {
+		case <-p.stopCh:
+			return
+		case <-u.closed():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probePath sends Echo Request to the peer and waits for Echo Response, with
+// retransmissions up to N3 times.
+func (u *UPlaneConn) probePath(p *path) (*echoResult, bool) {
+	for try := 0; try <= p.cfg.N3; try++ {
+		seq := u.nextEchoSequence()
+		if err := u.sendEchoRequest(p.raddr, seq); err != nil {
+			logf("failed to send Echo Request to %s: %v", p.raddr, err)
+		}
+
+		timer := time.NewTimer(p.cfg.T3)
+	wait:
+		for {
+			select {
+			case <-p.stopCh:
+				timer.Stop()
+				return nil, false
+			case <-u.closed():
+				timer.Stop()
+				return nil, false
+			case res := <-p.respCh:
+				// ignore the late response to the older request.
+				if res.seq != seq {
+					continue
+				}
+				timer.Stop()
+				return res, true
+			case <-timer.C:
+				break wait
+			}
+		}
+	}
+	return nil, false
+}
+
+func (u *UPlaneConn) pathActivated(p *path, res *echoResult) {
+	p.mu.Lock()
+	p.isActive = true
+
+	var restarted bool
+	if res.recovery != nil {
+		if counter, err := res.recovery.Recovery(); err == nil {
+			restarted = p.counterKnown && counter != p.restartCounter
+			p.restartCounter = counter
+			p.counterKnown = true
+		}
+	}
+	counter := p.restartCounter
+	p.mu.Unlock()
+
+	if !restarted {
+		return
+	}
+
+	u.mu.Lock()
+	fn := u.peerRestartHandler
+	u.mu.Unlock()
+	if fn != nil {
+		fn(p.raddr, counter)
+	}
+}
+
+func (u *UPlaneConn) pathFailed(p *path) {
+	p.mu.Lock()
+	wasActive := p.isActive
+	p.isActive = false
+	p.mu.Unlock()
+
+	// notify only when the path goes down.
+	if !wasActive {
+		return
+	}
+
+	u.mu.Lock()
+	fn := u.pathFailureHandler
+	u.mu.Unlock()
+	if fn != nil {
+		fn(p.raddr)
+		return
+	}
+	logf("GTP-U path to %s is down", p.raddr)
+}
+
+// handleEchoResponseForPath passes the Echo Response to the path monitor if exists.
+func (u *UPlaneConn) handleEchoResponseForPath(senderAddr net.Addr, res *message.EchoResponse) {
+	u.mu.Lock()
+	p, ok := u.pathMap[senderAddr.String()]
+	u.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	select {
+	case p.respCh <- &echoResult{seq: res.Sequence(), recovery: res.Recovery}:
+	default:
+		// discard if the previous one is not consumed yet.
+	}
+}
+
+func (u *UPlaneConn) nextEchoSequence() uint16 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.echoSequence++
+	return u.echoSequence
+}
+
+func (u *UPlaneConn) sendEchoRequest(raddr net.Addr, seq uint16) error {
+	b, err := message.NewEchoRequest(seq, ie.NewRecovery(u.Restarts())).Marshal()
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.WriteTo(b, raddr); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wmnsk/go-gtp/gtpv1"
+	"github.com/wmnsk/go-gtp/gtpv1/ie"
+	"github.com/wmnsk/go-gtp/gtpv1/message"
+)
+
+func TestPathMonitoring(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.51:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	uConn := gtpv1.NewUPlaneConn(laddr)
+	go func() {
+		if err := uConn.ListenAndServe(ctx); err != nil {
+			t.Errorf("failed to listen on %s: %s", laddr, err)
+			return
+		}
+	}()
+	// XXX - waiting for conn to be well-prepared, should consider better way.
+	time.Sleep(100 * time.Millisecond)
+
+	peer, err := net.ListenPacket("udp", "127.0.0.52:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+
+	var (
+		mu         sync.Mutex
+		responding = true
+		recovery   uint8
+	)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, raddr, err := peer.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			msg, err := message.Parse(buf[:n])
+			if err != nil {
+				continue
+			}
+
+			mu.Lock()
+			ok, r := responding, recovery
+			mu.Unlock()
+			if !ok {
+				continue
+			}
+
+			b, err := message.NewEchoResponse(msg.Sequence(), ie.NewRecovery(r)).Marshal()
+			if err != nil {
+				continue
+			}
+			if _, err := peer.WriteTo(b, raddr); err != nil {
+				return
+			}
+		}
+	}()
+
+	restartCh := make(chan uint8, 1)
+	failureCh := make(chan net.Addr, 1)
+	uConn.OnPeerRestart(func(peer net.Addr, restartCounter uint8) {
+		restartCh <- restartCounter
+	})
+	uConn.OnPathFailure(func(peer net.Addr) {
+		failureCh <- peer
+	})
+
+	if err := uConn.StartPathMonitoring(peer.LocalAddr(), &gtpv1.PathMonitorConfig{
+		Interval: 100 * time.Millisecond,
+		T3:       50 * time.Millisecond,
+		N3:       1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer uConn.StopPathMonitoring(peer.LocalAddr())
+
+	// the change of Restart Counter should be notified.
+	// XXX - waiting for the first Echo exchange to be done, should consider better way.
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	recovery = 1
+	mu.Unlock()
+	select {
+	case got := <-restartCh:
+		if got != 1 {
+			t.Errorf("got unexpected restart counter: %d", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out while waiting for peer restart to be detected")
+	}
+
+	// no response should be notified as a path failure.
+	mu.Lock()
+	responding = false
+	mu.Unlock()
+	select {
+	case got := <-failureCh:
+		if got.String() != peer.LocalAddr().String() {
+			t.Errorf("got unexpected peer: %s", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out while waiting for path failure to be detected")
+	}
+
+	active, err := uConn.IsPathActive(peer.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active {
+		t.Error("path should not be active after failure")
+	}
+}
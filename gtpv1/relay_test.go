@@ -8,10 +8,62 @@ import (
 	"context"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/wmnsk/go-gtp/gtpv1"
+	"github.com/wmnsk/go-gtp/gtpv1/message"
 )
 
+// serveUPlane starts serving on conn, and waits until conn handles an Echo Request,
+// so that the tests don't use conn before the underlying connection is created.
+//
+// The handler of Echo Request is replaced not to respond to it.
+func serveUPlane(ctx context.Context, t *testing.T, conn *gtpv1.UPlaneConn, addr net.Addr) {
+	t.Helper()
+
+	ready := make(chan struct{}, 1)
+	conn.AddHandler(message.MsgTypeEchoRequest, func(gtpv1.Conn, net.Addr, message.Message) error {
+		select {
+		case ready <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	failed := make(chan struct{})
+	go func() {
+		if err := conn.ListenAndServe(ctx); err != nil {
+			t.Errorf("failed to listen on %s: %s", addr, err)
+			close(failed)
+		}
+	}()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	b, err := message.NewEchoRequest(0).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(3 * time.Second)
+	for {
+		if _, err := pc.WriteTo(b, addr); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-ready:
+			return
+		case <-failed:
+			t.FailNow()
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("timed out while waiting for %s to be ready", addr)
+		}
+	}
+}
+
 func TestRelay(t *testing.T) {
 	leftAddr, err := net.ResolveUDPAddr("udp", "127.0.0.11:2152")
 	if err != nil {
@@ -50,3 +102,78 @@ func TestRelay(t *testing.T) {
 
 	// TODO: add tests to check if the traffic goes through conns.
 }
+
+func TestSwitchRelay(t *testing.T) {
+	s5Addr, err := net.ResolveUDPAddr("udp", "127.0.0.31:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1Addr, err := net.ResolveUDPAddr("udp", "127.0.0.32:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s5Conn := gtpv1.NewUPlaneConn(s5Addr)
+	serveUPlane(ctx, t, s5Conn, s5Addr)
+	s1Conn := gtpv1.NewUPlaneConn(s1Addr)
+	serveUPlane(ctx, t, s1Conn, s1Addr)
+
+	var peers []net.PacketConn
+	for _, addr := range []string{"127.0.0.33:2152", "127.0.0.34:2152", "127.0.0.35:2152"} {
+		pc, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pc.Close()
+		peers = append(peers, pc)
+	}
+	oldENB, newENB, pgw := peers[0], peers[1], peers[2]
+
+	expectEndMarker := func(pc net.PacketConn, teid uint32) {
+		t.Helper()
+
+		buf := make([]byte, 1500)
+		if err := pc.SetReadDeadline(time.Now().Add(3 * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := message.Parse(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := msg.(*message.EndMarker); !ok {
+			t.Fatalf("got unexpected type of message: %T", msg)
+		}
+		if got := msg.TEID(); got != teid {
+			t.Errorf("got unexpected TEID: %#x, want: %#x", got, teid)
+		}
+	}
+
+	if err := s5Conn.SwitchRelay(s1Conn, 0x11111111, 0x33333333, newENB.LocalAddr()); err == nil {
+		t.Error("SwitchRelay should fail without relay")
+	}
+	if err := s5Conn.RelayTo(s1Conn, 0x11111111, 0x22222222, oldENB.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s5Conn.SwitchRelay(s1Conn, 0x11111111, 0x33333333, newENB.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	expectEndMarker(oldENB, 0x22222222)
+
+	// End Marker from P-GW should be relayed to the new eNB.
+	b, err := message.NewEndMarker(0x11111111).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pgw.WriteTo(b, s5Addr); err != nil {
+		t.Fatal(err)
+	}
+	expectEndMarker(newENB, 0x33333333)
+}
@@ -6,6 +6,7 @@ package gtpv1
 
 import (
 	"errors"
+	"fmt"
 	"net"
 )
 
@@ -32,6 +33,33 @@ func (u *UPlaneConn) RelayTo(c *UPlaneConn, teidIn, teidOut uint32, raddr net.Ad
 	return nil
 }
 
+// SwitchRelay switches the peer to which the T-PDU with teidIn is relayed by RelayTo.
+//
+// After switching, End Marker is sent to the old peer with the outgoing TEID used so far
+// to notify the end of the payload on the old path, which is typically done by S-GW when
+// the eNB F-TEID is changed by Modify Bearer Request at the handover.
+// The End Marker received from the other side is also relayed to the new peer as well
+// as T-PDU.
+func (u *UPlaneConn) SwitchRelay(c *UPlaneConn, teidIn, teidOut uint32, raddr net.Addr) error {
+	if u.KernelGTP.enabled {
+		return errors.New("cannot call SwitchRelay when using Kernel GTP-U")
+	}
+
+	u.mu.Lock()
+	old, ok := u.relayMap[teidIn]
+	if !ok {
+		u.mu.Unlock()
+		return fmt.Errorf("no relay found for TEID: %#x", teidIn)
+	}
+	u.relayMap[teidIn] = &peer{teid: teidOut, addr: raddr, srcConn: c}
+	u.mu.Unlock()
+
+	if err := old.srcConn.SendEndMarker(old.teid, old.addr); err != nil {
+		return fmt.Errorf("failed to send End Marker to %s: %w", old.addr, err)
+	}
+	return nil
+}
+
 // CloseRelay stops relaying T-PDU from a conn to conn.
 func (u *UPlaneConn) CloseRelay(teidIn uint32) error {
 	if u.KernelGTP.enabled {
@@ -40,8 +68,9 @@ func (u *UPlaneConn) CloseRelay(teidIn uint32) error {
 
 	u.mu.Lock()
 	delete(u.relayMap, teidIn)
+	delete(u.bufferMap, teidIn)
 	u.mu.Unlock()
 
-	u.iteiMap.delete(teidIn)
+	u.teidAllocator.Release(teidIn)
 	return nil
 }
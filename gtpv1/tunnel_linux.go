@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"syscall"
 
 	"github.com/vishvananda/netlink"
 )
@@ -75,30 +76,60 @@ func (u *UPlaneConn) EnableKernelGTP(devname string, role Role) error {
 	u.KernelGTP.enabled = true
 
 	// remove relayed userland tunnels if exists
-	if len(u.relayMap) != 0 {
+	if len(u.relayMap) != 0 || len(u.bufferMap) != 0 {
 		u.mu.Lock()
 		u.relayMap = nil
+		u.bufferMap = nil
 		u.mu.Unlock()
 	}
 
 	return nil
 }
 
-// AddTunnel adds a GTP-U tunnel with Linux Kernel GTP-U via netlink.
-func (u *UPlaneConn) AddTunnel(peerIP, msIP net.IP, otei, itei uint32) error {
-	if !u.KernelGTP.enabled {
-		return errors.New("cannot call AddTunnel when not using Kernel GTP-U")
+func (k *KernelGTP) pdpHandler() pdpHandler {
+	if k.pdp == nil {
+		return genlPDPHandler{}
+	}
+	return k.pdp
+}
+
+// getByITEI returns the tunnel with itei in either of the address families, as
+// Kernel looks up the tunnels by the incoming TEID in the family given.
+func (k *KernelGTP) getByITEI(itei uint32) (*Tunnel, error) {
+	h := k.pdpHandler()
+	t, err := h.getByITEI(k.Link, itei, syscall.AF_INET)
+	if err == nil {
+		return t, nil
 	}
+	if t, err6 := h.getByITEI(k.Link, itei, syscall.AF_INET6); err6 == nil {
+		return t, nil
+	}
+	return nil, err
+}
 
-	pdp := &netlink.PDP{
+// AddTunnel adds a GTPv1-U tunnel with Linux Kernel GTP-U via netlink.
+//
+// Both IPv4 and IPv6 are available for peerIP and msIP, while IPv6 requires
+// the Kernel that supports it. Use AddTunnelWith to specify the other parameters.
+func (u *UPlaneConn) AddTunnel(peerIP, msIP net.IP, otei, itei uint32) error {
+	return u.AddTunnelWith(&Tunnel{
 		Version:     1,
 		PeerAddress: peerIP,
 		MSAddress:   msIP,
 		OTEI:        otei,
 		ITEI:        itei,
+	})
+}
+
+// AddTunnelWith adds a GTP-U tunnel with Linux Kernel GTP-U via netlink.
+// The GTP version and the address family can be specified in the Tunnel given.
+func (u *UPlaneConn) AddTunnelWith(t *Tunnel) error {
+	if !u.KernelGTP.enabled {
+		return errors.New("cannot call AddTunnel when not using Kernel GTP-U")
 	}
-	if err := netlink.GTPPDPAdd(u.KernelGTP.Link, pdp); err != nil {
-		return fmt.Errorf("failed to add tunnel for %s with %s: %w", msIP, peerIP, err)
+
+	if err := u.KernelGTP.pdpHandler().add(u.KernelGTP.Link, t, false); err != nil {
+		return fmt.Errorf("failed to add tunnel for %s with %s: %w", t.MSAddress, t.PeerAddress, err)
 	}
 	return nil
 }
@@ -111,34 +142,97 @@ func (u *UPlaneConn) AddTunnelOverride(peerIP, msIP net.IP, otei, itei uint32) e
 		return errors.New("cannot call AddTunnelOverride when not using Kernel GTP-U")
 	}
 
-	if pdp, _ := netlink.GTPPDPByMSAddress(u.KernelGTP.Link, msIP); pdp != nil {
+	h := u.KernelGTP.pdpHandler()
+	if t, _ := h.getByMSAddress(u.KernelGTP.Link, msIP); t != nil {
 		// do nothing even this fails
-		_ = netlink.GTPPDPDel(u.KernelGTP.Link, pdp)
+		_ = h.del(u.KernelGTP.Link, t)
 	}
-	if pdp, _ := netlink.GTPPDPByITEI(u.KernelGTP.Link, int(itei)); pdp != nil {
+	if t, _ := u.KernelGTP.getByITEI(itei); t != nil {
 		// do nothing even this fails
-		_ = netlink.GTPPDPDel(u.KernelGTP.Link, pdp)
+		_ = h.del(u.KernelGTP.Link, t)
 	}
 
 	return u.AddTunnel(peerIP, msIP, otei, itei)
 }
 
+// ModifyTunnel modifies the existing GTP-U tunnel in place with Linux Kernel GTP-U via netlink.
+//
+// The tunnel to be modified is identified by the MS address, and the peer address and
+// the outgoing TEID are updated with the values in the Tunnel given. Unlike deleting and
+// adding the tunnel again, the packets are not dropped while modifying.
+func (u *UPlaneConn) ModifyTunnel(t *Tunnel) error {
+	if !u.KernelGTP.enabled {
+		return errors.New("cannot call ModifyTunnel when not using Kernel GTP-U")
+	}
+
+	h := u.KernelGTP.pdpHandler()
+	if _, err := h.getByMSAddress(u.KernelGTP.Link, t.MSAddress); err != nil {
+		return fmt.Errorf("failed to find tunnel for %s: %w", t.MSAddress, err)
+	}
+
+	if err := h.add(u.KernelGTP.Link, t, true); err != nil {
+		return fmt.Errorf("failed to modify tunnel for %s with %s: %w", t.MSAddress, t.PeerAddress, err)
+	}
+	return nil
+}
+
+// ListTunnels returns the GTP-U tunnels in Linux Kernel GTP-U on the GTP device
+// used by UPlaneConn. The ones on the other GTP devices are not included.
+func (u *UPlaneConn) ListTunnels() ([]*Tunnel, error) {
+	if !u.KernelGTP.enabled {
+		return nil, errors.New("cannot call ListTunnels when not using Kernel GTP-U")
+	}
+
+	tunnels, err := u.KernelGTP.pdpHandler().list(u.KernelGTP.Link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tunnels: %w", err)
+	}
+	return tunnels, nil
+}
+
+// GetTunnelByITEI returns a Linux Kernel GTP-U tunnel specified with the incoming TEID.
+func (u *UPlaneConn) GetTunnelByITEI(itei uint32) (*Tunnel, error) {
+	if !u.KernelGTP.enabled {
+		return nil, errors.New("cannot call GetTunnel when not using Kernel GTP-U")
+	}
+
+	t, err := u.KernelGTP.getByITEI(itei)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tunnel with %d: %w", itei, err)
+	}
+	return t, nil
+}
+
+// GetTunnelByMSAddress returns a Linux Kernel GTP-U tunnel specified with the subscriber's IP.
+func (u *UPlaneConn) GetTunnelByMSAddress(msIP net.IP) (*Tunnel, error) {
+	if !u.KernelGTP.enabled {
+		return nil, errors.New("cannot call GetTunnel when not using Kernel GTP-U")
+	}
+
+	t, err := u.KernelGTP.pdpHandler().getByMSAddress(u.KernelGTP.Link, msIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tunnel with %s: %w", msIP, err)
+	}
+	return t, nil
+}
+
 // DelTunnelByITEI deletes a Linux Kernel GTP-U tunnel specified with the incoming TEID.
 func (u *UPlaneConn) DelTunnelByITEI(itei uint32) error {
 	if !u.KernelGTP.enabled {
 		return errors.New("cannot call DelTunnel when not using Kernel GTP-U")
 	}
 
-	pdp, err := netlink.GTPPDPByITEI(u.KernelGTP.Link, int(itei))
+	h := u.KernelGTP.pdpHandler()
+	t, err := u.KernelGTP.getByITEI(itei)
 	if err != nil {
 		return fmt.Errorf("failed to delete tunnel with %d: %w", itei, err)
 	}
 
-	if err := netlink.GTPPDPDel(u.KernelGTP.Link, pdp); err != nil {
-		return fmt.Errorf("failed to delete tunnel for %s: %w", pdp, err)
+	if err := h.del(u.KernelGTP.Link, t); err != nil {
+		return fmt.Errorf("failed to delete tunnel for %s: %w", t, err)
 	}
 
-	u.iteiMap.delete(itei)
+	u.teidAllocator.Release(itei)
 	return nil
 }
 
@@ -148,16 +242,17 @@ func (u *UPlaneConn) DelTunnelByMSAddress(msIP net.IP) error {
 		return errors.New("cannot call DelTunnel when not using Kernel GTP-U")
 	}
 
-	pdp, err := netlink.GTPPDPByMSAddress(u.KernelGTP.Link, msIP)
+	h := u.KernelGTP.pdpHandler()
+	t, err := h.getByMSAddress(u.KernelGTP.Link, msIP)
 	if err != nil {
 		return fmt.Errorf("failed to delete tunnel with %s: %w", msIP, err)
 	}
-	itei := pdp.ITEI
+	itei := t.ITEI
 
-	if err := netlink.GTPPDPDel(u.KernelGTP.Link, pdp); err != nil {
-		return fmt.Errorf("failed to delete tunnel for %s: %w", pdp, err)
+	if err := h.del(u.KernelGTP.Link, t); err != nil {
+		return fmt.Errorf("failed to delete tunnel for %s: %w", t, err)
 	}
 
-	u.iteiMap.delete(itei)
+	u.teidAllocator.Release(itei)
 	return nil
 }
@@ -0,0 +1,152 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv1_test
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/wmnsk/go-gtp/gtpv1"
+)
+
+func TestKernelTunnels(t *testing.T) {
+	u := gtpv1.NewUPlaneConn(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2152})
+	if err := u.AddTunnel(net.ParseIP("127.0.0.2"), net.ParseIP("10.0.0.1"), 1, 1); err == nil {
+		t.Fatal("AddTunnel should fail when Kernel GTP-U is not enabled")
+	}
+	gtpv1.EnableFakeKernelGTP(u)
+
+	if err := u.AddTunnel(net.ParseIP("127.0.0.2"), net.ParseIP("10.0.0.1"), 0x11111111, 0x22222222); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.AddTunnelWith(&gtpv1.Tunnel{
+		Version:     1,
+		PeerAddress: net.ParseIP("2001:db8::2"),
+		MSAddress:   net.ParseIP("2001:db8:1::1"),
+		OTEI:        0x33333333,
+		ITEI:        0x44444444,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.AddTunnel(net.ParseIP("127.0.0.3"), net.ParseIP("10.0.0.1"), 0x55555555, 0x22222222); err == nil {
+		t.Error("AddTunnel should fail with the existing tunnel")
+	}
+
+	tunnels, err := u.ListTunnels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(len(tunnels), 2); diff != "" {
+		t.Error(diff)
+	}
+
+	tun, err := u.GetTunnelByITEI(0x44444444)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(tun.AddressFamily(), uint8(syscall.AF_INET6)); diff != "" {
+		t.Error(diff)
+	}
+
+	if err := u.ModifyTunnel(&gtpv1.Tunnel{
+		Version:     1,
+		PeerAddress: net.ParseIP("127.0.0.3"),
+		MSAddress:   net.ParseIP("10.0.0.1"),
+		OTEI:        0x55555555,
+		ITEI:        0x22222222,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tun, err = u.GetTunnelByMSAddress(net.ParseIP("10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(tun.OTEI, uint32(0x55555555)); diff != "" {
+		t.Error(diff)
+	}
+	if !tun.PeerAddress.Equal(net.ParseIP("127.0.0.3")) {
+		t.Errorf("unexpected peer address: %s", tun.PeerAddress)
+	}
+	if err := u.ModifyTunnel(&gtpv1.Tunnel{Version: 1, MSAddress: net.ParseIP("10.0.0.2")}); err == nil {
+		t.Error("ModifyTunnel should fail with unknown tunnel")
+	}
+
+	if err := u.DelTunnelByITEI(0x22222222); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.DelTunnelByMSAddress(net.ParseIP("2001:db8:1::1")); err != nil {
+		t.Fatal(err)
+	}
+	tunnels, err = u.ListTunnels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(len(tunnels), 0); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestKernelTunnelsOnLinks(t *testing.T) {
+	u := gtpv1.NewUPlaneConn(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2152})
+	gtpv1.EnableFakeKernelGTP(u)
+	v := gtpv1.NewUPlaneConn(&net.UDPAddr{IP: net.ParseIP("127.0.0.2"), Port: 2152})
+	gtpv1.EnableFakeKernelGTPWith(v, u)
+
+	if err := u.AddTunnel(net.ParseIP("127.0.0.3"), net.ParseIP("10.0.0.1"), 0x11111111, 0x22222222); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddTunnel(net.ParseIP("127.0.0.3"), net.ParseIP("10.0.0.2"), 0x33333333, 0x44444444); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		conn *gtpv1.UPlaneConn
+		itei uint32
+	}{{u, 0x22222222}, {v, 0x44444444}} {
+		tunnels, err := c.conn.ListTunnels()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tunnels) != 1 || tunnels[0].ITEI != c.itei {
+			t.Errorf("unexpected tunnels: %v", tunnels)
+		}
+	}
+}
+
+func TestParseTunnels(t *testing.T) {
+	pdp := func(link, itei uint32) []byte {
+		b := (&nl.Genlmsg{Command: nl.GENL_GTP_CMD_GETPDP, Version: nl.GENL_GTP_VERSION}).Serialize()
+		b = append(b, nl.NewRtAttr(nl.GENL_GTP_ATTR_LINK, nl.Uint32Attr(link)).Serialize()...)
+		b = append(b, nl.NewRtAttr(nl.GENL_GTP_ATTR_VERSION, nl.Uint32Attr(1)).Serialize()...)
+		b = append(b, nl.NewRtAttr(nl.GENL_GTP_ATTR_I_TEI, nl.Uint32Attr(itei)).Serialize()...)
+		return b
+	}
+	msgs := [][]byte{pdp(1, 0x11111111), pdp(2, 0x22222222), pdp(1, 0x33333333)}
+
+	tunnels, err := gtpv1.ParseTunnels(msgs, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var iteis []uint32
+	for _, tun := range tunnels {
+		iteis = append(iteis, tun.ITEI)
+	}
+	if diff := cmp.Diff(iteis, []uint32{0x11111111, 0x33333333}); diff != "" {
+		t.Error(diff)
+	}
+
+	// not filtered without link.
+	tunnels, err = gtpv1.ParseTunnels(msgs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(len(tunnels), 3); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -6,7 +6,6 @@ package gtpv1
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -18,9 +17,11 @@ import (
 	"time"
 
 	"github.com/vishvananda/netlink"
+	"github.com/wmnsk/go-gtp"
 	"github.com/wmnsk/go-gtp/gtpv1/ie"
 	"github.com/wmnsk/go-gtp/gtpv1/message"
 	v2ie "github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/teid"
 )
 
 type tpduSet struct {
@@ -36,14 +37,24 @@ type UPlaneConn struct {
 	laddr   net.Addr
 	pktConn net.PacketConn
 	*msgHandlerMap
-	*iteiMap
+	teidAllocator teid.Allocator
 
 	tpduCh  chan *tpduSet
 	closeCh chan struct{}
 
-	relayMap map[uint32]*peer
+	relayMap  map[uint32]*peer
+	bufferMap map[uint32]*tpduBuffer
 
 	errIndEnabled bool
+	errIndHandler func(teid uint32, peer net.Addr)
+
+	// for path supervision
+	pathMap            map[string]*path
+	echoSequence       uint16
+	pathFailureHandler func(peer net.Addr)
+	peerRestartHandler func(peer net.Addr, restartCounter uint8)
+
+	tap gtp.TapFunc
 
 	// for Linux kernel GTP with netlink
 	KernelGTP
@@ -54,6 +65,9 @@ type KernelGTP struct {
 	enabled  bool
 	connFile *os.File
 	Link     *netlink.GTP
+
+	// pdp is used to manage tunnels instead of netlink if set.
+	pdp pdpHandler
 }
 
 // NewUPlaneConn creates a new UPlaneConn used for server. On client side, use DialUPlane instead.
@@ -61,7 +75,7 @@ func NewUPlaneConn(laddr net.Addr) *UPlaneConn {
 	return &UPlaneConn{
 		mu:            sync.Mutex{},
 		msgHandlerMap: newDefaultMsgHandlerMap(),
-		iteiMap:       newiteiMap(),
+		teidAllocator: teid.NewDefaultAllocator(),
 		laddr:         laddr,
 
 		tpduCh:  make(chan *tpduSet),
@@ -76,7 +90,7 @@ func DialUPlane(ctx context.Context, laddr, raddr net.Addr) (*UPlaneConn, error)
 	u := &UPlaneConn{
 		mu:            sync.Mutex{},
 		msgHandlerMap: newDefaultMsgHandlerMap(),
-		iteiMap:       newiteiMap(),
+		teidAllocator: teid.NewDefaultAllocator(),
 		laddr:         laddr,
 
 		tpduCh:  make(chan *tpduSet),
@@ -113,13 +127,14 @@ func DialUPlane(ctx context.Context, laddr, raddr net.Addr) (*UPlaneConn, error)
 			return nil, err
 		}
 
-		n, _, err := u.pktConn.ReadFrom(buf)
+		n, raddr, err := u.pktConn.ReadFrom(buf)
 		if err != nil {
 			return nil, err
 		}
 		if err := u.pktConn.SetReadDeadline(time.Time{}); err != nil {
 			return nil, err
 		}
+		u.capture(gtp.DirectionReceived, raddr, buf[:n])
 
 		// decode incoming message and let it be handled by default handler funcs.
 		msg, err := message.Parse(buf[:n])
@@ -213,11 +228,21 @@ func (u *UPlaneConn) serve(ctx context.Context) error {
 		raw := make([]byte, n)
 		copy(raw, buf)
 		go func() {
-			// just forward T-PDU instead of passing it to reader if relayer is
-			// configured and the message type is T-PDU.
-			if len(u.relayMap) != 0 && raw[1] == message.MsgTypeTPDU {
+			u.capture(gtp.DirectionReceived, raddr, raw)
+
+			// just forward T-PDU and End Marker instead of passing it to reader
+			// if relayer is configured and the message type is either of them.
+			u.mu.Lock()
+			relaying := len(u.relayMap) != 0 || len(u.bufferMap) != 0
+			u.mu.Unlock()
+			if relaying && (raw[1] == message.MsgTypeTPDU || raw[1] == message.MsgTypeEndMarker) {
 				// ignore if the packet size is smaller than minimum header size
-				if n < 11 {
+				if n < 8 {
+					return
+				}
+
+				// queue T-PDU if buffering is enabled for the TEID.
+				if raw[1] == message.MsgTypeTPDU && u.bufferTPDU(raw[:n]) {
 					return
 				}
 
@@ -304,7 +329,11 @@ func (u *UPlaneConn) ReadFromGTP(p []byte) (n int, addr net.Addr, teid uint32, e
 // see SetDeadline and SetWriteDeadline.
 // On packet-oriented connections, write timeouts are rare.
 func (u *UPlaneConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
-	return u.pktConn.WriteTo(p, addr)
+	n, err = u.pktConn.WriteTo(p, addr)
+	if err == nil {
+		u.capture(gtp.DirectionSent, addr, p[:n])
+	}
+	return n, err
 }
 
 // WriteToGTP writes a packet with TEID and payload to addr.
@@ -314,7 +343,7 @@ func (u *UPlaneConn) WriteToGTP(teid uint32, p []byte, addr net.Addr) (n int, er
 		return
 	}
 
-	if _, err = u.pktConn.WriteTo(b, addr); err != nil {
+	if _, err = u.WriteTo(b, addr); err != nil {
 		return
 	}
 	return len(b), nil
@@ -388,9 +417,10 @@ func (u *UPlaneConn) SetWriteDeadline(t time.Time) error {
 // any values, which is in most cases vital to continue working as a node, from the incoming
 // message.
 //
-// HandlerFuncs for EchoResponse and ErrorIndication are registered by default.
+// HandlerFuncs for T-PDU, EchoRequest, EchoResponse, ErrorIndication, EndMarker and
+// SupportedExtensionHeadersNotification are registered by default.
 // These HandlerFuncs can be overwritten by specifying message.MsgTypeEchoResponse and/or
-// message.MsgTypeErrorIndication as msgType parameter.
+// message.MsgTypeErrorIndication, etc. as msgType parameter.
 func (u *UPlaneConn) AddHandler(msgType uint8, fn HandlerFunc) {
 	u.msgHandlerMap.store(msgType, fn)
 }
@@ -424,7 +454,7 @@ func (u *UPlaneConn) EchoRequest(raddr net.Addr) error {
 		return err
 	}
 
-	if _, err := u.pktConn.WriteTo(b, raddr); err != nil {
+	if _, err := u.WriteTo(b, raddr); err != nil {
 		return err
 	}
 	return nil
@@ -437,7 +467,23 @@ func (u *UPlaneConn) EchoResponse(raddr net.Addr) error {
 		return err
 	}
 
-	if _, err := u.pktConn.WriteTo(b, raddr); err != nil {
+	if _, err := u.WriteTo(b, raddr); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SendEndMarker sends End Marker with TEID to raddr.
+//
+// This is typically used by the node that switches the path of the bearer
+// to notify the end of the payload on the old path.
+func (u *UPlaneConn) SendEndMarker(teid uint32, raddr net.Addr) error {
+	b, err := message.NewEndMarker(teid).Marshal()
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.WriteTo(b, raddr); err != nil {
 		return err
 	}
 	return nil
@@ -487,68 +533,35 @@ func (u *UPlaneConn) Restarts() uint8 {
 	return 0
 }
 
-// NewFTEID creates a new GTPv2 F-TEID with random TEID value that is unique within UPlaneConn.
+// NewFTEID creates a new GTPv2 F-TEID with TEID value that is unique within UPlaneConn.
 // To ensure the uniqueness, don't create in the other way if you once use this method.
 // This is meant to be used for creating F-TEID IE for non-local interface type, such as
 // the ones that are used in U-Plane. For local interface, use (*Conn).NewSenderFTEID instead.
 //
-// Note that in the case there's a lot of Session on the Conn, it may take a long
-// time to find a new unique value.
-//
-// TODO: optimize performance...
+// The TEID is allocated by the teid.Allocator of UPlaneConn, which allocates it randomly
+// by default. Use SetTEIDAllocator to change the way to allocate. The TEID is released
+// when the tunnel or relay with it is deleted, or it can be released explicitly with
+// ReleaseTEID. It returns nil if no TEID is available.
 func (u *UPlaneConn) NewFTEID(ifType uint8, v4, v6 string) (fteidIE *v2ie.IE) {
-	var teid uint32
-	for try := uint32(0); try < 0xffff; try++ {
-		const logEvery = 0xff
-		if try&logEvery == logEvery {
-			logf("Generating NewSenderFTEID crossed tries:%d", try)
-		}
-
-		t := generateRandomUint32()
-		if t == 0 {
-			continue
-		}
-
-		// Try to mark TEID as taken. Fails if something exists
-		if ok := u.iteiMap.tryStore(t, time.Now()); !ok {
-			logf("TEID-U: %#08x has already been taken, trying to generate another one...", t)
-			continue
-		}
-
-		teid = t
-		break
-	}
-
-	if teid == 0 {
+	t, err := u.teidAllocator.Allocate()
+	if err != nil {
+		logf("failed to allocate TEID-U: %+v", err)
 		return nil
 	}
-	return v2ie.NewFullyQualifiedTEID(ifType, teid, v4, v6)
-}
-
-func generateRandomUint32() uint32 {
-	b := make([]byte, 4)
-	if _, err := rand.Read(b); err != nil {
-		return 0
-	}
-
-	return binary.BigEndian.Uint32(b)
+	return v2ie.NewFullyQualifiedTEID(ifType, t, v4, v6)
 }
 
-type iteiMap struct {
-	syncMap sync.Map
+// ReleaseTEID makes the TEID allocated by NewFTEID available again.
+func (u *UPlaneConn) ReleaseTEID(teid uint32) {
+	u.teidAllocator.Release(teid)
 }
 
-func newiteiMap() *iteiMap {
-	return &iteiMap{}
-}
-
-func (t *iteiMap) tryStore(itei uint32, ts time.Time) bool {
-	_, loaded := t.syncMap.LoadOrStore(itei, ts)
-	return !loaded
-}
-
-func (t *iteiMap) delete(itei uint32) {
-	t.syncMap.Delete(itei)
+// SetTEIDAllocator replaces the teid.Allocator used by NewFTEID with a.
+//
+// This should be called before any TEID is allocated, as the TEIDs allocated
+// by the current one are not moved to the new one.
+func (u *UPlaneConn) SetTEIDAllocator(a teid.Allocator) {
+	u.teidAllocator = a
 }
 
 // EnableErrorIndication re-enables automatic sending of
@@ -562,6 +575,45 @@ func (u *UPlaneConn) EnableErrorIndication() {
 	u.mu.Unlock()
 }
 
+// OnErrorIndication registers fn to be called when the default handler receives
+// Error Indication.
+//
+// teid is the value in TEID Data I IE, which is the TEID that the peer does not know,
+// and peer is the address of the peer taken from GTP-U Peer Address IE (the sender's
+// address is used if it is unavailable). The port of peer is always 2152.
+// This is typically used to find the Session and its Bearer associated with them by
+// (*gtpv2.Conn).GetSessionByBearerTEID and to initiate the deletion of the bearer as
+// described in TS 23.007.
+//
+// Passing nil lets the default handler just log the Error Indication again.
+// The fn is not called if the handler for Error Indication is overridden by AddHandler.
+func (u *UPlaneConn) OnErrorIndication(fn func(teid uint32, peer net.Addr)) {
+	u.mu.Lock()
+	u.errIndHandler = fn
+	u.mu.Unlock()
+}
+
+// SetTap registers tap to be called with every datagram sent and received on
+// UPlaneConn, including the T-PDUs relayed. Passing nil removes the tap.
+//
+// Note that the packets handled by Kernel GTP are not passed to tap, as they never
+// reach UPlaneConn.
+func (u *UPlaneConn) SetTap(tap gtp.TapFunc) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.tap = tap
+}
+
+func (u *UPlaneConn) capture(dir gtp.Direction, raddr net.Addr, b []byte) {
+	u.mu.Lock()
+	tap := u.tap
+	u.mu.Unlock()
+
+	if tap != nil {
+		tap(gtp.NewCapture(dir, u.LocalAddr(), raddr, b))
+	}
+}
+
 // DisableErrorIndication makes default T-PDU handler stop
 // responding with Error Indication in case of receiving T-PDU
 // with unknown TEID.
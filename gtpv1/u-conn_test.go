@@ -12,7 +12,10 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 
+	"github.com/wmnsk/go-gtp"
 	"github.com/wmnsk/go-gtp/gtpv1"
+	"github.com/wmnsk/go-gtp/gtpv1/ie"
+	"github.com/wmnsk/go-gtp/gtpv1/message"
 )
 
 type testVal struct {
@@ -103,3 +106,129 @@ func TestClientWrite(t *testing.T) {
 		t.Fatal("timed out while waiting for response to come")
 	}
 }
+
+func TestErrorIndicationHook(t *testing.T) {
+	srvAddr, err := net.ResolveUDPAddr("udp", "127.0.0.21:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type indicated struct {
+		teid uint32
+		peer net.Addr
+	}
+	indCh := make(chan indicated)
+
+	srvConn := gtpv1.NewUPlaneConn(srvAddr)
+	srvConn.OnErrorIndication(func(teid uint32, peer net.Addr) {
+		indCh <- indicated{teid, peer}
+	})
+	go func() {
+		if err := srvConn.ListenAndServe(ctx); err != nil {
+			t.Errorf("failed to listen on %s: %s", srvAddr, err)
+		}
+	}()
+	// XXX - waiting for server to be well-prepared, should consider better way.
+	time.Sleep(100 * time.Millisecond)
+
+	cliConn, err := net.ListenPacket("udp", "127.0.0.22:2152")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cliConn.Close()
+
+	b, err := message.NewErrorIndication(
+		0, 0, ie.NewTEIDDataI(0x11111111), ie.NewGSNAddress("127.0.0.23"),
+	).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cliConn.WriteTo(b, srvAddr); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-indCh:
+		if diff := cmp.Diff(got.teid, uint32(0x11111111)); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff(got.peer.String(), "127.0.0.23:2152"); diff != "" {
+			t.Error(diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out while waiting for Error Indication to be handled")
+	}
+}
+
+func TestTap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srvAddr := &net.UDPAddr{IP: net.IP{127, 0, 0, 24}, Port: 2152}
+	cliAddr := &net.UDPAddr{IP: net.IP{127, 0, 0, 25}, Port: 2152}
+
+	srvRec, cliRec := gtp.NewRecorder(), gtp.NewRecorder()
+	srvConn := gtpv1.NewUPlaneConn(srvAddr)
+	srvConn.DisableErrorIndication()
+	srvConn.SetTap(srvRec.Tap)
+	go func() {
+		if err := srvConn.ListenAndServe(ctx); err != nil {
+			t.Errorf("failed to listen on %s: %s", srvAddr, err)
+		}
+	}()
+	// XXX - waiting for server to be well-prepared, should consider better way.
+	time.Sleep(100 * time.Millisecond)
+
+	cliConn, err := gtpv1.DialUPlane(ctx, cliAddr, srvAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cliConn.SetTap(cliRec.Tap)
+
+	if _, err := cliConn.WriteToGTP(0x11111111, []byte{0xde, 0xad, 0xbe, 0xef}, srvAddr); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1500)
+	if _, _, _, err := srvConn.ReadFromGTP(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		description string
+		rec         *gtp.Recorder
+		dir         gtp.Direction
+		src, dst    string
+	}{
+		{"client", cliRec, gtp.DirectionSent, cliAddr.String(), srvAddr.String()},
+		{"server", srvRec, gtp.DirectionReceived, cliAddr.String(), srvAddr.String()},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			// the last one, as the Echo exchanged on dialing is also captured on server.
+			captures := c.rec.Captures()
+			if len(captures) == 0 {
+				t.Fatal("nothing captured")
+			}
+			got := captures[len(captures)-1]
+			if got.Direction != c.dir {
+				t.Errorf("got direction %s, want %s", got.Direction, c.dir)
+			}
+			if diff := cmp.Diff(got.SrcAddr().String(), c.src); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(got.DstAddr().String(), c.dst); diff != "" {
+				t.Error(diff)
+			}
+			tpdu, ok := got.Message.(*message.TPDU)
+			if !ok {
+				t.Fatalf("got %T, want *message.TPDU", got.Message)
+			}
+			if diff := cmp.Diff(tpdu.Decapsulate(), []byte{0xde, 0xad, 0xbe, 0xef}); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
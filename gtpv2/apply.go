@@ -0,0 +1,359 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"net"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// ApplyCreateSessionResponse updates Session with the values in CreateSessionResponse.
+//
+// This is the counterpart of ParseCreateSession, which populates Session from the
+// request. The C-Plane F-TEIDs and the U-Plane F-TEIDs of the default bearer are
+// added to the TEIDs of Session, and the UE IP address in PAA is set as SubscriberIP
+// of the default bearer. For each Bearer Context Created, the EBI, Charging ID, Bearer
+// QoS and the first U-Plane F-TEID (as the outgoing TEID and remote address) are set
+// to the Bearer with the same EBI, which is created if it does not exist.
+//
+// The state of Session is updated with Transition first, and *InvalidStateError is
+// returned if the response is not expected in the current state. If the request is
+// rejected as a whole, it returns *CauseNotOKError without updating Session. If some
+// of the bearers are rejected, the accepted ones are applied and it returns
+// *BearerRejectedError.
+func (s *Session) ApplyCreateSessionResponse(res *message.CreateSessionResponse) error {
+	if err := s.Transition(res); err != nil {
+		return err
+	}
+	if err := checkCause(res, res.Cause); err != nil {
+		return err
+	}
+
+	for _, fteid := range []*ie.IE{res.SenderFTEIDC, res.PGWS5S8FTEIDC} {
+		if fteid == nil {
+			continue
+		}
+		it, err := fteid.InterfaceType()
+		if err != nil {
+			return err
+		}
+		teid, err := fteid.TEID()
+		if err != nil {
+			return err
+		}
+		s.AddTEID(it, teid)
+	}
+
+	if paa := res.PAA; paa != nil {
+		ip, err := paa.IPAddress()
+		if err != nil {
+			return err
+		}
+		s.GetDefaultBearer().SubscriberIP = ip
+	}
+
+	bcs, err := bearerContexts(res.Header, res.BearerContextsCreated)
+	if err != nil {
+		return err
+	}
+	return s.applyBearerContexts(res, bcs)
+}
+
+// ApplyModifyBearerResponse updates the Bearers in Session with the Bearer Contexts
+// Modified in ModifyBearerResponse, in the same way as ApplyCreateSessionResponse.
+//
+// It returns *InvalidStateError if the response is not expected in the current state.
+// If the request is rejected as a whole, it returns *CauseNotOKError without updating
+// Session. If some of the bearers are rejected, the accepted ones are applied and it
+// returns *BearerRejectedError.
+func (s *Session) ApplyModifyBearerResponse(res *message.ModifyBearerResponse) error {
+	if err := s.Transition(res); err != nil {
+		return err
+	}
+	if err := checkCause(res, res.Cause); err != nil {
+		return err
+	}
+
+	bcs, err := bearerContexts(res.Header, res.BearerContextsModified)
+	if err != nil {
+		return err
+	}
+	return s.applyBearerContexts(res, bcs)
+}
+
+// ApplyCreateBearerResponse adds the Bearers accepted in CreateBearerResponse to
+// Session, and returns them.
+//
+// The Bearer Contexts in the response are matched in order with the bearers in the
+// request sent by (*Conn).CreateBearer. The EBI assigned by the MME and the U-Plane
+// F-TEID of the peer are set to the Bearer, and it is added to Session with the name
+// "dedicated-<EBI>".
+//
+// It returns *InvalidStateError if the response is not expected in the current state.
+// If the request is rejected as a whole, it returns *CauseNotOKError. If some of the
+// bearers are rejected, it returns the accepted ones with *BearerRejectedError.
+func (s *Session) ApplyCreateBearerResponse(res *message.CreateBearerResponse) ([]*Bearer, error) {
+	if err := s.Transition(res); err != nil {
+		return nil, err
+	}
+	proc, err := s.loadBearerProcedure(res, message.MsgTypeCreateBearerRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCause(res, res.Cause); err != nil {
+		return nil, err
+	}
+
+	bcs, err := bearerContexts(res.Header, res.BearerContexts)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []*Bearer
+	rejected := map[uint8]uint8{}
+	for i, bc := range bcs {
+		if i >= len(proc.bearers) {
+			break
+		}
+		ebi, cause, err := parseBearerContextResult(bc)
+		if err != nil {
+			return created, err
+		}
+		if !IsAcceptedCause(cause) {
+			rejected[ebi] = cause
+			continue
+		}
+
+		br := proc.bearers[i].Bearer
+		br.EBI = ebi
+		if len(proc.bearers[i].FTEIDs) > 0 {
+			if teid, err := proc.bearers[i].FTEIDs[0].TEID(); err == nil {
+				br.SetIncomingTEID(teid)
+			}
+		}
+		if err := s.applyBearerContext(br, bc, false); err != nil {
+			return created, err
+		}
+		if br.APN == "" {
+			br.APN = s.APN()
+		}
+
+		s.AddBearer(dedicatedBearerName(ebi), br)
+		created = append(created, br)
+	}
+	return created, s.bearerRejected(res, rejected)
+}
+
+// ApplyUpdateBearerResponse updates the QoSProfile of the Bearers accepted in the
+// UpdateBearerResponse with the one in the request sent by (*Conn).UpdateBearer, and
+// returns the updated Bearers.
+//
+// It returns *InvalidStateError if the response is not expected in the current state.
+// If the request is rejected as a whole, it returns *CauseNotOKError.
+func (s *Session) ApplyUpdateBearerResponse(res *message.UpdateBearerResponse) ([]*Bearer, error) {
+	if err := s.Transition(res); err != nil {
+		return nil, err
+	}
+	proc, err := s.loadBearerProcedure(res, message.MsgTypeUpdateBearerRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCause(res, res.Cause); err != nil {
+		return nil, err
+	}
+
+	bcs, err := bearerContexts(res.Header, res.BearerContexts)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []*Bearer
+	for _, bc := range bcs {
+		ebi, cause, err := parseBearerContextResult(bc)
+		if err != nil {
+			return updated, err
+		}
+		if !IsAcceptedCause(cause) {
+			continue
+		}
+
+		br, err := s.LookupBearerByEBI(ebi)
+		if err != nil {
+			return updated, err
+		}
+		for _, req := range proc.bearers {
+			if req.EBI == ebi && req.QoSProfile != nil {
+				br.QoSProfile = req.QoSProfile
+			}
+		}
+		updated = append(updated, br)
+	}
+	return updated, nil
+}
+
+// ApplyDeleteBearerResponse removes the Bearers whose deletion is accepted in the
+// DeleteBearerResponse from Session, and returns their EBIs.
+//
+// If the response doesn't contain any Bearer Context, all the bearers in the request
+// sent by (*Conn).DeleteDedicatedBearers are removed.
+//
+// It returns *InvalidStateError if the response is not expected in the current state.
+// If the request is rejected as a whole, it returns *CauseNotOKError.
+func (s *Session) ApplyDeleteBearerResponse(res *message.DeleteBearerResponse) ([]uint8, error) {
+	if err := s.Transition(res); err != nil {
+		return nil, err
+	}
+	proc, err := s.loadBearerProcedure(res, message.MsgTypeDeleteBearerRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCause(res, res.Cause); err != nil {
+		return nil, err
+	}
+
+	bcs, err := bearerContexts(res.Header, res.BearerContexts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bcs) == 0 {
+		for _, ebi := range proc.ebis {
+			s.RemoveBearerByEBI(ebi)
+		}
+		return proc.ebis, nil
+	}
+
+	var deleted []uint8
+	for _, bc := range bcs {
+		ebi, cause, err := parseBearerContextResult(bc)
+		if err != nil {
+			return deleted, err
+		}
+		if !IsAcceptedCause(cause) {
+			continue
+		}
+		s.RemoveBearerByEBI(ebi)
+		deleted = append(deleted, ebi)
+	}
+	return deleted, nil
+}
+
+// applyBearerContexts applies bcs to the Bearers with the same EBI.
+func (s *Session) applyBearerContexts(res message.Message, bcs []*ie.IE) error {
+	rejected := map[uint8]uint8{}
+	for _, bc := range bcs {
+		ebi, cause, err := parseBearerContextResult(bc)
+		if err != nil {
+			return err
+		}
+		if !IsAcceptedCause(cause) {
+			rejected[ebi] = cause
+			continue
+		}
+
+		br, isDefault := s.bearerToApply(ebi)
+		if err := s.applyBearerContext(br, bc, isDefault); err != nil {
+			return err
+		}
+	}
+	return s.bearerRejected(res, rejected)
+}
+
+// bearerToApply returns the Bearer with ebi, and whether it is the default bearer.
+//
+// The default bearer is returned if its EBI is not set yet, and a new Bearer is
+// added to Session if no Bearer is found.
+func (s *Session) bearerToApply(ebi uint8) (*Bearer, bool) {
+	def := s.GetDefaultBearer()
+	if def.EBI == ebi || def.EBI == 0 {
+		def.EBI = ebi
+		return def, true
+	}
+	if br, err := s.LookupBearerByEBI(ebi); err == nil {
+		return br, false
+	}
+
+	br := NewBearer(ebi, def.APN, &QoSProfile{})
+	s.AddBearer(dedicatedBearerName(ebi), br)
+	return br, false
+}
+
+// applyBearerContext sets the values in bc to br. The F-TEIDs are also added to
+// the TEIDs of Session if br is the default bearer.
+func (s *Session) applyBearerContext(br *Bearer, bc *ie.IE, isDefault bool) error {
+	var outgoingSet bool
+	for _, child := range bc.ChildIEs {
+		switch child.Type {
+		case ie.FullyQualifiedTEID:
+			it, err := child.InterfaceType()
+			if err != nil {
+				return err
+			}
+			teid, err := child.TEID()
+			if err != nil {
+				return err
+			}
+			if isDefault {
+				s.AddTEID(it, teid)
+			}
+
+			// the first one that is not of the local node is of the peer.
+			if outgoingSet || teid == br.IncomingTEID() {
+				continue
+			}
+			br.SetOutgoingTEID(teid)
+			if ip, err := child.IP(); err == nil {
+				br.SetRemoteAddress(&net.UDPAddr{IP: ip, Port: 2152})
+			}
+			outgoingSet = true
+		case ie.ChargingID:
+			id, err := child.ChargingID()
+			if err != nil {
+				return err
+			}
+			br.ChargingID = id
+		case ie.BearerQoS:
+			qos, err := qosProfile(child)
+			if err != nil {
+				return err
+			}
+			br.QoSProfile = qos
+		}
+	}
+	return nil
+}
+
+// qosProfile returns QoSProfile built from Bearer QoS IE.
+func qosProfile(i *ie.IE) (*QoSProfile, error) {
+	qos, err := i.BearerQoS()
+	if err != nil {
+		return nil, err
+	}
+	return &QoSProfile{
+		PCI:   qos.ARP&0x40 != 0,
+		PL:    qos.ARP >> 2 & 0x0f,
+		PVI:   qos.ARP&0x01 != 0,
+		QCI:   qos.QCI,
+		MBRUL: qos.MaximumBitRateForUplink,
+		MBRDL: qos.MaximumBitRateForDownlink,
+		GBRUL: qos.GuaranteedBitRateForUplink,
+		GBRDL: qos.GuaranteedBitRateForDownlink,
+	}, nil
+}
+
+// bearerRejected returns *BearerRejectedError if rejected is not empty.
+func (s *Session) bearerRejected(res message.Message, rejected map[uint8]uint8) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	var imsi string
+	if s.Subscriber != nil {
+		imsi = s.IMSI
+	}
+	return &BearerRejectedError{IMSI: imsi, MsgType: res.MessageTypeName(), Causes: rejected}
+}
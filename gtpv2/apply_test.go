@@ -0,0 +1,153 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func TestApplyCreateSessionResponse(t *testing.T) {
+	sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+	sess.AddTEID(gtpv2.IFTypeS11MMEGTPC, 0x11)
+	sess.GetDefaultBearer().APN = "internet"
+	if err := sess.Transition(message.NewCreateSessionRequest(0, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil)
+	res := decode(t, message.MsgTypeCreateSessionResponse, 0x11, 1,
+		ie.NewCause(gtpv2.CauseRequestAcceptedPartially, 0, 0, 0, nil),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11S4SGWGTPC, 0x22, "127.0.0.2", ""),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPC, 0x33, "127.0.0.3", "").WithInstance(1),
+		ie.NewPDNAddressAllocation("10.0.0.1"),
+		ie.NewBearerContext(
+			ie.NewEPSBearerID(5), accepted,
+			ie.NewFullyQualifiedTEID(gtpv2.IFTypeS1USGWGTPU, 0x44, "127.0.0.2", ""),
+			ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPU, 0x55, "127.0.0.3", "").WithInstance(2),
+			ie.NewChargingID(0x66),
+			ie.NewBearerQoS(1, 2, 1, 9, 0, 0, 0, 0),
+		),
+		ie.NewBearerContext(
+			ie.NewEPSBearerID(6), ie.NewCause(gtpv2.CauseNoResourcesAvailable, 0, 0, 0, nil),
+		),
+	).(*message.CreateSessionResponse)
+
+	err := sess.ApplyCreateSessionResponse(res)
+	var rejectedErr *gtpv2.BearerRejectedError
+	if !errors.As(err, &rejectedErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(rejectedErr.Causes, map[uint8]uint8{6: gtpv2.CauseNoResourcesAvailable}); diff != "" {
+		t.Error(diff)
+	}
+
+	teids := map[uint8]uint32{
+		gtpv2.IFTypeS11MMEGTPC:   0x11,
+		gtpv2.IFTypeS11S4SGWGTPC: 0x22,
+		gtpv2.IFTypeS5S8PGWGTPC:  0x33,
+		gtpv2.IFTypeS1USGWGTPU:   0x44,
+		gtpv2.IFTypeS5S8PGWGTPU:  0x55,
+	}
+	for it, want := range teids {
+		got, err := sess.GetTEID(it)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("wrong TEID for interface type %d: want %#x, got %#x", it, want, got)
+		}
+	}
+
+	br := sess.GetDefaultBearer()
+	if br.EBI != 5 || br.SubscriberIP != "10.0.0.1" || br.ChargingID != 0x66 || br.QCI != 9 || br.PL != 2 {
+		t.Errorf("wrong default bearer: %+v, %+v", br, br.QoSProfile)
+	}
+	if br.OutgoingTEID() != 0x44 || br.RemoteAddress().String() != "127.0.0.2:2152" {
+		t.Errorf("wrong U-Plane peer: %#x, %s", br.OutgoingTEID(), br.RemoteAddress())
+	}
+	if sess.BearerCount() != 1 {
+		t.Errorf("rejected bearer should not be added: %d", sess.BearerCount())
+	}
+
+	// rejected as a whole.
+	other := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567892"})
+	if err := other.Transition(message.NewCreateSessionRequest(0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	err = other.ApplyCreateSessionResponse(message.NewCreateSessionResponse(0x11, 2,
+		ie.NewCause(gtpv2.CauseMissingOrUnknownAPN, 0, 0, 0, nil),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11S4SGWGTPC, 0x22, "127.0.0.2", ""),
+	))
+	var causeErr *gtpv2.CauseNotOKError
+	if !errors.As(err, &causeErr) || causeErr.Cause != gtpv2.CauseMissingOrUnknownAPN {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := other.GetTEID(gtpv2.IFTypeS11S4SGWGTPC); err == nil {
+		t.Error("Session should not be updated")
+	}
+	if other.State() != gtpv2.SessionStateDeleted {
+		t.Errorf("wrong state: %s", other.State())
+	}
+
+	// not expected in the current state.
+	err = sess.ApplyCreateSessionResponse(message.NewCreateSessionResponse(0x11, 3,
+		ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil),
+	))
+	var stateErr *gtpv2.InvalidStateError
+	if !errors.As(err, &stateErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestApplyModifyBearerResponse(t *testing.T) {
+	sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+	sess.GetDefaultBearer().EBI = 5
+	sess.AddBearer("dedicated-6", gtpv2.NewBearer(6, "internet", &gtpv2.QoSProfile{}))
+	if err := sess.Activate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.Transition(message.NewModifyBearerRequest(0x22, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil)
+	res := decode(t, message.MsgTypeModifyBearerResponse, 0x11, 1,
+		accepted,
+		ie.NewBearerContext(
+			ie.NewEPSBearerID(5), accepted,
+			ie.NewFullyQualifiedTEID(gtpv2.IFTypeS1USGWGTPU, 0x44, "127.0.0.2", ""),
+		),
+		ie.NewBearerContext(
+			ie.NewEPSBearerID(6), accepted,
+			ie.NewFullyQualifiedTEID(gtpv2.IFTypeS1USGWGTPU, 0x45, "127.0.0.2", ""),
+		),
+	).(*message.ModifyBearerResponse)
+
+	if err := sess.ApplyModifyBearerResponse(res); err != nil {
+		t.Fatal(err)
+	}
+	if got := sess.GetDefaultBearer().OutgoingTEID(); got != 0x44 {
+		t.Errorf("wrong TEID of default bearer: %#x", got)
+	}
+	br, err := sess.LookupBearerByEBI(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := br.OutgoingTEID(); got != 0x45 {
+		t.Errorf("wrong TEID of dedicated bearer: %#x", got)
+	}
+
+	// the TEID of the default bearer is kept in Session.
+	if teid, err := sess.GetTEID(gtpv2.IFTypeS1USGWGTPU); err != nil || teid != 0x44 {
+		t.Errorf("wrong TEID: %#x, %v", teid, err)
+	}
+}
@@ -5,6 +5,7 @@
 package gtpv2
 
 import (
+	"encoding/json"
 	"net"
 )
 
@@ -65,3 +66,64 @@ func (b *Bearer) OutgoingTEID() uint32 {
 func (b *Bearer) SetOutgoingTEID(teid uint32) {
 	b.teidOut = teid
 }
+
+// bearerJSON is the serialised format of Bearer.
+type bearerJSON struct {
+	EBI           uint8       `json:"ebi"`
+	SubscriberIP  string      `json:"subscriberIP,omitempty"`
+	APN           string      `json:"apn,omitempty"`
+	ChargingID    uint32      `json:"chargingID,omitempty"`
+	QoSProfile    *QoSProfile `json:"qosProfile,omitempty"`
+	RemoteAddress string      `json:"remoteAddress,omitempty"`
+	IncomingTEID  uint32      `json:"incomingTEID,omitempty"`
+	OutgoingTEID  uint32      `json:"outgoingTEID,omitempty"`
+}
+
+// MarshalJSON serialises Bearer into JSON, including the remote address and TEIDs.
+func (b *Bearer) MarshalJSON() ([]byte, error) {
+	v := &bearerJSON{
+		EBI:          b.EBI,
+		SubscriberIP: b.SubscriberIP,
+		APN:          b.APN,
+		ChargingID:   b.ChargingID,
+		QoSProfile:   b.QoSProfile,
+		IncomingTEID: b.teidIn,
+		OutgoingTEID: b.teidOut,
+	}
+	if b.raddr != nil {
+		v.RemoteAddress = b.raddr.String()
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON restores Bearer from JSON serialised by MarshalJSON.
+//
+// The remote address is restored as *net.UDPAddr.
+func (b *Bearer) UnmarshalJSON(data []byte) error {
+	v := &bearerJSON{}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	if v.RemoteAddress != "" {
+		raddr, err := net.ResolveUDPAddr("udp", v.RemoteAddress)
+		if err != nil {
+			return err
+		}
+		b.raddr = raddr
+	}
+
+	b.EBI = v.EBI
+	b.SubscriberIP = v.SubscriberIP
+	b.APN = v.APN
+	b.ChargingID = v.ChargingID
+	b.QoSProfile = v.QoSProfile
+	if b.QoSProfile == nil {
+		b.QoSProfile = &QoSProfile{}
+	}
+	b.teidIn = v.IncomingTEID
+	b.teidOut = v.OutgoingTEID
+
+	return nil
+}
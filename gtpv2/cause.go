@@ -0,0 +1,178 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import "fmt"
+
+// CauseCategory is the category of the Cause value defined in TS 29.274 §8.4.
+type CauseCategory uint8
+
+// CauseCategory definitions.
+const (
+	CauseCategoryUnknown CauseCategory = iota
+	// CauseCategoryRequest is the Cause used in request/initial messages (2-15).
+	CauseCategoryRequest
+	// CauseCategoryAcceptance is the Cause used in responses to accept the request (16-63).
+	CauseCategoryAcceptance
+	// CauseCategoryRejection is the Cause used in responses to reject the request (64-239).
+	CauseCategoryRejection
+)
+
+// String returns the name of CauseCategory.
+func (c CauseCategory) String() string {
+	switch c {
+	case CauseCategoryRequest:
+		return "Request"
+	case CauseCategoryAcceptance:
+		return "Acceptance"
+	case CauseCategoryRejection:
+		return "Rejection"
+	default:
+		return "Unknown"
+	}
+}
+
+var causeNames = map[uint8]string{
+	CauseLocalDetach:                                   "Local Detach",
+	CauseCompleteDetach:                                "Complete Detach",
+	CauseRATChangedFrom3GPPToNon3GPP:                   "RAT changed from 3GPP to Non-3GPP",
+	CauseISRDeactivation:                               "ISR deactivation",
+	CauseErrorIndicationReceivedFromRNCeNodeBS4SGSNMME: "Error Indication received from RNC/eNodeB/S4-SGSN/MME",
+	CauseIMSIDetachOnly:                                "IMSI Detach Only",
+	CauseReactivationRequested:                         "Reactivation Requested",
+	CausePDNReconnectionToThisAPNDisallowed:            "PDN reconnection to this APN disallowed",
+	CauseAccessChangedFromNon3GPPTo3GPP:                "Access changed from Non-3GPP to 3GPP",
+	CausePDNConnectionInactivityTimerExpires:           "PDN connection inactivity timer expires",
+	CausePGWNotResponding:                              "PGW not responding",
+	CauseNetworkFailure:                                "Network Failure",
+	CauseQoSParameterMismatch:                          "QoS parameter mismatch",
+	CauseRequestAccepted:                               "Request accepted",
+	CauseRequestAcceptedPartially:                      "Request accepted partially",
+	CauseNewPDNTypeDueToNetworkPreference:              "New PDN type due to network preference",
+	CauseNewPDNTypeDueToSingleAddressBearerOnly:        "New PDN type due to single address bearer only",
+	CauseContextNotFound:                               "Context Not Found",
+	CauseInvalidMessageFormat:                          "Invalid Message Format",
+	CauseVersionNotSupportedByNextPeer:                 "Version not supported by next peer",
+	CauseInvalidLength:                                 "Invalid length",
+	CauseServiceNotSupported:                           "Service not supported",
+	CauseMandatoryIEIncorrect:                          "Mandatory IE incorrect",
+	CauseMandatoryIEMissing:                            "Mandatory IE missing",
+	CauseSystemFailure:                                 "System failure",
+	CauseNoResourcesAvailable:                          "No resources available",
+	CauseSemanticErrorInTheTFTOperation:                "Semantic error in the TFT operation",
+	CauseSyntacticErrorInTheTFTOperation:               "Syntactic error in the TFT operation",
+	CauseSemanticErrorsInPacketFilters:                 "Semantic errors in packet filter(s)",
+	CauseSyntacticErrorsInPacketFilters:                "Syntactic errors in packet filter(s)",
+	CauseMissingOrUnknownAPN:                           "Missing or unknown APN",
+	CauseGREKeyNotFound:                                "GRE key not found",
+	CauseRelocationFailure:                             "Relocation failure",
+	CauseDeniedInRAT:                                   "Denied in RAT",
+	CausePreferredPDNTypeNotSupported:                  "Preferred PDN type not supported",
+	CauseAllDynamicAddressesAreOccupied:                "All dynamic addresses are occupied",
+	CauseUEContextWithoutTFTAlreadyActivated:           "UE context without TFT already activated",
+	CauseProtocolTypeNotSupported:                      "Protocol type not supported",
+	CauseUENotResponding:                               "UE not responding",
+	CauseUERefuses:                                     "UE refuses",
+	CauseServiceDenied:                                 "Service denied",
+	CauseUnableToPageUE:                                "Unable to page UE",
+	CauseNoMemoryAvailable:                             "No memory available",
+	CauseUserAuthenticationFailed:                      "User authentication failed",
+	CauseAPNAccessDeniedNoSubscription:                 "APN access denied - no subscription",
+	CauseRequestRejectedReasonNotSpecified:             "Request rejected (reason not specified)",
+	CausePTMSISignatureMismatch:                        "P-TMSI Signature mismatch",
+	CauseIMSIIMEINotKnown:                              "IMSI/IMEI not known",
+	CauseSemanticErrorInTheTADOperation:                "Semantic error in the TAD operation",
+	CauseSyntacticErrorInTheTADOperation:               "Syntactic error in the TAD operation",
+	CauseRemotePeerNotResponding:                       "Remote peer not responding",
+	CauseCollisionWithNetworkInitiatedRequest:          "Collision with network initiated request",
+	CauseUnableToPageUEDueToSuspension:                 "Unable to page UE due to Suspension",
+	CauseConditionalIEMissing:                          "Conditional IE missing",
+	CauseAPNRestrictionTypeIncompatibleWithCurrentlyActivePDNConnection:                 "APN Restriction type Incompatible with currently active PDN connection",
+	CauseInvalidOverallLengthOfTheTriggeredResponseMessageAndAPiggybackedInitialMessage: "Invalid overall length of the triggered response message and a piggybacked initial message",
+	CauseDataForwardingNotSupported:                                                     "Data forwarding not supported",
+	CauseInvalidReplyFromRemotePeer:                                                     "Invalid reply from remote peer",
+	CauseFallbackToGTPv1:                                                                "Fallback to GTPv1",
+	CauseInvalidPeer:                                                                    "Invalid peer",
+	CauseTemporarilyRejectedDueToHandoverTAURAUProcedureInProgress:                      "Temporarily rejected due to handover/TAU/RAU procedure in progress",
+	CauseModificationsNotLimitedToS1UBearers:                                            "Modifications not limited to S1-U bearers",
+	CauseRequestRejectedForAPMIPv6Reason:                                                "Request rejected for a PMIPv6 reason",
+	CauseAPNCongestion:                                                                  "APN Congestion",
+	CauseBearerHandlingNotSupported:                                                     "Bearer handling not supported",
+	CauseUEAlreadyReattached:                                                            "UE already re-attached",
+	CauseMultiplePDNConnectionsForAGivenAPNNotAllowed:                                   "Multiple PDN connections for a given APN not allowed",
+	CauseTargetAccessRestrictedForTheSubscriber:                                         "Target access restricted for the subscriber",
+	CauseMMESGSNRefusesDueToVPLMNPolicy:                                                 "MME/SGSN refuses due to VPLMN Policy",
+	CauseGTPCEntityCongestion:                                                           "GTP-C Entity Congestion",
+	CauseLateOverlappingRequest:                                                         "Late Overlapping Request",
+	CauseTimedOutRequest:                                                                "Timed out Request",
+	CauseUEIsTemporarilyNotReachableDueToPowerSaving:                                    "UE is temporarily not reachable due to power saving",
+	CauseRelocationFailureDueToNASMessageRedirection:                                    "Relocation failure due to NAS message redirection",
+	CauseUENotAuthorisedByOCSOrExternalAAAServer:                                        "UE not authorised by OCS or external AAA Server",
+	CauseMultipleAccessesToAPDNConnectionNotAllowed:                                     "Multiple accesses to a PDN connection not allowed",
+	CauseRequestRejectedDueToUECapability:                                               "Request rejected due to UE capability",
+	CauseS1UPathFailure:                                                                 "S1-U Path Failure",
+}
+
+// retryableCauses is the rejection Causes that indicate the temporary condition
+// of the peer, with which the request may succeed if it is sent again later.
+var retryableCauses = map[uint8]bool{
+	CauseNoResourcesAvailable:                                      true,
+	CauseNoMemoryAvailable:                                         true,
+	CauseRemotePeerNotResponding:                                   true,
+	CauseCollisionWithNetworkInitiatedRequest:                      true,
+	CauseTemporarilyRejectedDueToHandoverTAURAUProcedureInProgress: true,
+	CauseAPNCongestion:                                             true,
+	CauseGTPCEntityCongestion:                                      true,
+	CauseTimedOutRequest:                                           true,
+	CauseUEIsTemporarilyNotReachableDueToPowerSaving:               true,
+}
+
+// CauseName returns the name of the Cause value as defined in TS 29.274.
+//
+// For the values not defined, it returns "Unknown(<value>)".
+func CauseName(cause uint8) string {
+	if name, ok := causeNames[cause]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", cause)
+}
+
+// CauseCategoryOf returns the CauseCategory of the Cause value.
+func CauseCategoryOf(cause uint8) CauseCategory {
+	switch {
+	case cause >= 2 && cause < CauseRequestAccepted:
+		return CauseCategoryRequest
+	case cause >= CauseRequestAccepted && cause < CauseContextNotFound:
+		return CauseCategoryAcceptance
+	case cause >= CauseContextNotFound && cause < 240:
+		return CauseCategoryRejection
+	default:
+		return CauseCategoryUnknown
+	}
+}
+
+// IsAcceptedCause reports whether the Cause value in the response means the
+// acceptance of the request.
+func IsAcceptedCause(cause uint8) bool {
+	return CauseCategoryOf(cause) == CauseCategoryAcceptance
+}
+
+// IsRetryableCause reports whether the request rejected with the Cause value may
+// be accepted if it is sent again later, e.g., the rejection due to the congestion
+// or the collision with another procedure.
+func IsRetryableCause(cause uint8) bool {
+	return retryableCauses[cause]
+}
+
+// CauseHasOffendingIE reports whether the Cause IE with the value should contain
+// the type and instance of the offending IE, which can be given to ie.NewCause.
+func CauseHasOffendingIE(cause uint8) bool {
+	switch cause {
+	case CauseMandatoryIEIncorrect, CauseMandatoryIEMissing, CauseConditionalIEMissing:
+		return true
+	default:
+		return false
+	}
+}
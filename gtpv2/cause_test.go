@@ -0,0 +1,124 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func TestCauseCatalog(t *testing.T) {
+	cases := []struct {
+		cause     uint8
+		name      string
+		category  gtpv2.CauseCategory
+		retryable bool
+		offending bool
+	}{
+		{gtpv2.CauseLocalDetach, "Local Detach", gtpv2.CauseCategoryRequest, false, false},
+		{gtpv2.CauseRequestAccepted, "Request accepted", gtpv2.CauseCategoryAcceptance, false, false},
+		{gtpv2.CauseMandatoryIEMissing, "Mandatory IE missing", gtpv2.CauseCategoryRejection, false, true},
+		{gtpv2.CauseGTPCEntityCongestion, "GTP-C Entity Congestion", gtpv2.CauseCategoryRejection, true, false},
+		{250, "Unknown(250)", gtpv2.CauseCategoryUnknown, false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := gtpv2.CauseName(c.cause); got != c.name {
+				t.Errorf("wrong name: %s", got)
+			}
+			if got := gtpv2.CauseCategoryOf(c.cause); got != c.category {
+				t.Errorf("wrong category: %s", got)
+			}
+			if got := gtpv2.IsRetryableCause(c.cause); got != c.retryable {
+				t.Errorf("wrong retryable: %v", got)
+			}
+			if got := gtpv2.CauseHasOffendingIE(c.cause); got != c.offending {
+				t.Errorf("wrong offending IE: %v", got)
+			}
+		})
+	}
+}
+
+func TestRejectWithCauseError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srvConn := gtpv2.NewConn(&net.UDPAddr{IP: net.IP{127, 0, 0, 1}}, gtpv2.IFTypeS11S4SGWGTPC, 0)
+	srvConn.AddHandler(
+		message.MsgTypeCreateSessionRequest,
+		func(c *gtpv2.Conn, cliAddr net.Addr, msg message.Message) error {
+			csReq := msg.(*message.CreateSessionRequest)
+			if csReq.IMSI == nil {
+				return fmt.Errorf("wrapped: %w", gtpv2.NewCauseError(
+					gtpv2.CauseMandatoryIEMissing, ie.New(ie.IMSI, 0, nil), "no IMSI",
+				))
+			}
+			return nil
+		},
+	)
+	if err := srvConn.Listen(ctx); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if err := srvConn.Serve(ctx); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	cli, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	req, err := message.NewCreateSessionRequest(0, 0x10,
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11MMEGTPC, 0xdeadbeef, "127.0.0.1", ""),
+	).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.WriteTo(req, srvConn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1500)
+	if err := cli.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, _, err := cli.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := message.Parse(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csRsp, ok := msg.(*message.CreateSessionResponse)
+	if !ok {
+		t.Fatalf("got unexpected message: %T", msg)
+	}
+	if csRsp.TEID() != 0xdeadbeef || csRsp.Sequence() != 0x10 {
+		t.Errorf("wrong header: TEID: %#x, Seq: %#x", csRsp.TEID(), csRsp.Sequence())
+	}
+	if got := csRsp.Cause.MustCause(); got != gtpv2.CauseMandatoryIEMissing {
+		t.Errorf("wrong Cause: %d", got)
+	}
+	offending, err := csRsp.Cause.OffendingIE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offending.Type != ie.IMSI {
+		t.Errorf("wrong offending IE: %d", offending.Type)
+	}
+}
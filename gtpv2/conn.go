@@ -6,8 +6,6 @@ package gtpv2
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -16,8 +14,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/wmnsk/go-gtp"
 	"github.com/wmnsk/go-gtp/gtpv2/ie"
 	"github.com/wmnsk/go-gtp/gtpv2/message"
+	"github.com/wmnsk/go-gtp/teid"
 )
 
 // Conn represents a GTPv2-C connection.
@@ -29,14 +29,21 @@ import (
 // connection(=between a node to another).
 // See the docs of CreateSession, AddSession, DeleteSession methods for details.
 type Conn struct {
-	mu      sync.Mutex
-	laddr   net.Addr
-	pktConn net.PacketConn
-	*imsiSessionMap
-	*iteiSessionMap
-	localIfType uint8
+	mu            sync.Mutex
+	laddr         net.Addr
+	pktConn       net.PacketConn
+	store         SessionStore
+	teidAllocator teid.Allocator
+	ipPool        *IPPool
+	localIfType   uint8
 
-	validationEnabled bool
+	sessionStateHandler func(session *Session, change *StateChange)
+
+	validationEnabled       bool
+	schemaValidationEnabled bool
+	lintEnabled             bool
+
+	tap gtp.TapFunc
 
 	closeCh chan struct{}
 	*msgHandlerMap
@@ -58,8 +65,8 @@ func NewConn(laddr net.Addr, localIfType, counter uint8) *Conn {
 	return &Conn{
 		mu:                sync.Mutex{},
 		laddr:             laddr,
-		imsiSessionMap:    newimsiSessionMap(),
-		iteiSessionMap:    newiteiSessionMap(),
+		store:             NewMemorySessionStore(),
+		teidAllocator:     teid.NewDefaultAllocator(),
 		localIfType:       localIfType,
 		validationEnabled: true,
 		closeCh:           make(chan struct{}),
@@ -79,8 +86,8 @@ func Dial(ctx context.Context, laddr, raddr net.Addr, localIfType, counter uint8
 	c := &Conn{
 		mu:                sync.Mutex{},
 		laddr:             laddr,
-		imsiSessionMap:    newimsiSessionMap(),
-		iteiSessionMap:    newiteiSessionMap(),
+		store:             NewMemorySessionStore(),
+		teidAllocator:     teid.NewDefaultAllocator(),
 		localIfType:       localIfType,
 		validationEnabled: true,
 		closeCh:           make(chan struct{}),
@@ -116,6 +123,7 @@ func Dial(ctx context.Context, laddr, raddr net.Addr, localIfType, counter uint8
 	if err := c.pktConn.SetReadDeadline(time.Time{}); err != nil {
 		return nil, err
 	}
+	c.capture(gtp.DirectionReceived, raddr, buf[:n])
 
 	// decode incoming message and let it be handled by default handler funcs.
 	msg, err := message.Parse(buf[:n])
@@ -195,6 +203,8 @@ func (c *Conn) Serve(ctx context.Context) error {
 		raw := make([]byte, n)
 		copy(raw, buf)
 		go func() {
+			c.capture(gtp.DirectionReceived, raddr, raw)
+
 			msg, err := message.Parse(raw)
 			if err != nil {
 				logf("error parsing the message: %v, %x", err, raw)
@@ -228,7 +238,11 @@ func (c *Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 // see SetDeadline and SetWriteDeadline.
 // On packet-oriented connections, write timeouts are rare.
 func (c *Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
-	return c.pktConn.WriteTo(p, addr)
+	n, err = c.pktConn.WriteTo(p, addr)
+	if err == nil {
+		c.capture(gtp.DirectionSent, addr, p[:n])
+	}
+	return n, err
 }
 
 // Close closes the connection.
@@ -294,6 +308,8 @@ func (c *Conn) SetWriteDeadline(t time.Time) error {
 //
 // The error returned from handler is just logged. Any important due should be done inside
 // the HandlerFunc before returning. This behavior might change in the future.
+// The exception is *CauseError returned for a request, with which Conn rejects the request
+// by sending the response with the Cause IE. See RejectRequest.
 //
 // HandlerFunc for EchoResponse and VersionNotSupportedIndication are registered by default.
 // These HandlerFunc can be overridden by specifying message.MsgTypeEchoResponse and/or
@@ -320,12 +336,52 @@ func (c *Conn) handleMessage(senderAddr net.Addr, msg message.Message) error {
 		}
 	}
 
+	if c.schemaValidationEnabled {
+		if err := c.validateSchema(senderAddr, msg); err != nil {
+			return fmt.Errorf("failed to validate %s: %w", msg.MessageTypeName(), err)
+		}
+	}
+
 	handle, ok := c.msgHandlerMap.load(msg.MessageType())
 	if !ok {
 		return &HandlerNotFoundError{MsgType: msg.MessageTypeName()}
 	}
 
+	// the request not allowed in the state of Session is rejected without being
+	// passed to the handler, and the unexpected response is discarded. The
+	// retransmission of the request being handled is discarded as well.
+	var session *Session
+	if teid := msg.TEID(); teid != 0 {
+		if sess, err := c.GetSessionByTEID(teid, senderAddr); err == nil {
+			retransmitted, err := sess.transition(msg, senderAddr)
+			if err != nil {
+				var causeErr *CauseError
+				if errors.As(err, &causeErr) {
+					if err := c.RejectRequest(senderAddr, msg, causeErr); err != nil {
+						logf("failed to reject %s: %v", msg.MessageTypeName(), err)
+					}
+				}
+				return fmt.Errorf("failed to handle %s: %w", msg.MessageTypeName(), err)
+			}
+			if retransmitted {
+				return nil
+			}
+			session = sess
+		}
+	}
+
 	if err := handle(c, senderAddr, msg); err != nil {
+		var causeErr *CauseError
+		if errors.As(err, &causeErr) {
+			if err := c.RejectRequest(senderAddr, msg, causeErr); err != nil {
+				logf("failed to reject %s: %v", msg.MessageTypeName(), err)
+			}
+		}
+		// the state goes back if the request is not responded, so that the
+		// following requests are not rejected as the collision.
+		if session != nil {
+			session.cancel(msg)
+		}
 		return fmt.Errorf("failed to handle %s: %w", msg.MessageTypeName(), err)
 	}
 
@@ -378,9 +434,101 @@ func (c *Conn) validate(senderAddr net.Addr, msg message.Message) error {
 	return nil
 }
 
+// SetTap registers tap to be called with every datagram sent and received on Conn,
+// including the ones that cannot be decoded or are discarded by the validation.
+// Passing nil removes the tap.
+//
+// This is useful to record the messages exchanged, e.g., with (*pcap.Writer).WriteCapture
+// or (*gtp.Recorder).Tap.
+func (c *Conn) SetTap(tap gtp.TapFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tap = tap
+}
+
+func (c *Conn) capture(dir gtp.Direction, raddr net.Addr, b []byte) {
+	c.mu.Lock()
+	tap := c.tap
+	c.mu.Unlock()
+
+	if tap != nil {
+		tap(gtp.NewCapture(dir, c.LocalAddr(), raddr, b))
+	}
+}
+
+// EnableSchemaValidation turns on the validation of incoming message against the
+// MessageSchema registered for its type. See ValidateMessage for what are validated.
+//
+// If the validation of an initial message fails, Conn responds to it with the Cause
+// "Mandatory IE missing" or "Mandatory IE incorrect" and the offending IE, instead of
+// passing it to HandlerFunc. The invalid triggered messages are just discarded.
+func (c *Conn) EnableSchemaValidation() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schemaValidationEnabled = true
+}
+
+// DisableSchemaValidation turns off the validation of incoming message against the
+// MessageSchema. This is the default.
+func (c *Conn) DisableSchemaValidation() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schemaValidationEnabled = false
+}
+
+// EnableLint turns on the validation of outgoing message against the MessageSchema
+// registered for its type. The message that fails the validation is not sent, and
+// SendMessageTo and RespondTo return *CauseError.
+//
+// This is useful in testing to find the messages that the peer would reject.
+func (c *Conn) EnableLint() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lintEnabled = true
+}
+
+// DisableLint turns off the validation of outgoing message. This is the default.
+func (c *Conn) DisableLint() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lintEnabled = false
+}
+
+func (c *Conn) validateSchema(senderAddr net.Addr, msg message.Message) error {
+	err := ValidateMessage(msg)
+	if err == nil {
+		return nil
+	}
+
+	var causeErr *CauseError
+	if !errors.As(err, &causeErr) {
+		return err
+	}
+	if _, ok := responseTypes[msg.MessageType()]; ok {
+		if err := c.RejectRequest(senderAddr, msg, causeErr); err != nil {
+			logf("failed to reject %s: %v", msg.MessageTypeName(), err)
+		}
+	}
+	return err
+}
+
+func (c *Conn) lint(msg message.Message) error {
+	if !c.lintEnabled {
+		return nil
+	}
+	if err := ValidateMessage(msg); err != nil {
+		return fmt.Errorf("failed to lint %s: %w", msg.MessageTypeName(), err)
+	}
+	return nil
+}
+
 // SendMessageTo sends a message to addr.
 // Unlike WriteTo, it sets the Sequence Number properly and returns the one used in the message.
 func (c *Conn) SendMessageTo(msg message.Message, addr net.Addr) (uint32, error) {
+	if err := c.lint(msg); err != nil {
+		return 0, err
+	}
+
 	seq := c.IncSequence()
 	msg.SetSequenceNumber(seq)
 
@@ -605,7 +753,7 @@ func (c *Conn) CreateSession(raddr net.Addr, ie ...*ie.IE) (*Session, uint32, er
 	// set IEs into CreateSessionRequest.
 	msg := message.NewCreateSessionRequest(0, 0, ie...)
 
-	seq, err := c.SendMessageTo(msg, raddr)
+	seq, err := c.sendRequest(sess, msg)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -616,7 +764,7 @@ func (c *Conn) CreateSession(raddr net.Addr, ie ...*ie.IE) (*Session, uint32, er
 func (c *Conn) DeleteSession(teid uint32, sess *Session, ie ...*ie.IE) (uint32, error) {
 	msg := message.NewDeleteSessionRequest(teid, 0, ie...)
 
-	seq, err := c.SendMessageTo(msg, sess.peerAddr)
+	seq, err := c.sendRequest(sess, msg)
 	if err != nil {
 		return 0, err
 	}
@@ -627,7 +775,7 @@ func (c *Conn) DeleteSession(teid uint32, sess *Session, ie ...*ie.IE) (uint32,
 func (c *Conn) ModifyBearer(teid uint32, sess *Session, ie ...*ie.IE) (uint32, error) {
 	msg := message.NewModifyBearerRequest(teid, 0, ie...)
 
-	seq, err := c.SendMessageTo(msg, sess.peerAddr)
+	seq, err := c.sendRequest(sess, msg)
 	if err != nil {
 		return 0, err
 	}
@@ -638,8 +786,23 @@ func (c *Conn) ModifyBearer(teid uint32, sess *Session, ie ...*ie.IE) (uint32, e
 func (c *Conn) DeleteBearer(teid uint32, sess *Session, ie ...*ie.IE) (uint32, error) {
 	msg := message.NewDeleteBearerRequest(teid, 0, ie...)
 
+	seq, err := c.sendRequest(sess, msg)
+	if err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
+
+// sendRequest sends the request on Session after validating it against the state
+// of Session. The state is reverted if it fails to send.
+func (c *Conn) sendRequest(sess *Session, msg message.Message) (uint32, error) {
+	if err := sess.Transition(msg); err != nil {
+		return 0, err
+	}
+
 	seq, err := c.SendMessageTo(msg, sess.peerAddr)
 	if err != nil {
+		sess.cancel(msg)
 		return 0, err
 	}
 	return seq, nil
@@ -648,8 +811,54 @@ func (c *Conn) DeleteBearer(teid uint32, sess *Session, ie ...*ie.IE) (uint32, e
 // RespondTo sends a message(specified with "toBeSent" param) in response to a message
 // (specified with "received" param).
 //
-// This exists to make it easier to handle SequenceNumber.
+// This exists to make it easier to handle SequenceNumber. If the message is sent on
+// a Session registered to Conn, the state of Session is updated with it, and error
+// is returned without sending it if it is not allowed in the current state.
 func (c *Conn) RespondTo(raddr net.Addr, received, toBeSent message.Message) error {
+	if err := c.lint(toBeSent); err != nil {
+		return err
+	}
+
+	if sess := c.respondingSession(raddr, received, toBeSent); sess != nil {
+		// Create Session Request is not given to the Session in handleMessage, as
+		// the Session is created in the handler.
+		if received.TEID() == 0 {
+			if err := sess.Transition(received); err != nil {
+				return err
+			}
+		}
+		if err := sess.Transition(toBeSent); err != nil {
+			return err
+		}
+	}
+
+	return c.writeResponse(raddr, received, toBeSent)
+}
+
+// respondingSession returns the Session that the response is sent on, or nil if not
+// found. It is looked up by the TEID in the request, or by the Sender F-TEID in the
+// response to the request without TEID, i.e., Create Session Request.
+func (c *Conn) respondingSession(raddr net.Addr, received, toBeSent message.Message) *Session {
+	teid := received.TEID()
+	if teid == 0 {
+		res, ok := toBeSent.(*message.CreateSessionResponse)
+		if !ok || res.SenderFTEIDC == nil {
+			return nil
+		}
+		var err error
+		if teid, err = res.SenderFTEIDC.TEID(); err != nil {
+			return nil
+		}
+	}
+
+	sess, err := c.GetSessionByTEID(teid, raddr)
+	if err != nil {
+		return nil
+	}
+	return sess
+}
+
+func (c *Conn) writeResponse(raddr net.Addr, received, toBeSent message.Message) error {
 	toBeSent.SetSequenceNumber(received.Sequence())
 	b := make([]byte, toBeSent.MarshalLen())
 
@@ -663,11 +872,42 @@ func (c *Conn) RespondTo(raddr net.Addr, received, toBeSent message.Message) err
 	return nil
 }
 
+// OnSessionStateChange registers fn to be called when the SessionState of any
+// Session registered to Conn afterwards is changed. It does not replace the one
+// registered to each Session with (*Session).OnStateChange, and fn is called after it.
+func (c *Conn) OnSessionStateChange(fn func(session *Session, change *StateChange)) {
+	c.mu.Lock()
+	c.sessionStateHandler = fn
+	c.mu.Unlock()
+}
+
+// SetSessionStore replaces the SessionStore used by Conn with store.
+//
+// This should be called before any Session is registered, as the Sessions
+// in the current store are not moved to the new one.
+func (c *Conn) SetSessionStore(store SessionStore) {
+	c.store = store
+}
+
+// SetIPPool sets the IPPool from which the UE IP addresses are allocated.
+//
+// The address(es) allocated to the subscriber of a Session are released when
+// the Session is removed with RemoveSession, e.g., in the handler of Delete
+// Session Request or Response.
+func (c *Conn) SetIPPool(pool *IPPool) {
+	c.mu.Lock()
+	c.ipPool = pool
+	c.mu.Unlock()
+}
+
 // GetSessionByTEID returns Session looked up by TEID and sender of the message.
 func (c *Conn) GetSessionByTEID(teid uint32, peer net.Addr) (*Session, error) {
-	session, ok := c.iteiSessionMap.load(teid)
-	if !ok {
-		return nil, &InvalidTEIDError{TEID: teid}
+	session, err := c.store.LoadByTEID(teid)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, &InvalidTEIDError{TEID: teid}
+		}
+		return nil, err
 	}
 	if peer.String() != session.peerAddrString {
 		return nil, &InvalidTEIDError{TEID: teid}
@@ -675,12 +915,126 @@ func (c *Conn) GetSessionByTEID(teid uint32, peer net.Addr) (*Session, error) {
 	return session, nil
 }
 
+// GetSessionByBearerTEID returns Session and the EBI of the Bearer looked up by
+// U-Plane TEID and the address of the U-Plane peer.
+//
+// This is meant to be used to find the Session affected by Error Indication received
+// on U-Plane, by giving the values in TEID Data I and GTP-U Peer Address IEs. As
+// TEID Data I is the TEID that the peer received, only the outgoing TEIDs of the
+// Bearers are looked into. Only the IP address of peer is compared with the remote
+// address of the Bearer, and the comparison is skipped if the remote address is not
+// set in the Bearer.
+func (c *Conn) GetSessionByBearerTEID(teid uint32, peer net.Addr) (*Session, uint8, error) {
+	var (
+		session *Session
+		ebi     uint8
+	)
+	err := c.store.Range(func(sess *Session) bool {
+		for _, br := range sess.Bearers() {
+			if br.teidOut != teid {
+				continue
+			}
+			if peer != nil && br.raddr != nil && !sameIP(peer, br.raddr) {
+				continue
+			}
+			session, ebi = sess, br.EBI
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if session == nil {
+		return nil, 0, &InvalidTEIDError{TEID: teid}
+	}
+	return session, ebi, nil
+}
+
+func sameIP(a, b net.Addr) bool {
+	ipA, _, err := net.SplitHostPort(a.String())
+	if err != nil {
+		ipA = a.String()
+	}
+	ipB, _, err := net.SplitHostPort(b.String())
+	if err != nil {
+		ipB = b.String()
+	}
+	return net.ParseIP(ipA).Equal(net.ParseIP(ipB))
+}
+
 // GetSessionByIMSI returns Session looked up by IMSI.
+//
+// If the subscriber has multiple PDN connections, the one registered first is
+// returned. Use GetSessionsByIMSI, GetSessionByIMSIAndEBI or GetSessionByIMSIAndAPN
+// to get the others.
 func (c *Conn) GetSessionByIMSI(imsi string) (*Session, error) {
-	if session, ok := c.imsiSessionMap.load(imsi); ok {
-		return session, nil
+	sessions, err := c.GetSessionsByIMSI(imsi)
+	if err != nil {
+		return nil, err
 	}
-	return nil, &UnknownIMSIError{IMSI: imsi}
+	return sessions[0], nil
+}
+
+// GetSessionsByIMSI returns all the Sessions(PDN connections) of the subscriber
+// looked up by IMSI, in the order of registration.
+func (c *Conn) GetSessionsByIMSI(imsi string) ([]*Session, error) {
+	sessions, err := c.store.LoadByIMSI(imsi)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, &UnknownIMSIError{IMSI: imsi}
+		}
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, &UnknownIMSIError{IMSI: imsi}
+	}
+	return sessions, nil
+}
+
+// GetSessionsByPeer returns all the Sessions with the peer node.
+// It returns no error with empty result if no Session is found.
+func (c *Conn) GetSessionsByPeer(peer net.Addr) ([]*Session, error) {
+	sessions, err := c.store.LoadByPeer(peer)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetSessionByIMSIAndEBI returns Session looked up by IMSI and EBI.
+//
+// ebi can be either the EBI of the default bearer(LBI) or the one of a dedicated
+// bearer that belongs to the PDN connection.
+func (c *Conn) GetSessionByIMSIAndEBI(imsi string, ebi uint8) (*Session, error) {
+	sessions, err := c.GetSessionsByIMSI(imsi)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sess := range sessions {
+		if _, err := sess.LookupBearerByEBI(ebi); err == nil {
+			return sess, nil
+		}
+	}
+	return nil, &BearerNotFoundError{IMSI: imsi}
+}
+
+// GetSessionByIMSIAndAPN returns Session looked up by IMSI and the APN of the
+// default bearer.
+func (c *Conn) GetSessionByIMSIAndAPN(imsi, apn string) (*Session, error) {
+	sessions, err := c.GetSessionsByIMSI(imsi)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sess := range sessions {
+		if sess.APN() == apn {
+			return sess, nil
+		}
+	}
+	return nil, &UnknownAPNError{APN: apn}
 }
 
 // GetIMSIByTEID returns IMSI associated with TEID and the peer node.
@@ -699,99 +1053,138 @@ func (c *Conn) GetIMSIByTEID(teid uint32, peer net.Addr) (string, error) {
 // Incoming TEID(itei) should be the one with it's local interface type.
 // e.g., if the Conn is used for S-GW on S11 I/F, itei should be the one
 // with interface type=IFTypeS11S4SGWGTPC.
+//
+// A subscriber can have multiple Sessions as PDN connections, which are distinguished
+// by the EBI of the default bearer or APN. If the Session for the same PDN connection
+// is already registered, it is replaced with the given one.
+//
+// If the SessionStore holds copies of Session, the changes made to the Session after
+// the registration should be stored again by calling RegisterSession.
 func (c *Conn) RegisterSession(itei uint32, session *Session) {
-	c.iteiSessionMap.store(itei, session)
-	c.imsiSessionMap.store(session.IMSI, session)
+	if err := c.registerSession(itei, session); err != nil {
+		logf("failed to store session: %+v", err)
+	}
+}
 
+func (c *Conn) registerSession(itei uint32, session *Session) error {
 	session.AddTEID(c.localIfType, itei)
+
+	c.mu.Lock()
+	if fn := c.sessionStateHandler; fn != nil {
+		session.onConnStateChange(fn)
+	}
+	c.mu.Unlock()
+
+	// mark as used in case it is not allocated by NewSenderFTEID.
+	// the error is expected if so, and can be ignored.
+	_ = c.teidAllocator.Reserve(itei)
+
+	return c.store.Store(itei, session)
 }
 
 // RemoveSession removes a session registered in a Conn.
+//
+// The other PDN connections of the same subscriber are kept as they are. If
+// IPPool is set with SetIPPool, the UE IP address(es) of the session are
+// released as well.
 func (c *Conn) RemoveSession(session *Session) {
-	c.imsiSessionMap.delete(session.IMSI)
-
 	itei, err := session.GetTEID(c.localIfType)
 	if err != nil { // if incoming TEID could not be found for some reason
 		logf("failed to find incoming TEID in session: %+v", err)
+	} else {
+		c.teidAllocator.Release(itei)
+	}
 
-		c.iteiSessionMap.rangeWithFunc(func(k, v interface{}) bool {
-			s := v.(*Session)
-			if s.IMSI == session.IMSI {
-				c.iteiSessionMap.delete(k.(uint32))
-			}
-			return true
-		})
+	if err := c.store.Delete(itei, session); err != nil {
+		logf("failed to delete session: %+v", err)
+	}
 
-		return
+	c.mu.Lock()
+	pool := c.ipPool
+	c.mu.Unlock()
+	if pool != nil {
+		pool.ReleaseSession(session)
 	}
-	c.iteiSessionMap.delete(itei)
 }
 
-// RemoveSessionByIMSI removes a session looked up by IMSI.
+// RemoveSessionByIMSI removes the sessions looked up by IMSI.
+// All the PDN connections of the subscriber are removed.
 //
 // Use RemoveSession instead if you already have the Session in your hand.
 func (c *Conn) RemoveSessionByIMSI(imsi string) {
-	sess, ok := c.imsiSessionMap.load(imsi)
-	if !ok {
+	sessions, err := c.GetSessionsByIMSI(imsi)
+	if err != nil {
 		logf("Session not found by IMSI: %s", imsi)
 		return
 	}
-	c.RemoveSession(sess)
+	for _, sess := range sessions {
+		c.RemoveSession(sess)
+	}
 }
 
-// NewSenderFTEID creates a new F-TEID with random TEID value that is unique within Conn.
+// RemoveSessionsByPeer removes all the sessions with the peer node.
+//
+// This is useful to clean up the sessions when the peer is found to be restarted.
+func (c *Conn) RemoveSessionsByPeer(peer net.Addr) {
+	sessions, err := c.GetSessionsByPeer(peer)
+	if err != nil {
+		logf("failed to find sessions by peer: %+v", err)
+		return
+	}
+	for _, sess := range sessions {
+		c.RemoveSession(sess)
+	}
+}
+
+// NewSenderFTEID creates a new F-TEID with TEID value that is unique within Conn.
 // To ensure the uniqueness, don't create in the other way if you once use this method.
 // This is meant to be used for creating F-TEID IE only for local interface type that is
 // specified at the creation of Conn.
 //
-// Note that in the case there's a lot of Session on the Conn, it may take a long
-// time to find a new unique value.
-//
-// TODO: optimize performance...
+// The TEID is allocated by the teid.Allocator of Conn, which allocates it randomly by
+// default. Use SetTEIDAllocator to change the way to allocate. It returns nil if no
+// TEID is available.
 func (c *Conn) NewSenderFTEID(v4, v6 string) (fteidIE *ie.IE) {
-	var teid uint32
-	for try := uint32(0); try < 0xffff; try++ {
-		const logEvery = 0xff
-		if try&logEvery == logEvery {
-			logf("Generating NewSenderFTEID crossed tries:%d", try)
+	for {
+		t, err := c.teidAllocator.Allocate()
+		if err != nil {
+			logf("failed to allocate TEID: %+v", err)
+			return nil
 		}
 
-		t := generateRandomUint32()
-		if t == 0 {
-			continue
+		// the TEID might be used by the Session registered without the allocator.
+		ok, err := c.store.Reserve(t)
+		if err != nil {
+			logf("failed to reserve TEID %#08x: %+v", t, err)
+			c.teidAllocator.Release(t)
+			return nil
 		}
-
-		// Try to mark TEID as taken. Fails if something exists
-		if ok := c.iteiSessionMap.tryStore(t, nil); !ok {
+		if !ok {
 			continue
 		}
 
-		teid = t
-		break
-	}
-
-	if teid == 0 {
-		return nil
+		return ie.NewFullyQualifiedTEID(c.localIfType, t, v4, v6)
 	}
-	return ie.NewFullyQualifiedTEID(c.localIfType, teid, v4, v6)
 }
 
-func generateRandomUint32() uint32 {
-	b := make([]byte, 4)
-	if _, err := rand.Read(b); err != nil {
-		return 0
-	}
-
-	return binary.BigEndian.Uint32(b)
+// SetTEIDAllocator replaces the teid.Allocator used by NewSenderFTEID with a.
+//
+// This should be called before any TEID is allocated, as the TEIDs allocated by the
+// current one are not moved to the new one, except the ones of the Sessions that
+// are registered later.
+func (c *Conn) SetTEIDAllocator(a teid.Allocator) {
+	c.teidAllocator = a
 }
 
 // Sessions returns all the sessions registered in Conn.
 func (c *Conn) Sessions() []*Session {
 	var ss []*Session
-	c.imsiSessionMap.rangeWithFunc(func(k, v interface{}) bool {
-		ss = append(ss, v.(*Session))
+	if err := c.store.Range(func(sess *Session) bool {
+		ss = append(ss, sess)
 		return true
-	})
+	}); err != nil {
+		logf("failed to range over sessions: %+v", err)
+	}
 
 	return ss
 }
@@ -801,13 +1194,14 @@ func (c *Conn) Sessions() []*Session {
 // This may have some impact on performance in case of large number of Session exists.
 func (c *Conn) SessionCount() int {
 	var count int
-	c.imsiSessionMap.rangeWithFunc(func(k, v interface{}) bool {
-		sess := v.(*Session)
+	if err := c.store.Range(func(sess *Session) bool {
 		if sess.IsActive() {
 			count++
 		}
 		return true
-	})
+	}); err != nil {
+		logf("failed to range over sessions: %+v", err)
+	}
 
 	return count
 }
@@ -817,74 +1211,14 @@ func (c *Conn) SessionCount() int {
 // This may have some impact on performance in case of large number of Session and Bearer exist.
 func (c *Conn) BearerCount() int {
 	var count int
-	c.imsiSessionMap.rangeWithFunc(func(k, v interface{}) bool {
-		sess := v.(*Session)
+	if err := c.store.Range(func(sess *Session) bool {
 		if sess.IsActive() {
 			count += sess.BearerCount()
 		}
 		return true
-	})
-
-	return count
-}
-
-type imsiSessionMap struct {
-	syncMap sync.Map
-}
-
-func newimsiSessionMap() *imsiSessionMap {
-	return &imsiSessionMap{}
-}
-
-func (i *imsiSessionMap) store(imsi string, session *Session) {
-	i.syncMap.Store(imsi, session)
-}
-
-func (i *imsiSessionMap) load(imsi string) (*Session, bool) {
-	session, ok := i.syncMap.Load(imsi)
-	if ok && session != nil {
-		return session.(*Session), true
-	}
-	return nil, ok
-}
-
-func (i *imsiSessionMap) delete(imsi string) {
-	i.syncMap.Delete(imsi)
-}
-
-func (i *imsiSessionMap) rangeWithFunc(fn func(imsi, session interface{}) bool) {
-	i.syncMap.Range(fn)
-}
-
-type iteiSessionMap struct {
-	syncMap sync.Map
-}
-
-func newiteiSessionMap() *iteiSessionMap {
-	return &iteiSessionMap{}
-}
-
-func (t *iteiSessionMap) store(teid uint32, session *Session) {
-	t.syncMap.Store(teid, session)
-}
-
-func (t *iteiSessionMap) tryStore(teid uint32, session *Session) bool {
-	_, loaded := t.syncMap.LoadOrStore(teid, session)
-	return !loaded
-}
-
-func (t *iteiSessionMap) load(teid uint32) (*Session, bool) {
-	session, ok := t.syncMap.Load(teid)
-	if ok && session != nil {
-		return session.(*Session), true
+	}); err != nil {
+		logf("failed to range over sessions: %+v", err)
 	}
-	return nil, ok
-}
 
-func (t *iteiSessionMap) delete(teid uint32) {
-	t.syncMap.Delete(teid)
-}
-
-func (t *iteiSessionMap) rangeWithFunc(fn func(imsi, session interface{}) bool) {
-	t.syncMap.Range(fn)
+	return count
 }
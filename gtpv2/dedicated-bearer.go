@@ -0,0 +1,526 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"fmt"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// DedicatedBearer is a Bearer to be created or updated in the network initiated
+// dedicated bearer procedures, with the IEs that are not kept in Bearer.
+type DedicatedBearer struct {
+	*Bearer
+
+	// TFT is the Bearer TFT IE of the bearer.
+	TFT *ie.IE
+
+	// FTEIDs are the U-Plane F-TEID IEs of the local node to be put in the Bearer Context,
+	// e.g., S5/S8-U PGW F-TEID. The TEID in the first one is set as the incoming TEID of
+	// the Bearer when it is created.
+	FTEIDs []*ie.IE
+
+	// Cause is the result of the bearer to be set in the response by the receiver of
+	// the request. Zero is regarded as Request accepted.
+	Cause uint8
+}
+
+// NewDedicatedBearer creates a new DedicatedBearer.
+//
+// The EBI of the Bearer should be zero when it is used in Create Bearer Request, as
+// it is assigned by the MME.
+func NewDedicatedBearer(ebi uint8, apn string, qos *QoSProfile, tft *ie.IE, fTEIDs ...*ie.IE) *DedicatedBearer {
+	return &DedicatedBearer{
+		Bearer: NewBearer(ebi, apn, qos),
+		TFT:    tft,
+		FTEIDs: fTEIDs,
+	}
+}
+
+// bearerProcedure is the dedicated bearer procedure waiting for the response.
+type bearerProcedure struct {
+	msgType uint8
+	bearers []*DedicatedBearer
+	ebis    []uint8
+}
+
+// dedicatedBearerName returns the name of the Bearer created by the dedicated
+// bearer procedures.
+func dedicatedBearerName(ebi uint8) string {
+	return fmt.Sprintf("dedicated-%d", ebi)
+}
+
+// CreateBearer sends a CreateBearerRequest with the Bearer Contexts built from
+// bearers and the IEs given, and returns the sequence number of the request.
+//
+// The Bearer Contexts contain EBI, Bearer TFT, Bearer QoS built from the QoSProfile
+// if it is not nil, Charging ID if it is not zero, and the U-Plane F-TEIDs in
+// DedicatedBearer. The Linked EBI IE is built from lbi.
+//
+// The bearers are kept in Session until the response is given to (*Session).ApplyCreateBearerResponse,
+// which adds the accepted ones to Session.
+func (c *Conn) CreateBearer(teid uint32, sess *Session, lbi uint8, bearers []*DedicatedBearer, ies ...*ie.IE) (uint32, error) {
+	var bcs []*ie.IE
+	for _, br := range bearers {
+		var qos, chargingID *ie.IE
+		if br.QoSProfile != nil {
+			qos = newBearerQoS(br.QoSProfile)
+		}
+		if br.ChargingID != 0 {
+			chargingID = ie.NewChargingID(br.ChargingID)
+		}
+		bcs = append(bcs, ie.NewBearerContextWithinCreateBearerRequest(
+			ie.NewEPSBearerID(br.EBI), br.TFT, qos, chargingID,
+			nil, nil, nil, nil, br.FTEIDs...,
+		))
+	}
+
+	msg := message.NewCreateBearerRequest(teid, 0, append([]*ie.IE{ie.NewEPSBearerID(lbi)}, ies...)...)
+	msg.BearerContexts, msg.AdditionalIEs = splitRepeatedIEs(bcs, msg.AdditionalIEs)
+
+	seq, err := c.sendRequest(sess, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	sess.storeBearerProcedure(seq, &bearerProcedure{
+		msgType: message.MsgTypeCreateBearerRequest, bearers: bearers,
+	})
+	return seq, nil
+}
+
+// UpdateBearer sends an UpdateBearerRequest with the Bearer Contexts built from
+// bearers and the IEs given, and returns the sequence number of the request.
+//
+// The Bearer Contexts contain EBI, Bearer TFT if it is not nil, and Bearer QoS built
+// from the QoSProfile if it is not nil. The QoSProfile of the Bearer in Session is
+// updated when the response is given to (*Session).ApplyUpdateBearerResponse.
+func (c *Conn) UpdateBearer(teid uint32, sess *Session, bearers []*DedicatedBearer, ies ...*ie.IE) (uint32, error) {
+	var bcs []*ie.IE
+	for _, br := range bearers {
+		var qos *ie.IE
+		if br.QoSProfile != nil {
+			qos = newBearerQoS(br.QoSProfile)
+		}
+		bcs = append(bcs, ie.NewBearerContextWithinUpdateBearerRequest(
+			ie.NewEPSBearerID(br.EBI), br.TFT, qos, nil, nil, nil, nil, nil,
+		))
+	}
+
+	msg := message.NewUpdateBearerRequest(teid, 0, ies...)
+	msg.BearerContexts, msg.AdditionalIEs = splitRepeatedIEs(bcs, msg.AdditionalIEs)
+
+	seq, err := c.sendRequest(sess, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	sess.storeBearerProcedure(seq, &bearerProcedure{
+		msgType: message.MsgTypeUpdateBearerRequest, bearers: bearers,
+	})
+	return seq, nil
+}
+
+// DeleteDedicatedBearers sends a DeleteBearerRequest with the EBIs of the dedicated
+// bearers and the IEs given, and returns the sequence number of the request.
+//
+// The Bearers are removed from Session when the response is given to (*Session).ApplyDeleteBearerResponse.
+// To delete the default bearer, use DeleteBearer with Linked EBI IE instead.
+func (c *Conn) DeleteDedicatedBearers(teid uint32, sess *Session, ebis []uint8, ies ...*ie.IE) (uint32, error) {
+	var ebiIEs []*ie.IE
+	for _, ebi := range ebis {
+		ebiIEs = append(ebiIEs, ie.NewEPSBearerID(ebi).WithInstance(1))
+	}
+
+	msg := message.NewDeleteBearerRequest(teid, 0, ies...)
+	msg.EBI, msg.AdditionalIEs = splitRepeatedIEs(ebiIEs, msg.AdditionalIEs)
+
+	seq, err := c.sendRequest(sess, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	sess.storeBearerProcedure(seq, &bearerProcedure{
+		msgType: message.MsgTypeDeleteBearerRequest, ebis: ebis,
+	})
+	return seq, nil
+}
+
+// ParseCreateBearerRequest returns the DedicatedBearers requested in CreateBearerRequest
+// received, which is for the receiver of the request, e.g., MME or SGW.
+//
+// The EBI, Bearer TFT, Bearer QoS and Charging ID are set to the DedicatedBearers, and
+// the first U-Plane F-TEID is set as the outgoing TEID and the remote address of the
+// Bearer. The EBI is zero if the request is sent by PGW, which should be assigned
+// before responding. Set the U-Plane F-TEIDs of the local node, or Cause to reject the
+// bearer, and give them to (*Conn).RespondToCreateBearer.
+func (s *Session) ParseCreateBearerRequest(req *message.CreateBearerRequest) ([]*DedicatedBearer, error) {
+	bcs, err := bearerContexts(req.Header, req.BearerContexts)
+	if err != nil {
+		return nil, err
+	}
+
+	var bearers []*DedicatedBearer
+	for _, bc := range bcs {
+		br := &DedicatedBearer{Bearer: NewBearer(0, s.APN(), &QoSProfile{})}
+		for _, child := range bc.ChildIEs {
+			switch child.Type {
+			case ie.EPSBearerID:
+				if br.EBI, err = child.EPSBearerID(); err != nil {
+					return nil, err
+				}
+			case ie.BearerTFT:
+				br.TFT = child
+			}
+		}
+		if err := s.applyBearerContext(br.Bearer, bc, false); err != nil {
+			return nil, err
+		}
+		bearers = append(bearers, br)
+	}
+	return bearers, nil
+}
+
+// RespondToCreateBearer sends a CreateBearerResponse to the request received, with the
+// Bearer Contexts built from bearers given by ParseCreateBearerRequest and the IEs given.
+//
+// The Bearer Contexts contain EBI, Cause and the U-Plane F-TEIDs in DedicatedBearer. The
+// Cause of the message is Request accepted, Request accepted partially, or the Cause of
+// the first bearer if all of them are rejected. The accepted ones are added to Session
+// with the name "dedicated-<EBI>", and the TEID in the first U-Plane F-TEID is set as
+// the incoming TEID.
+func (c *Conn) RespondToCreateBearer(teid uint32, sess *Session, req *message.CreateBearerRequest, bearers []*DedicatedBearer, ies ...*ie.IE) error {
+	var bcs []*ie.IE
+	for _, br := range bearers {
+		if br.EBI == 0 && IsAcceptedCause(br.cause()) {
+			return &RequiredParameterMissingError{"EBI", "EBI should be assigned to the accepted bearer"}
+		}
+		var fTEIDs []*ie.IE
+		if IsAcceptedCause(br.cause()) {
+			fTEIDs = br.FTEIDs
+		}
+		bcs = append(bcs, ie.NewBearerContextWithinCreateBearerResponse(
+			ie.NewEPSBearerID(br.EBI), ie.NewCause(br.cause(), 0, 0, 0, nil), nil, nil, nil, fTEIDs...,
+		))
+	}
+
+	res := message.NewCreateBearerResponse(teid, 0, append([]*ie.IE{responseCause(bearers)}, ies...)...)
+	res.BearerContexts, res.AdditionalIEs = splitRepeatedIEs(bcs, res.AdditionalIEs)
+	if err := c.RespondTo(sess.peerAddr, req, res); err != nil {
+		return err
+	}
+
+	for _, br := range bearers {
+		if !IsAcceptedCause(br.cause()) {
+			continue
+		}
+		if len(br.FTEIDs) > 0 {
+			if teid, err := br.FTEIDs[0].TEID(); err == nil {
+				br.SetIncomingTEID(teid)
+			}
+		}
+		sess.AddBearer(dedicatedBearerName(br.EBI), br.Bearer)
+	}
+	return nil
+}
+
+// ParseUpdateBearerRequest returns the DedicatedBearers requested in UpdateBearerRequest
+// received, with the EBI, and the Bearer TFT and Bearer QoS if they are present.
+//
+// The Cause of the one that is not found in Session is set to Context not found. Set
+// Cause to reject the others if necessary, and give them to (*Conn).RespondToUpdateBearer.
+func (s *Session) ParseUpdateBearerRequest(req *message.UpdateBearerRequest) ([]*DedicatedBearer, error) {
+	bcs, err := bearerContexts(req.Header, req.BearerContexts)
+	if err != nil {
+		return nil, err
+	}
+
+	var bearers []*DedicatedBearer
+	for _, bc := range bcs {
+		br := &DedicatedBearer{Bearer: NewBearer(0, s.APN(), nil)}
+		for _, child := range bc.ChildIEs {
+			switch child.Type {
+			case ie.EPSBearerID:
+				if br.EBI, err = child.EPSBearerID(); err != nil {
+					return nil, err
+				}
+			case ie.BearerTFT:
+				br.TFT = child
+			case ie.BearerQoS:
+				if br.QoSProfile, err = qosProfile(child); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if _, err := s.LookupBearerByEBI(br.EBI); err != nil {
+			br.Cause = CauseContextNotFound
+		}
+		bearers = append(bearers, br)
+	}
+	return bearers, nil
+}
+
+// RespondToUpdateBearer sends an UpdateBearerResponse to the request received, with the
+// Bearer Contexts built from bearers given by ParseUpdateBearerRequest and the IEs given.
+//
+// The Bearer Contexts contain EBI and Cause, and the Cause of the message is decided in
+// the same way as RespondToCreateBearer. The QoSProfile of the accepted Bearers in Session
+// is updated with the one in DedicatedBearer if it is not nil.
+func (c *Conn) RespondToUpdateBearer(teid uint32, sess *Session, req *message.UpdateBearerRequest, bearers []*DedicatedBearer, ies ...*ie.IE) error {
+	var bcs []*ie.IE
+	for _, br := range bearers {
+		bcs = append(bcs, ie.NewBearerContextWithinUpdateBearerResponse(
+			ie.NewEPSBearerID(br.EBI), ie.NewCause(br.cause(), 0, 0, 0, nil), nil, nil, nil,
+		))
+	}
+
+	res := message.NewUpdateBearerResponse(teid, 0, append([]*ie.IE{responseCause(bearers)}, ies...)...)
+	res.BearerContexts, res.AdditionalIEs = splitRepeatedIEs(bcs, res.AdditionalIEs)
+	if err := c.RespondTo(sess.peerAddr, req, res); err != nil {
+		return err
+	}
+
+	for _, br := range bearers {
+		if !IsAcceptedCause(br.cause()) || br.QoSProfile == nil {
+			continue
+		}
+		if existing, err := sess.LookupBearerByEBI(br.EBI); err == nil {
+			existing.QoSProfile = br.QoSProfile
+		}
+	}
+	return nil
+}
+
+// ParseDeleteBearerRequest returns the Bearers in Session to be deleted by the
+// DeleteBearerRequest received, as DedicatedBearers.
+//
+// If the request has the EBIs, the Cause of the one that is not found in Session is
+// set to Context not found. If the request has only Linked EBI, i.e., the deletion of
+// the PDN connection, the default bearer is returned. Set Cause to reject the others if
+// necessary, and give them to (*Conn).RespondToDeleteBearer.
+func (s *Session) ParseDeleteBearerRequest(req *message.DeleteBearerRequest) ([]*DedicatedBearer, error) {
+	ebiIEs, err := repeatedIEs(req.Header, req.EBI, ie.EPSBearerID, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ebiIEs) == 0 {
+		if req.LinkedEBI == nil {
+			return nil, &RequiredIEMissingError{Type: ie.EPSBearerID}
+		}
+		return []*DedicatedBearer{{Bearer: s.GetDefaultBearer()}}, nil
+	}
+
+	var bearers []*DedicatedBearer
+	for _, i := range ebiIEs {
+		ebi, err := i.EPSBearerID()
+		if err != nil {
+			return nil, err
+		}
+		br, err := s.LookupBearerByEBI(ebi)
+		if err != nil {
+			bearers = append(bearers, &DedicatedBearer{
+				Bearer: NewBearer(ebi, s.APN(), nil), Cause: CauseContextNotFound,
+			})
+			continue
+		}
+		bearers = append(bearers, &DedicatedBearer{Bearer: br})
+	}
+	return bearers, nil
+}
+
+// RespondToDeleteBearer sends a DeleteBearerResponse to the request received, with the
+// Bearer Contexts built from bearers given by ParseDeleteBearerRequest and the IEs given.
+//
+// The Bearer Contexts contain EBI and Cause, and the Cause of the message is decided in
+// the same way as RespondToCreateBearer. The accepted Bearers are removed from Session.
+// If the request has only Linked EBI, the response has Linked EBI instead of the Bearer
+// Contexts and no Bearer is removed, as the Session itself should be removed with
+// RemoveSession.
+func (c *Conn) RespondToDeleteBearer(teid uint32, sess *Session, req *message.DeleteBearerRequest, bearers []*DedicatedBearer, ies ...*ie.IE) error {
+	ebiIEs, err := repeatedIEs(req.Header, req.EBI, ie.EPSBearerID, 1)
+	if err != nil {
+		return err
+	}
+	pdn := len(ebiIEs) == 0
+
+	res := message.NewDeleteBearerResponse(teid, 0, append([]*ie.IE{responseCause(bearers)}, ies...)...)
+	if pdn {
+		res.LinkedEBI = ie.NewEPSBearerID(sess.LinkedEBI())
+	} else {
+		var bcs []*ie.IE
+		for _, br := range bearers {
+			bcs = append(bcs, ie.NewBearerContextWithinDeleteBearerResponse(
+				ie.NewEPSBearerID(br.EBI), ie.NewCause(br.cause(), 0, 0, 0, nil), nil, nil, nil,
+			))
+		}
+		res.BearerContexts, res.AdditionalIEs = splitRepeatedIEs(bcs, res.AdditionalIEs)
+	}
+	if err := c.RespondTo(sess.peerAddr, req, res); err != nil {
+		return err
+	}
+
+	if pdn {
+		return nil
+	}
+	for _, br := range bearers {
+		if IsAcceptedCause(br.cause()) {
+			sess.RemoveBearerByEBI(br.EBI)
+		}
+	}
+	return nil
+}
+
+// cause returns the Cause of the bearer to be set in the response.
+func (b *DedicatedBearer) cause() uint8 {
+	if b.Cause == 0 {
+		return CauseRequestAccepted
+	}
+	return b.Cause
+}
+
+// responseCause returns the Cause IE of the response to the request for bearers.
+func responseCause(bearers []*DedicatedBearer) *ie.IE {
+	var accepted int
+	for _, br := range bearers {
+		if IsAcceptedCause(br.cause()) {
+			accepted++
+		}
+	}
+
+	cause := CauseRequestAccepted
+	switch {
+	case accepted == 0 && len(bearers) > 0:
+		cause = bearers[0].cause()
+	case accepted < len(bearers):
+		cause = CauseRequestAcceptedPartially
+	}
+	return ie.NewCause(cause, 0, 0, 0, nil)
+}
+
+func (s *Session) storeBearerProcedure(seq uint32, proc *bearerProcedure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bearerProcedures == nil {
+		s.bearerProcedures = map[uint32]*bearerProcedure{}
+	}
+	s.bearerProcedures[seq] = proc
+}
+
+// loadBearerProcedure returns the procedure that res is the response to, and
+// removes it from Session.
+func (s *Session) loadBearerProcedure(res message.Message, reqType uint8) (*bearerProcedure, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proc, ok := s.bearerProcedures[res.Sequence()]
+	if !ok || proc.msgType != reqType {
+		return nil, &InvalidSequenceError{Seq: res.Sequence()}
+	}
+	delete(s.bearerProcedures, res.Sequence())
+	return proc, nil
+}
+
+// checkCause returns *CauseNotOKError if the Cause in the response is not the acceptance.
+func checkCause(res message.Message, causeIE *ie.IE) error {
+	if causeIE == nil {
+		return &RequiredIEMissingError{Type: ie.Cause}
+	}
+	cause, err := causeIE.Cause()
+	if err != nil {
+		return err
+	}
+	if !IsAcceptedCause(cause) {
+		return &CauseNotOKError{
+			MsgType: res.MessageTypeName(),
+			Cause:   cause,
+			Msg:     "request rejected",
+		}
+	}
+	return nil
+}
+
+// splitRepeatedIEs returns the first one in ies to be set in the field of the typed
+// message, and additional with the rest appended, as the typed messages can hold only
+// one IE in a field.
+func splitRepeatedIEs(ies, additional []*ie.IE) (*ie.IE, []*ie.IE) {
+	if len(ies) == 0 {
+		return nil, additional
+	}
+	return ies[0], append(additional, ies[1:]...)
+}
+
+// bearerContexts returns all the Bearer Context IEs in the message.
+func bearerContexts(h *message.Header, bc *ie.IE) ([]*ie.IE, error) {
+	return repeatedIEs(h, bc, ie.BearerContext, 0)
+}
+
+// repeatedIEs returns all the IEs of the type and instance in the message.
+//
+// The typed messages keep only one IE in a field, so the payload is parsed again
+// if the message is decoded from bytes.
+func repeatedIEs(h *message.Header, first *ie.IE, typ, instance uint8) ([]*ie.IE, error) {
+	if len(h.Payload) == 0 {
+		if first == nil {
+			return nil, nil
+		}
+		return []*ie.IE{first}, nil
+	}
+
+	ies, err := ie.ParseMultiIEs(h.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*ie.IE
+	for _, i := range ies {
+		if i.Type == typ && i.Instance() == instance {
+			found = append(found, i)
+		}
+	}
+	return found, nil
+}
+
+// parseBearerContextResult returns the EBI and the Cause in Bearer Context.
+// The Cause is regarded as Request accepted if it is not present.
+func parseBearerContextResult(bc *ie.IE) (uint8, uint8, error) {
+	var (
+		ebi   uint8
+		cause = CauseRequestAccepted
+		err   error
+	)
+	for _, child := range bc.ChildIEs {
+		switch child.Type {
+		case ie.EPSBearerID:
+			ebi, err = child.EPSBearerID()
+			if err != nil {
+				return 0, 0, err
+			}
+		case ie.Cause:
+			cause, err = child.Cause()
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if ebi == 0 {
+		return 0, 0, &RequiredIEMissingError{Type: ie.EPSBearerID}
+	}
+	return ebi, cause, nil
+}
+
+func newBearerQoS(qos *QoSProfile) *ie.IE {
+	var pci, pvi uint8
+	if qos.PCI {
+		pci = 1
+	}
+	if qos.PVI {
+		pvi = 1
+	}
+	return ie.NewBearerQoS(pci, qos.PL, pvi, qos.QCI, qos.MBRUL, qos.MBRDL, qos.GBRUL, qos.GBRDL)
+}
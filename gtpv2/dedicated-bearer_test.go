@@ -0,0 +1,388 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// setupBearerPeer returns a Conn that sends messages to a Session whose peer is
+// the returned net.PacketConn.
+func setupBearerPeer(t *testing.T) (*gtpv2.Conn, *gtpv2.Session, net.PacketConn) {
+	t.Helper()
+
+	peer, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	conn := gtpv2.NewConn(&net.UDPAddr{IP: net.IP{127, 0, 0, 1}}, gtpv2.IFTypeS5S8PGWGTPC, 0)
+	if err := conn.Listen(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	sess := gtpv2.NewSession(peer.LocalAddr(), &gtpv2.Subscriber{IMSI: "001011234567891"})
+	sess.GetDefaultBearer().EBI = 5
+	sess.GetDefaultBearer().APN = "internet"
+	if err := sess.Activate(); err != nil {
+		t.Fatal(err)
+	}
+	return conn, sess, peer
+}
+
+// readRequest reads a message sent to peer and returns it.
+func readRequest(t *testing.T, peer net.PacketConn) message.Message {
+	t.Helper()
+
+	buf := make([]byte, 1500)
+	if err := peer.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, _, err := peer.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := message.Parse(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// decode returns the message built from IEs and decoded from bytes, as it is
+// received on Conn. This is to put multiple Bearer Contexts in the message.
+func decode(t *testing.T, msgType uint8, teid, seq uint32, ies ...*ie.IE) message.Message {
+	t.Helper()
+
+	var payload []byte
+	for _, i := range ies {
+		b, err := i.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload = append(payload, b...)
+	}
+
+	b, err := message.NewHeader(message.NewHeaderFlags(2, 0, 1), msgType, teid, seq, payload).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := message.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestDedicatedBearerLifecycle(t *testing.T) {
+	conn, sess, peer := setupBearerPeer(t)
+
+	tft := ie.New(ie.BearerTFT, 0x00, []byte{0x21, 0x10, 0x04, 0x10, 0x0a, 0x00, 0x00, 0x01})
+	bearers := []*gtpv2.DedicatedBearer{
+		gtpv2.NewDedicatedBearer(0, "", &gtpv2.QoSProfile{PL: 2, QCI: 1, GBRUL: 64000, GBRDL: 64000}, tft,
+			ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPU, 0x1001, "127.0.0.1", "")),
+		gtpv2.NewDedicatedBearer(0, "", &gtpv2.QoSProfile{PL: 3, QCI: 2}, tft,
+			ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPU, 0x1002, "127.0.0.1", "")),
+	}
+
+	// Create Bearer: the second one is rejected by the MME.
+	seq, err := conn.CreateBearer(0x11, sess, 5, bearers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := readRequest(t, peer).(*message.CreateBearerRequest)
+	if req.Sequence() != seq || req.LinkedEBI.MustEPSBearerID() != 5 {
+		t.Errorf("wrong request: %v", req)
+	}
+	bcs, err := ie.ParseMultiIEs(req.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var qcis []uint8
+	for _, i := range bcs {
+		if i.Type != ie.BearerContext {
+			continue
+		}
+		qci, err := i.ChildIEs[2].QCILabel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		qcis = append(qcis, qci)
+	}
+	if diff := cmp.Diff(qcis, []uint8{1, 2}); diff != "" {
+		t.Errorf("wrong QCIs in Bearer Contexts: %s", diff)
+	}
+
+	accepted := ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil)
+	res := decode(t, message.MsgTypeCreateBearerResponse, 0x22, seq,
+		ie.NewCause(gtpv2.CauseRequestAcceptedPartially, 0, 0, 0, nil),
+		ie.NewBearerContextWithinCreateBearerResponse(
+			ie.NewEPSBearerID(6), accepted, nil, nil, nil,
+			ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8SGWGTPU, 0x2001, "127.0.0.2", ""),
+			ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPU, 0x1001, "127.0.0.1", "").WithInstance(1),
+		),
+		ie.NewBearerContextWithinCreateBearerResponse(
+			ie.NewEPSBearerID(7), ie.NewCause(gtpv2.CauseNoResourcesAvailable, 0, 0, 0, nil), nil, nil, nil,
+		),
+	).(*message.CreateBearerResponse)
+
+	created, err := sess.ApplyCreateBearerResponse(res)
+	var rejectedErr *gtpv2.BearerRejectedError
+	if !errors.As(err, &rejectedErr) || rejectedErr.Causes[7] != gtpv2.CauseNoResourcesAvailable {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(created) != 1 || sess.BearerCount() != 2 {
+		t.Fatalf("wrong number of bearers: %d, %d", len(created), sess.BearerCount())
+	}
+	br, err := sess.LookupBearerByEBI(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.IncomingTEID() != 0x1001 || br.OutgoingTEID() != 0x2001 || br.APN != "internet" {
+		t.Errorf("wrong bearer: %+v", br)
+	}
+	if got := br.RemoteAddress().String(); got != "127.0.0.2:2152" {
+		t.Errorf("wrong remote address: %s", got)
+	}
+	if _, err := sess.LookupBearerByEBI(7); err == nil {
+		t.Error("rejected bearer should not be added")
+	}
+
+	// the response is processed only once.
+	var seqErr *gtpv2.InvalidSequenceError
+	if _, err := sess.ApplyCreateBearerResponse(res); !errors.As(err, &seqErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Update Bearer.
+	seq, err = conn.UpdateBearer(0x11, sess, []*gtpv2.DedicatedBearer{
+		gtpv2.NewDedicatedBearer(6, "", &gtpv2.QoSProfile{PL: 2, QCI: 1, GBRUL: 128000, GBRDL: 128000}, nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readRequest(t, peer).(*message.UpdateBearerRequest); !ok {
+		t.Fatal("got unexpected message")
+	}
+	updated, err := sess.ApplyUpdateBearerResponse(message.NewUpdateBearerResponse(0x22, seq,
+		accepted, ie.NewBearerContextWithinUpdateBearerResponse(ie.NewEPSBearerID(6), accepted, nil, nil, nil),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 1 || br.GBRDL != 128000 {
+		t.Errorf("QoS not updated: %+v", br.QoSProfile)
+	}
+
+	// Delete Bearer rejected as a whole.
+	seq, err = conn.DeleteDedicatedBearers(0x11, sess, []uint8{6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := readRequest(t, peer).(*message.DeleteBearerRequest); req.EBI.MustEPSBearerID() != 6 {
+		t.Errorf("wrong EBI in request: %v", req.EBI)
+	}
+	var causeErr *gtpv2.CauseNotOKError
+	_, err = sess.ApplyDeleteBearerResponse(message.NewDeleteBearerResponse(0x22, seq,
+		ie.NewCause(gtpv2.CauseContextNotFound, 0, 0, 0, nil),
+	))
+	if !errors.As(err, &causeErr) || causeErr.Cause != gtpv2.CauseContextNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if sess.BearerCount() != 2 {
+		t.Error("bearer should not be removed")
+	}
+
+	// Delete Bearer accepted.
+	seq, err = conn.DeleteDedicatedBearers(0x11, sess, []uint8{6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readRequest(t, peer)
+	deleted, err := sess.ApplyDeleteBearerResponse(message.NewDeleteBearerResponse(0x22, seq,
+		accepted, ie.NewBearerContextWithinDeleteBearerResponse(ie.NewEPSBearerID(6), accepted, nil, nil, nil),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || sess.BearerCount() != 1 {
+		t.Errorf("bearer not removed: %v, %d", deleted, sess.BearerCount())
+	}
+}
+
+func TestDedicatedBearerReceiver(t *testing.T) {
+	conn, sess, peer := setupBearerPeer(t)
+	conn.RegisterSession(0x100, sess)
+
+	handled := make(chan error, 1)
+	conn.AddHandlers(map[uint8]gtpv2.HandlerFunc{
+		message.MsgTypeCreateBearerRequest: func(c *gtpv2.Conn, _ net.Addr, msg message.Message) error {
+			req := msg.(*message.CreateBearerRequest)
+			bearers, err := sess.ParseCreateBearerRequest(req)
+			if err == nil && len(bearers) == 2 {
+				bearers[0].EBI = 6
+				bearers[0].FTEIDs = []*ie.IE{ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8SGWGTPU, 0x4001, "127.0.0.1", "")}
+				bearers[1].Cause = gtpv2.CauseNoResourcesAvailable
+				err = c.RespondToCreateBearer(0x11, sess, req, bearers)
+			}
+			handled <- err
+			return err
+		},
+		message.MsgTypeUpdateBearerRequest: func(c *gtpv2.Conn, _ net.Addr, msg message.Message) error {
+			req := msg.(*message.UpdateBearerRequest)
+			bearers, err := sess.ParseUpdateBearerRequest(req)
+			if err == nil {
+				err = c.RespondToUpdateBearer(0x11, sess, req, bearers)
+			}
+			handled <- err
+			return err
+		},
+		message.MsgTypeDeleteBearerRequest: func(c *gtpv2.Conn, _ net.Addr, msg message.Message) error {
+			req := msg.(*message.DeleteBearerRequest)
+			bearers, err := sess.ParseDeleteBearerRequest(req)
+			if err == nil {
+				err = c.RespondToDeleteBearer(0x11, sess, req, bearers)
+			}
+			handled <- err
+			return err
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = conn.Serve(ctx)
+	}()
+
+	// exchange sends the request from peer and returns the Causes in the response,
+	// the one of the message first and the ones in Bearer Contexts by EBI.
+	exchange := func(msgType uint8, ies ...*ie.IE) (uint8, map[uint8]uint8) {
+		t.Helper()
+
+		var payload []byte
+		for _, i := range ies {
+			b, err := i.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload = append(payload, b...)
+		}
+		b, err := message.NewHeader(message.NewHeaderFlags(2, 0, 1), msgType, 0x100, 1, payload).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := peer.WriteTo(b, conn.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-handled:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+
+		// the Header is parsed as the typed message keeps only one Bearer Context.
+		buf := make([]byte, 1500)
+		if err := peer.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		n, _, err := peer.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := message.ParseHeader(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Type != msgType+1 || h.TEID != 0x11 {
+			t.Errorf("wrong response: type %d, TEID %#x", h.Type, h.TEID)
+		}
+		rs, err := ie.ParseMultiIEs(h.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cause uint8
+		causes := map[uint8]uint8{}
+		for _, i := range rs {
+			switch i.Type {
+			case ie.Cause:
+				cause = i.MustCause()
+			case ie.BearerContext:
+				ebi, err := i.ChildIEs[0].EPSBearerID()
+				if err != nil {
+					t.Fatal(err)
+				}
+				causes[ebi] = i.ChildIEs[1].MustCause()
+			}
+		}
+		return cause, causes
+	}
+
+	tft := ie.New(ie.BearerTFT, 0x00, []byte{0x21, 0x10, 0x04, 0x10, 0x0a, 0x00, 0x00, 0x01})
+	cause, causes := exchange(message.MsgTypeCreateBearerRequest,
+		ie.NewEPSBearerID(5),
+		ie.NewBearerContextWithinCreateBearerRequest(
+			ie.NewEPSBearerID(0), tft, ie.NewBearerQoS(0, 2, 0, 1, 0, 0, 64000, 64000), nil,
+			nil, nil, nil, nil, ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPU, 0x3001, "127.0.0.3", ""),
+		),
+		ie.NewBearerContextWithinCreateBearerRequest(
+			ie.NewEPSBearerID(0), tft, ie.NewBearerQoS(0, 3, 0, 2, 0, 0, 0, 0), nil,
+			nil, nil, nil, nil, ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPU, 0x3002, "127.0.0.3", ""),
+		),
+	)
+	if cause != gtpv2.CauseRequestAcceptedPartially {
+		t.Errorf("wrong Cause: %d", cause)
+	}
+	if diff := cmp.Diff(causes, map[uint8]uint8{6: gtpv2.CauseRequestAccepted, 0: gtpv2.CauseNoResourcesAvailable}); diff != "" {
+		t.Error(diff)
+	}
+	br, err := sess.LookupBearerByEBI(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.IncomingTEID() != 0x4001 || br.OutgoingTEID() != 0x3001 || br.QCI != 1 || br.APN != "internet" {
+		t.Errorf("wrong bearer: %+v", br)
+	}
+	if sess.BearerCount() != 2 || sess.State() != gtpv2.SessionStateActive {
+		t.Errorf("wrong Session: %d bearers, %s", sess.BearerCount(), sess.State())
+	}
+
+	// Update Bearer for an unknown bearer is rejected.
+	_, causes = exchange(message.MsgTypeUpdateBearerRequest,
+		ie.NewBearerContextWithinUpdateBearerRequest(
+			ie.NewEPSBearerID(6), nil, ie.NewBearerQoS(0, 2, 0, 1, 0, 0, 128000, 128000), nil, nil, nil, nil, nil,
+		),
+		ie.NewBearerContextWithinUpdateBearerRequest(
+			ie.NewEPSBearerID(9), nil, ie.NewBearerQoS(0, 2, 0, 1, 0, 0, 0, 0), nil, nil, nil, nil, nil,
+		),
+	)
+	if diff := cmp.Diff(causes, map[uint8]uint8{6: gtpv2.CauseRequestAccepted, 9: gtpv2.CauseContextNotFound}); diff != "" {
+		t.Error(diff)
+	}
+	if br.GBRDL != 128000 {
+		t.Errorf("QoS not updated: %+v", br.QoSProfile)
+	}
+
+	cause, _ = exchange(message.MsgTypeDeleteBearerRequest, ie.NewEPSBearerID(6).WithInstance(1))
+	if cause != gtpv2.CauseRequestAccepted {
+		t.Errorf("wrong Cause: %d", cause)
+	}
+	if _, err := sess.LookupBearerByEBI(6); err == nil || sess.State() != gtpv2.SessionStateActive {
+		t.Errorf("bearer not removed: %d, %s", sess.BearerCount(), sess.State())
+	}
+}
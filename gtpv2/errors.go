@@ -7,7 +7,10 @@ package gtpv2
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
 	"github.com/wmnsk/go-gtp/gtpv2/message"
 )
 
@@ -15,6 +18,9 @@ var (
 	// ErrTEIDNotFound indicates that TEID is not registered for the interface specified.
 	ErrTEIDNotFound = errors.New("no TEID found")
 
+	// ErrSessionNotFound indicates that no Session is found in SessionStore.
+	ErrSessionNotFound = errors.New("no Session found")
+
 	// ErrTimeout indicates that a handler failed to complete its work due to the
 	// absence of message expected to come from another endpoint.
 	ErrTimeout = errors.New("timed out")
@@ -32,6 +38,46 @@ func (e *CauseNotOKError) Error() string {
 	return fmt.Sprintf("got non-OK Cause: %d in %s; %s", e.Cause, e.MsgType, e.Msg)
 }
 
+// CauseName returns the name of the Cause.
+func (e *CauseNotOKError) CauseName() string {
+	return CauseName(e.Cause)
+}
+
+// IsRetryable reports whether the request may be accepted if it is sent again later.
+func (e *CauseNotOKError) IsRetryable() bool {
+	return IsRetryableCause(e.Cause)
+}
+
+// CauseError is an error to reject the incoming request with the Cause.
+//
+// When HandlerFunc returns CauseError, or an error that wraps it, for a request,
+// Conn responds to the request with the corresponding response message containing
+// the Cause IE built from the values in CauseError.
+type CauseError struct {
+	Cause uint8
+
+	// OffendingIE is the IE that caused the rejection. The type and instance of it
+	// are set to the Cause IE. See CauseHasOffendingIE.
+	OffendingIE *ie.IE
+
+	// TEID is the TEID to be set in the response. If it is zero, the C-Plane TEID of
+	// the peer in the Session that the request is sent on is used, or the one in the
+	// Sender F-TEID for Control Plane for the initial request without TEID.
+	TEID uint32
+
+	Msg string
+}
+
+// NewCauseError creates a new CauseError.
+func NewCauseError(cause uint8, offendingIE *ie.IE, msg string) *CauseError {
+	return &CauseError{Cause: cause, OffendingIE: offendingIE, Msg: msg}
+}
+
+// Error returns the name of the Cause with message.
+func (e *CauseError) Error() string {
+	return fmt.Sprintf("rejected with Cause: %s(%d); %s", CauseName(e.Cause), e.Cause, e.Msg)
+}
+
 // RequiredIEMissingError indicates that the IE required is missing.
 type RequiredIEMissingError struct {
 	Type uint8
@@ -124,6 +170,20 @@ func (e *UnknownAPNError) Error() string {
 	return fmt.Sprintf("got unknown APN: %s", e.APN)
 }
 
+// IPAllocationError indicates that no IP address can be allocated to the subscriber.
+//
+// Cause is the value to be set in Cause IE of the response, e.g., Create Session Response.
+type IPAllocationError struct {
+	IMSI, APN string
+	Cause     uint8
+	Msg       string
+}
+
+// Error returns the reason with IMSI and APN.
+func (e *IPAllocationError) Error() string {
+	return fmt.Sprintf("failed to allocate IP to IMSI: %s, APN: %s; %s", e.IMSI, e.APN, e.Msg)
+}
+
 // InvalidSessionError indicates that something went wrong with Session.
 type InvalidSessionError struct {
 	IMSI string
@@ -134,6 +194,35 @@ func (e *InvalidSessionError) Error() string {
 	return fmt.Sprintf("invalid session, IMSI: %s", e.IMSI)
 }
 
+// InvalidStateError indicates that the message is not allowed in the current state
+// of Session.
+//
+// Cause is the value to be set in the response to reject the request. It is zero
+// if the message is a response, which cannot be rejected.
+type InvalidStateError struct {
+	IMSI    string
+	State   SessionState
+	MsgType uint8
+	Cause   uint8
+}
+
+// Error returns message with the state and the message type.
+func (e *InvalidStateError) Error() string {
+	return fmt.Sprintf("message type %d is not allowed in state %s, IMSI: %s", e.MsgType, e.State, e.IMSI)
+}
+
+// As converts InvalidStateError to *CauseError with the Cause, so that the request
+// is rejected automatically when HandlerFunc returns it. It is not converted if the
+// message is a response, which has no Cause to be set.
+func (e *InvalidStateError) As(target interface{}) bool {
+	t, ok := target.(**CauseError)
+	if !ok || e.Cause == 0 {
+		return false
+	}
+	*t = &CauseError{Cause: e.Cause, Msg: e.Error()}
+	return true
+}
+
 // BearerNotFoundError indicates that no Bearer found by lookup methods.
 type BearerNotFoundError struct {
 	IMSI string
@@ -144,6 +233,31 @@ func (e *BearerNotFoundError) Error() string {
 	return fmt.Sprintf("no Bearer found: %s", e.IMSI)
 }
 
+// BearerRejectedError indicates that some of the bearers are rejected in the response
+// while the request itself is accepted.
+//
+// Causes is the Cause in the Bearer Context of each rejected bearer, keyed by EBI.
+type BearerRejectedError struct {
+	IMSI    string
+	MsgType string
+	Causes  map[uint8]uint8
+}
+
+// Error returns message with the EBIs and Causes of the rejected bearers.
+func (e *BearerRejectedError) Error() string {
+	ebis := make([]int, 0, len(e.Causes))
+	for ebi := range e.Causes {
+		ebis = append(ebis, int(ebi))
+	}
+	sort.Ints(ebis)
+
+	rejected := make([]string, len(ebis))
+	for i, ebi := range ebis {
+		rejected[i] = fmt.Sprintf("EBI: %d, Cause: %d", ebi, e.Causes[uint8(ebi)])
+	}
+	return fmt.Sprintf("bearers rejected in %s, IMSI: %s; %s", e.MsgType, e.IMSI, strings.Join(rejected, ", "))
+}
+
 // HandlerNotFoundError indicates that the handler func is not registered in *Conn
 // for the incoming GTPv2 message. In usual cases this error should not be taken
 // as fatal, as the other endpoint can make your program stop working just by
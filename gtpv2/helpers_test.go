@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/teid"
 )
 
 var testConn *gtpv2.Conn
@@ -150,3 +151,144 @@ func TestRemoveSessionByIMSI(t *testing.T) {
 	s.AddTEID(gtpv2.IFTypeS11MMEGTPC, uint32(0))
 	testConn.RegisterSession(0, s)
 }
+
+func TestGetSessionByBearerTEID(t *testing.T) {
+	conn := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS11S4SGWGTPC, 0)
+	sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+	br := sess.GetDefaultBearer()
+	br.EBI = 5
+	br.SetIncomingTEID(0x11111111)
+	br.SetOutgoingTEID(0x22222222)
+	br.SetRemoteAddress(&net.UDPAddr{IP: net.IP{127, 0, 0, 2}, Port: 2152})
+	conn.RegisterSession(1, sess)
+
+	got, ebi, err := conn.GetSessionByBearerTEID(0x22222222, &net.UDPAddr{IP: net.IP{127, 0, 0, 2}, Port: 2152})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != sess || ebi != 5 {
+		t.Errorf("got wrong session or EBI: %s, %d", got.IMSI, ebi)
+	}
+
+	if _, _, err := conn.GetSessionByBearerTEID(0x22222222, &net.UDPAddr{IP: net.IP{127, 0, 0, 3}, Port: 2152}); err == nil {
+		t.Error("should fail with unknown peer")
+	}
+	if _, _, err := conn.GetSessionByBearerTEID(0x33333333, nil); err == nil {
+		t.Error("should fail with unknown TEID")
+	}
+
+	// the incoming TEID of another session is not matched.
+	other := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567892"})
+	obr := other.GetDefaultBearer()
+	obr.EBI = 6
+	obr.SetIncomingTEID(0x44444444)
+	obr.SetOutgoingTEID(0x55555555)
+	conn.RegisterSession(2, other)
+	if _, _, err := conn.GetSessionByBearerTEID(0x44444444, nil); err == nil {
+		t.Error("should fail with incoming TEID")
+	}
+}
+
+func TestMultiplePDNConnections(t *testing.T) {
+	conn := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS11S4SGWGTPC, 0)
+
+	newPDN := func(ebi uint8, apn string) *gtpv2.Session {
+		sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+		_ = sess.Activate()
+		br := sess.GetDefaultBearer()
+		br.EBI = ebi
+		br.APN = apn
+		return sess
+	}
+	internet, ims := newPDN(5, "internet"), newPDN(6, "ims")
+	conn.RegisterSession(1, internet)
+	conn.RegisterSession(2, ims)
+	internet.AddBearer("dedicated", &gtpv2.Bearer{EBI: 7, QoSProfile: &gtpv2.QoSProfile{}})
+
+	if got := conn.SessionCount(); got != 2 {
+		t.Errorf("SessionCount is invalid. want: 2, got: %d", got)
+	}
+	if got := conn.BearerCount(); got != 3 {
+		t.Errorf("BearerCount is invalid. want: 3, got: %d", got)
+	}
+
+	all, err := conn.GetSessionsByIMSI("001011234567891")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0] != internet || all[1] != ims {
+		t.Errorf("got wrong sessions: %v", all)
+	}
+
+	if got, err := conn.GetSessionByIMSI("001011234567891"); err != nil || got != internet {
+		t.Errorf("got wrong session: %v, %v", got, err)
+	}
+	if got, err := conn.GetSessionByIMSIAndEBI("001011234567891", 6); err != nil || got != ims {
+		t.Errorf("got wrong session by LBI: %v, %v", got, err)
+	}
+	if got, err := conn.GetSessionByIMSIAndEBI("001011234567891", 7); err != nil || got != internet {
+		t.Errorf("got wrong session by EBI of dedicated bearer: %v, %v", got, err)
+	}
+	if got, err := conn.GetSessionByIMSIAndAPN("001011234567891", "ims"); err != nil || got != ims {
+		t.Errorf("got wrong session by APN: %v, %v", got, err)
+	}
+	if _, err := conn.GetSessionByIMSIAndAPN("001011234567891", "unknown"); err == nil {
+		t.Error("should fail with unknown APN")
+	}
+
+	// registering the same PDN connection replaces the existing one.
+	newIMS := newPDN(6, "ims")
+	conn.RegisterSession(3, newIMS)
+	if got, _ := conn.GetSessionByIMSIAndAPN("001011234567891", "ims"); got != newIMS {
+		t.Error("session is not replaced")
+	}
+	if got := conn.SessionCount(); got != 2 {
+		t.Errorf("SessionCount is invalid after replacing. want: 2, got: %d", got)
+	}
+
+	conn.RemoveSession(internet)
+	if _, err := conn.GetSessionByTEID(1, dummyAddr); err == nil {
+		t.Error("TEID of removed session should not be found")
+	}
+	if got, err := conn.GetSessionByIMSI("001011234567891"); err != nil || got != newIMS {
+		t.Errorf("the other PDN connection should be kept: %v, %v", got, err)
+	}
+
+	conn.RemoveSessionByIMSI("001011234567891")
+	if _, err := conn.GetSessionsByIMSI("001011234567891"); err == nil {
+		t.Error("all sessions should be removed")
+	}
+}
+
+func TestNewSenderFTEIDWithAllocator(t *testing.T) {
+	conn := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS11S4SGWGTPC, 0)
+	a, err := teid.NewSequentialAllocator(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetTEIDAllocator(a)
+
+	// TEID=1 is taken by the session registered without the allocator.
+	sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+	conn.RegisterSession(1, sess)
+
+	fteid := conn.NewSenderFTEID("127.0.0.1", "")
+	if fteid == nil {
+		t.Fatal("failed to create F-TEID")
+	}
+	if got, err := fteid.TEID(); err != nil || got != 2 {
+		t.Errorf("wrong TEID: %d, %v", got, err)
+	}
+	if fteid := conn.NewSenderFTEID("127.0.0.1", ""); fteid != nil {
+		t.Error("should fail when TEID is exhausted")
+	}
+
+	conn.RemoveSession(sess)
+	fteid = conn.NewSenderFTEID("127.0.0.1", "")
+	if fteid == nil {
+		t.Fatal("TEID of removed session should be released")
+	}
+	if got, err := fteid.TEID(); err != nil || got != 1 {
+		t.Errorf("wrong TEID: %d, %v", got, err)
+	}
+}
@@ -16,14 +16,27 @@ func NewCause(cause uint8, pce, bce, cs uint8, offendingIE *IE) *IE {
 	i.Payload[1] = ((pce << 2) & 0x04) | ((bce << 1) & 0x02) | cs&0x01
 
 	if offendingIE != nil {
-		// trailing zeroes are length, instance and spare fields which should be
-		// filled with zeroes in this case (cf. §8.4, TS29.274)
-		i.Payload = append(i.Payload, []byte{offendingIE.Type, 0x00, 0x00, 0x00}...)
+		// the length of the offending IE is filled with zeroes, and only the type and
+		// instance are set in this case (cf. §8.4, TS29.274)
+		i.Payload = append(i.Payload, []byte{offendingIE.Type, 0x00, 0x00, offendingIE.Instance()}...)
 		i.SetLength()
 	}
 	return i
 }
 
+// OffendingIE returns the type and instance of the offending IE in Cause as an IE
+// without payload, if the type of IE matches.
+func (i *IE) OffendingIE() (*IE, error) {
+	if i.Type != Cause {
+		return nil, &InvalidTypeError{Type: i.Type}
+	}
+
+	if len(i.Payload) < 6 {
+		return nil, ErrIENotFound
+	}
+	return New(i.Payload[2], i.Payload[5]&0x0f, nil), nil
+}
+
 // Cause returns Cause in uint8 if the type of IE matches.
 func (i *IE) Cause() (uint8, error) {
 	switch i.Type {
@@ -61,7 +74,7 @@ func (i *IE) MustCause() uint8 {
 func (i *IE) CauseFlags() (uint8, error) {
 	switch i.Type {
 	case Cause:
-		if len(i.Payload) < 1 {
+		if len(i.Payload) < 2 {
 			return 0, io.ErrUnexpectedEOF
 		}
 
@@ -74,7 +87,7 @@ func (i *IE) CauseFlags() (uint8, error) {
 
 		for _, child := range ies {
 			if child.Type == Cause {
-				return child.Cause()
+				return child.CauseFlags()
 			}
 		}
 		return 0, ErrIENotFound
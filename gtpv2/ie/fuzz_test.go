@@ -0,0 +1,96 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package ie_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+)
+
+// The seed corpus of FuzzParse is in testdata/fuzz, which is generated from the
+// test vectors in ie_test.go.
+
+func FuzzParse(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		i, err := ie.Parse(b)
+		if err != nil {
+			return
+		}
+		_ = i.Name()
+		_, _ = i.Value()
+
+		// IE should be serialized as it is.
+		got, err := i.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", i, err)
+		}
+		if want := b[:4+int(i.Length)]; !bytes.Equal(got, want) {
+			t.Errorf("not identical:\n got: %x\nwant: %x", got, want)
+		}
+	})
+}
+
+func FuzzParseMultiIEs(f *testing.F) {
+	f.Add([]byte{
+		0x01, 0x00, 0x08, 0x00, 0x21, 0x43, 0x15, 0x32, 0x54, 0x76, 0x98, 0xf0,
+		0x5d, 0x00, 0x0a, 0x00, 0x49, 0x00, 0x01, 0x00, 0x05, 0x03, 0x00, 0x01, 0x00, 0x01,
+	})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		ies, err := ie.ParseMultiIEs(b)
+		if err != nil {
+			return
+		}
+
+		var got []byte
+		for _, i := range ies {
+			s, err := i.Marshal()
+			if err != nil {
+				t.Fatalf("failed to marshal %v: %s", i, err)
+			}
+			got = append(got, s...)
+		}
+		if !bytes.Equal(got, b) {
+			t.Errorf("not identical:\n got: %x\nwant: %x", got, b)
+		}
+	})
+}
+
+func FuzzParseFields(f *testing.F) {
+	parsers := []func([]byte) (interface{}, error){
+		func(b []byte) (interface{}, error) { return ie.ParseAggregateMaximumBitRateFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseBearerQoSFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseFullyQualifiedTEIDFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseFlowQoSFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseFullyQualifiedCSIDFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseGUTIFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParsePDNAddressAllocationFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParsePagingAndServiceInformationFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParsePCOPPP(b) },
+		func(b []byte) (interface{}, error) { return ie.ParsePAPFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseCHAPFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseIPCPOption(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseProtocolConfigurationOptionsFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParsePCOContainer(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseRANNASCauseFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseS103PDNDataForwardingInfoFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseS1UDataForwardingFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseThrottlingFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseTraceReferenceFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseUserCSGInformationFields(b) },
+		func(b []byte) (interface{}, error) { return ie.ParseUserLocationInformationFields(b) },
+	}
+
+	f.Add(uint8(2), []byte{0x8a, 0xff, 0xff, 0xff, 0xff, 0x01, 0x01, 0x01, 0x01})
+	f.Add(uint8(20), []byte{0x18, 0x21, 0xf3, 0x54, 0x00, 0x01, 0x21, 0xf3, 0x54, 0x00, 0x00, 0x01, 0x01})
+	f.Fuzz(func(t *testing.T, n uint8, b []byte) {
+		// the parsers should return error instead of panicking.
+		_, _ = parsers[int(n)%len(parsers)](b)
+	})
+}
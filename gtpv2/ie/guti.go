@@ -102,7 +102,7 @@ func (f *GUTIFields) UnmarshalBinary(b []byte) error {
 	}
 
 	var err error
-	f.MCC, f.MNC, err = utils.DecodePLMN(b[1:3])
+	f.MCC, f.MNC, err = utils.DecodePLMN(b[0:3])
 	if err != nil {
 		return err
 	}
@@ -364,7 +364,7 @@ func Parse(b []byte) (*IE, error) {
 // UnmarshalBinary sets the values retrieved from byte sequence in GTPv2 IE.
 func (i *IE) UnmarshalBinary(b []byte) error {
 	l := len(b)
-	if l < 5 {
+	if l < 4 {
 		return io.ErrUnexpectedEOF
 	}
 
@@ -408,6 +408,7 @@ func (i *IE) SetLength() {
 			l += ie.MarshalLen()
 		}
 		i.Length = uint16(l)
+		return
 	}
 	i.Length = uint16(len(i.Payload))
 }
@@ -435,8 +436,12 @@ var grouped = []uint8{
 
 // IsGrouped reports whether an IE is grouped type or not.
 func (i *IE) IsGrouped() bool {
+	return isGroupedType(i.Type)
+}
+
+func isGroupedType(typ uint8) bool {
 	for _, itype := range grouped {
-		if i.Type == itype {
+		if typ == itype {
 			return true
 		}
 	}
@@ -31,6 +31,10 @@ func TestIEs(t *testing.T) {
 			"CauseIMSIIMEINotKnown",
 			ie.NewCause(gtpv2.CauseIMSIIMEINotKnown, 1, 0, 0, ie.NewIMSI("")),
 			[]byte{0x02, 0x00, 0x06, 0x00, 0x60, 0x04, 0x01, 0x00, 0x00, 0x00},
+		}, {
+			"CauseMandatoryIEMissing",
+			ie.NewCause(gtpv2.CauseMandatoryIEMissing, 0, 0, 0, ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPC, 0, "", "").WithInstance(1)),
+			[]byte{0x02, 0x00, 0x06, 0x00, 0x46, 0x00, 0x57, 0x00, 0x00, 0x01},
 		}, {
 			"Recovery",
 			ie.NewRecovery(0xff),
@@ -0,0 +1,191 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ie
+
+import "fmt"
+
+var ieNames = map[uint8]string{
+	IMSI:                                     "IMSI",
+	Cause:                                    "Cause",
+	Recovery:                                 "Recovery",
+	STNSR:                                    "STNSR",
+	AccessPointName:                          "AccessPointName",
+	AggregateMaximumBitRate:                  "AggregateMaximumBitRate",
+	EPSBearerID:                              "EPSBearerID",
+	IPAddress:                                "IPAddress",
+	MobileEquipmentIdentity:                  "MobileEquipmentIdentity",
+	MSISDN:                                   "MSISDN",
+	Indication:                               "Indication",
+	ProtocolConfigurationOptions:             "ProtocolConfigurationOptions",
+	PDNAddressAllocation:                     "PDNAddressAllocation",
+	BearerQoS:                                "BearerQoS",
+	FlowQoS:                                  "FlowQoS",
+	RATType:                                  "RATType",
+	ServingNetwork:                           "ServingNetwork",
+	BearerTFT:                                "BearerTFT",
+	TrafficAggregateDescription:              "TrafficAggregateDescription",
+	UserLocationInformation:                  "UserLocationInformation",
+	FullyQualifiedTEID:                       "FullyQualifiedTEID",
+	TMSI:                                     "TMSI",
+	GlobalCNID:                               "GlobalCNID",
+	S103PDNDataForwardingInfo:                "S103PDNDataForwardingInfo",
+	S1UDataForwarding:                        "S1UDataForwarding",
+	DelayValue:                               "DelayValue",
+	BearerContext:                            "BearerContext",
+	ChargingID:                               "ChargingID",
+	ChargingCharacteristics:                  "ChargingCharacteristics",
+	TraceInformation:                         "TraceInformation",
+	BearerFlags:                              "BearerFlags",
+	PDNType:                                  "PDNType",
+	ProcedureTransactionID:                   "ProcedureTransactionID",
+	MMContextGSMKeyAndTriplets:               "MMContextGSMKeyAndTriplets",
+	MMContextUMTSKeyUsedCipherAndQuintuplets: "MMContextUMTSKeyUsedCipherAndQuintuplets",
+	MMContextGSMKeyUsedCipherAndQuintuplets:  "MMContextGSMKeyUsedCipherAndQuintuplets",
+	MMContextUMTSKeyAndQuintuplets:           "MMContextUMTSKeyAndQuintuplets",
+	MMContextEPSSecurityContextQuadrupletsAndQuintuplets: "MMContextEPSSecurityContextQuadrupletsAndQuintuplets",
+	MMContextUMTSKeyQuadrupletsAndQuintuplets:            "MMContextUMTSKeyQuadrupletsAndQuintuplets",
+	PDNConnection:                          "PDNConnection",
+	PDUNumbers:                             "PDUNumbers",
+	PacketTMSI:                             "PacketTMSI",
+	PTMSISignature:                         "PTMSISignature",
+	HopCounter:                             "HopCounter",
+	UETimeZone:                             "UETimeZone",
+	TraceReference:                         "TraceReference",
+	CompleteRequestMessage:                 "CompleteRequestMessage",
+	GUTI:                                   "GUTI",
+	FContainer:                             "FContainer",
+	FCause:                                 "FCause",
+	PLMNID:                                 "PLMNID",
+	TargetIdentification:                   "TargetIdentification",
+	PacketFlowID:                           "PacketFlowID",
+	RABContext:                             "RABContext",
+	SourceRNCPDCPContextInfo:               "SourceRNCPDCPContextInfo",
+	PortNumber:                             "PortNumber",
+	APNRestriction:                         "APNRestriction",
+	SelectionMode:                          "SelectionMode",
+	SourceIdentification:                   "SourceIdentification",
+	Reserved:                               "Reserved",
+	ChangeReportingAction:                  "ChangeReportingAction",
+	FullyQualifiedCSID:                     "FullyQualifiedCSID",
+	ChannelNeeded:                          "ChannelNeeded",
+	EMLPPPriority:                          "EMLPPPriority",
+	NodeType:                               "NodeType",
+	FullyQualifiedDomainName:               "FullyQualifiedDomainName",
+	TI:                                     "TI",
+	MBMSSessionDuration:                    "MBMSSessionDuration",
+	MBMSServiceArea:                        "MBMSServiceArea",
+	MBMSSessionIdentifier:                  "MBMSSessionIdentifier",
+	MBMSFlowIdentifier:                     "MBMSFlowIdentifier",
+	MBMSIPMulticastDistribution:            "MBMSIPMulticastDistribution",
+	MBMSDistributionAcknowledge:            "MBMSDistributionAcknowledge",
+	RFSPIndex:                              "RFSPIndex",
+	UserCSGInformation:                     "UserCSGInformation",
+	CSGInformationReportingAction:          "CSGInformationReportingAction",
+	CSGID:                                  "CSGID",
+	CSGMembershipIndication:                "CSGMembershipIndication",
+	ServiceIndicator:                       "ServiceIndicator",
+	DetachType:                             "DetachType",
+	LocalDistinguishedName:                 "LocalDistinguishedName",
+	NodeFeatures:                           "NodeFeatures",
+	MBMSTimeToDataTransfer:                 "MBMSTimeToDataTransfer",
+	Throttling:                             "Throttling",
+	AllocationRetensionPriority:            "AllocationRetensionPriority",
+	EPCTimer:                               "EPCTimer",
+	SignallingPriorityIndication:           "SignallingPriorityIndication",
+	TMGI:                                   "TMGI",
+	AdditionalMMContextForSRVCC:            "AdditionalMMContextForSRVCC",
+	AdditionalFlagsForSRVCC:                "AdditionalFlagsForSRVCC",
+	MDTConfiguration:                       "MDTConfiguration",
+	AdditionalProtocolConfigurationOptions: "AdditionalProtocolConfigurationOptions",
+	AbsoluteTimeofMBMSDataTransfer:         "AbsoluteTimeofMBMSDataTransfer",
+	HeNBInformationReporting:               "HeNBInformationReporting",
+	IPv4ConfigurationParameters:            "IPv4ConfigurationParameters",
+	ChangeToReportFlags:                    "ChangeToReportFlags",
+	ActionIndication:                       "ActionIndication",
+	TWANIdentifier:                         "TWANIdentifier",
+	ULITimestamp:                           "ULITimestamp",
+	MBMSFlags:                              "MBMSFlags",
+	RANNASCause:                            "RANNASCause",
+	CNOperatorSelectionEntity:              "CNOperatorSelectionEntity",
+	TrustedWLANModeIndication:              "TrustedWLANModeIndication",
+	NodeNumber:                             "NodeNumber",
+	NodeIdentifier:                         "NodeIdentifier",
+	PresenceReportingAreaAction:            "PresenceReportingAreaAction",
+	PresenceReportingAreaInformation:       "PresenceReportingAreaInformation",
+	TWANIdentifierTimestamp:                "TWANIdentifierTimestamp",
+	OverloadControlInformation:             "OverloadControlInformation",
+	LoadControlInformation:                 "LoadControlInformation",
+	Metric:                                 "Metric",
+	SequenceNumber:                         "SequenceNumber",
+	APNAndRelativeCapacity:                 "APNAndRelativeCapacity",
+	WLANOffloadabilityIndication:           "WLANOffloadabilityIndication",
+	PagingAndServiceInformation:            "PagingAndServiceInformation",
+	IntegerNumber:                          "IntegerNumber",
+	MillisecondTimeStamp:                   "MillisecondTimeStamp",
+	MonitoringEventInformation:             "MonitoringEventInformation",
+	ECGIList:                               "ECGIList",
+	RemoteUEContext:                        "RemoteUEContext",
+	RemoteUserID:                           "RemoteUserID",
+	RemoteUEIPinformation:                  "RemoteUEIPinformation",
+	CIoTOptimizationsSupportIndication:     "CIoTOptimizationsSupportIndication",
+	SCEFPDNConnection:                      "SCEFPDNConnection",
+	HeaderCompressionConfiguration:         "HeaderCompressionConfiguration",
+	ExtendedProtocolConfigurationOptions:   "ExtendedProtocolConfigurationOptions",
+	ServingPLMNRateControl:                 "ServingPLMNRateControl",
+	Counter:                                "Counter",
+	MappedUEUsageType:                      "MappedUEUsageType",
+	SecondaryRATUsageDataReport:            "SecondaryRATUsageDataReport",
+	UPFunctionSelectionIndicationFlags:     "UPFunctionSelectionIndicationFlags",
+	MaximumPacketLossRate:                  "MaximumPacketLossRate",
+	APNRateControlStatus:                   "APNRateControlStatus",
+	ExtendedTraceInformation:               "ExtendedTraceInformation",
+	MonitoringEventExtensionInformation:    "MonitoringEventExtensionInformation",
+	AdditionalRRMPolicyIndex:               "AdditionalRRMPolicyIndex",
+	V2XContext:                             "V2XContext",
+	PC5QoSParameters:                       "PC5QoSParameters",
+	ServicesAuthorized:                     "ServicesAuthorized",
+	BitRate:                                "BitRate",
+	PC5QoSFlow:                             "PC5QoSFlow",
+	SpecialIETypeForIETypeExtension:        "SpecialIETypeForIETypeExtension",
+	PrivateExtension:                       "PrivateExtension",
+}
+
+// Name returns the name of the type of IE, e.g., "IMSI".
+func (i *IE) Name() string {
+	if n, ok := ieNames[i.Type]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", i.Type)
+}
+
+// abbreviations are the names of IE types commonly used in the specifications.
+var abbreviations = map[string]uint8{
+	"EBI":    EPSBearerID,
+	"APN":    AccessPointName,
+	"AMBR":   AggregateMaximumBitRate,
+	"MEI":    MobileEquipmentIdentity,
+	"PCO":    ProtocolConfigurationOptions,
+	"PAA":    PDNAddressAllocation,
+	"TFT":    BearerTFT,
+	"TAD":    TrafficAggregateDescription,
+	"ULI":    UserLocationInformation,
+	"F-TEID": FullyQualifiedTEID,
+	"F-CSID": FullyQualifiedCSID,
+	"FQDN":   FullyQualifiedDomainName,
+}
+
+// TypeByName returns the type of IE by the name returned by Name, e.g., "FullyQualifiedTEID",
+// or the abbreviation commonly used in the specifications, e.g., "F-TEID".
+func TypeByName(name string) (uint8, bool) {
+	if t, ok := abbreviations[name]; ok {
+		return t, true
+	}
+	for t, n := range ieNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
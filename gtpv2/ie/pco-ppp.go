@@ -118,6 +118,9 @@ func (p *PCOPPP) UnmarshalBinary(b []byte) error {
 	p.Identifier = b[1]
 	p.Length = binary.BigEndian.Uint16(b[2:4])
 
+	if p.Length < 4 {
+		return ErrInvalidLength
+	}
 	if l < int(p.Length) {
 		return io.ErrUnexpectedEOF
 	}
@@ -170,7 +173,7 @@ func (p *PAPFields) MarshalTo(b []byte) error {
 	b[0] = p.PeerIDLength
 	offset := 1
 
-	if l < offset+int(p.PeerIDLength) {
+	if l < offset+int(p.PeerIDLength)+1 {
 		return io.ErrUnexpectedEOF
 	}
 	copy(b[offset:offset+int(p.PeerIDLength)], p.PeerID)
@@ -207,7 +210,7 @@ func (p *PAPFields) UnmarshalBinary(b []byte) error {
 	p.PeerIDLength = b[0]
 	offset := 1
 
-	if l < offset+int(p.PeerIDLength) {
+	if l < offset+int(p.PeerIDLength)+1 {
 		return io.ErrUnexpectedEOF
 	}
 	p.PeerID = string(b[offset : offset+int(p.PeerIDLength)])
@@ -434,6 +437,9 @@ func (o *IPCPOption) UnmarshalBinary(b []byte) error {
 	o.Type = b[0]
 	o.Length = b[1]
 
+	if o.Length < 2 {
+		return ErrInvalidLength
+	}
 	if l < int(o.Length) {
 		return io.ErrUnexpectedEOF
 	}
@@ -149,7 +149,7 @@ func (f *S103PDNDataForwardingInfoFields) UnmarshalBinary(b []byte) error {
 	f.HSGWAddressForForwarding = net.IP(b[offset : offset+int(f.HSGWAddressForForwardingLength)])
 	offset += int(f.HSGWAddressForForwardingLength)
 
-	if l < offset+4 {
+	if l < offset+5 {
 		return io.ErrUnexpectedEOF
 	}
 	f.GREKey = binary.BigEndian.Uint32(b[offset : offset+4])
@@ -161,6 +161,7 @@ func (f *S103PDNDataForwardingInfoFields) UnmarshalBinary(b []byte) error {
 	if l < offset+int(f.EPSBearerIDNumber) {
 		return io.ErrUnexpectedEOF
 	}
+	f.EPSBearerIDs = make([]uint8, f.EPSBearerIDNumber)
 	for n := 0; n < int(f.EPSBearerIDNumber); n++ {
 		f.EPSBearerIDs[n] = b[offset]
 		offset++
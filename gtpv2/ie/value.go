@@ -0,0 +1,249 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ie
+
+import (
+	"time"
+)
+
+// Value returns the value of IE decoded with the accessor of its type, which is
+// meant to be used for rendering IE in human readable form.
+//
+// The type of returned value varies by the type of IE; e.g., string for IMSI,
+// *FullyQualifiedTEIDFields for F-TEID, and the names of the flags set for Indication.
+// It returns nil without error for the grouped IEs and the IEs that have no accessor.
+func (i *IE) Value() (interface{}, error) {
+	switch i.Type {
+	case IMSI:
+		return i.IMSI()
+	case Cause:
+		return causeValue(i)
+	case Recovery:
+		return i.Recovery()
+	case AccessPointName:
+		return i.AccessPointName()
+	case AggregateMaximumBitRate:
+		return i.AggregateMaximumBitRate()
+	case EPSBearerID:
+		return i.EPSBearerID()
+	case IPAddress:
+		return i.IPAddress()
+	case MobileEquipmentIdentity:
+		return i.MobileEquipmentIdentity()
+	case MSISDN:
+		return i.MSISDN()
+	case Indication:
+		return indicationValue(i)
+	case ProtocolConfigurationOptions:
+		return i.ProtocolConfigurationOptions()
+	case PDNAddressAllocation:
+		return ParsePDNAddressAllocationFields(i.Payload)
+	case BearerQoS:
+		return i.BearerQoS()
+	case FlowQoS:
+		return i.FlowQoS()
+	case RATType:
+		return i.RATType()
+	case ServingNetwork:
+		return i.ServingNetwork()
+	case UserLocationInformation:
+		return uliValue(i)
+	case FullyQualifiedTEID:
+		return i.FullyQualifiedTEID()
+	case GUTI:
+		return i.GUTI()
+	case DelayValue:
+		return durationValue(i.DelayValue())
+	case ChargingID:
+		return i.ChargingID()
+	case ChargingCharacteristics:
+		return i.ChargingCharacteristics()
+	case BearerFlags:
+		return i.BearerFlags()
+	case PDNType:
+		return i.PDNType()
+	case ProcedureTransactionID:
+		return i.ProcedureTransactionID()
+	case PacketTMSI:
+		return i.PacketTMSI()
+	case PTMSISignature:
+		return i.PTMSISignature()
+	case HopCounter:
+		return i.HopCounter()
+	case UETimeZone:
+		return timeZoneValue(i)
+	case TraceReference:
+		return ParseTraceReferenceFields(i.Payload)
+	case FullyQualifiedDomainName:
+		return i.FullyQualifiedDomainName()
+	case PortNumber:
+		return i.PortNumber()
+	case APNRestriction:
+		return i.APNRestriction()
+	case SelectionMode:
+		return i.SelectionMode()
+	case FullyQualifiedCSID:
+		return i.FullyQualifiedCSID()
+	case NodeType:
+		return i.NodeType()
+	case EPCTimer:
+		return durationValue(i.EPCTimer())
+	case AllocationRetensionPriority:
+		return i.AllocationRetensionPriority()
+	case TMSI:
+		return i.TMSI()
+	case CNOperatorSelectionEntity, NodeFeatures:
+		return i.NodeFeatures()
+	case UserCSGInformation:
+		return i.UserCSGInformation()
+	case CSGID:
+		return i.CSGID()
+	case CSGMembershipIndication:
+		return i.CMI()
+	case ServiceIndicator:
+		return i.ServiceIndicator()
+	case DetachType:
+		return i.DetachType()
+	case LocalDistinguishedName:
+		return i.LocalDistinguishedName()
+	case MBMSFlags:
+		return i.MBMSFlags()
+	case RANNASCause:
+		return i.RANNASCause()
+	case PagingAndServiceInformation:
+		return i.PagingAndServiceInformation()
+	case IntegerNumber:
+		return i.IntegerNumber()
+	case Throttling:
+		return i.Throttling()
+	case PLMNID:
+		return i.PLMNID()
+	case RFSPIndex:
+		return i.RFSPIndex()
+	case PrivateExtension:
+		return i.PrivateExtension()
+	default:
+		return nil, nil
+	}
+}
+
+func causeValue(i *IE) (interface{}, error) {
+	cause, err := i.Cause()
+	if err != nil {
+		return nil, err
+	}
+	flags, err := i.CauseFlags()
+	if err != nil {
+		return nil, err
+	}
+
+	v := map[string]interface{}{
+		"Cause": cause,
+		"PCE":   flags&0x04 != 0,
+		"BCE":   flags&0x02 != 0,
+		"CS":    flags&0x01 != 0,
+	}
+	if offending, err := i.OffendingIE(); err == nil {
+		v["OffendingIE"] = map[string]interface{}{
+			"Type":     offending.Type,
+			"Name":     offending.Name(),
+			"Instance": offending.Instance(),
+		}
+	}
+	return v, nil
+}
+
+var indicationFlags = []struct {
+	name string
+	has  func(*IE) bool
+}{
+	{"SGWCI", (*IE).HasSGWCI}, {"ISRAI", (*IE).HasISRAI}, {"ISRSI", (*IE).HasISRSI}, {"OI", (*IE).HasOI},
+	{"DFI", (*IE).HasDFI}, {"HI", (*IE).HasHI}, {"DTF", (*IE).HasDTF}, {"DAF", (*IE).HasDAF},
+	{"MSV", (*IE).HasMSV}, {"SI", (*IE).HasSI}, {"PT", (*IE).HasPT}, {"PS", (*IE).HasPS},
+	{"CRSI", (*IE).HasCRSI}, {"CFSI", (*IE).HasCFSI}, {"UIMSI", (*IE).HasUIMSI}, {"SQCI", (*IE).HasSQCI},
+	{"CCRSI", (*IE).HasCCRSI}, {"ISRAU", (*IE).HasISRAU}, {"MBMDT", (*IE).HasMBMDT}, {"S4AF", (*IE).HasS4AF},
+	{"S6AF", (*IE).HasS6AF}, {"SRNI", (*IE).HasSRNI}, {"PBIC", (*IE).HasPBIC}, {"RETLOC", (*IE).HasRETLOC},
+	{"CPSR", (*IE).HasCPSR}, {"CLII", (*IE).HasCLII}, {"CSFBI", (*IE).HasCSFBI}, {"PPSI", (*IE).HasPPSI},
+	{"PPON", (*IE).HasPPON}, {"PPOF", (*IE).HasPPOF}, {"ARRL", (*IE).HasARRL}, {"CPRAI", (*IE).HasCPRAI},
+	{"AOPI", (*IE).HasAOPI}, {"AOSI", (*IE).HasAOSI}, {"PCRI", (*IE).HasPCRI}, {"PSCI", (*IE).HasPSCI},
+	{"BDWI", (*IE).HasBDWI}, {"DTCI", (*IE).HasDTCI}, {"UACI", (*IE).HasUACI}, {"NSI", (*IE).HasNSI},
+	{"WPMSI", (*IE).HasWPMSI}, {"UNACCSI", (*IE).HasUNACCSI}, {"PNSI", (*IE).HasPNSI}, {"S11TF", (*IE).HasS11TF},
+	{"PMTMSI", (*IE).HasPMTMSI}, {"CPOPCI", (*IE).HasCPOPCI}, {"EPCOSI", (*IE).HasEPCOSI}, {"ROAAI", (*IE).HasROAAI},
+	{"TSPCMI", (*IE).HasTSPCMI}, {"ENBCRSI", (*IE).HasENBCRSI}, {"LTEMPI", (*IE).HasLTEMPI}, {"LTEMUI", (*IE).HasLTEMUI},
+	{"EEVRSI", (*IE).HasEEVRSI}, {"5GSIWK", (*IE).Has5GSIWK}, {"REPREFI", (*IE).HasREPREFI}, {"5GSNN26", (*IE).Has5GSNN26},
+	{"ETHPDN", (*IE).HasETHPDN}, {"5SRHOI", (*IE).Has5SRHOI}, {"5GCNRI", (*IE).Has5GCNRI}, {"5GCNRS", (*IE).Has5GCNRS},
+	{"N5GNMI", (*IE).HasN5GNMI}, {"MTEDTA", (*IE).HasMTEDTA}, {"MTEDTN", (*IE).HasMTEDTN}, {"CSRMFI", (*IE).HasCSRMFI},
+	{"EMCI", (*IE).HasEMCI},
+}
+
+// indicationValue returns the names of the flags set in Indication IE.
+func indicationValue(i *IE) (interface{}, error) {
+	if _, err := i.Indication(); err != nil {
+		return nil, err
+	}
+
+	flags := []string{}
+	for _, f := range indicationFlags {
+		if f.has(i) {
+			flags = append(flags, f.name)
+		}
+	}
+	return flags, nil
+}
+
+// uliValue returns the present fields in ULI IE. The fields are not flattened, as
+// the ones in UserLocationInformationFields have the same names.
+func uliValue(i *IE) (interface{}, error) {
+	f, err := i.UserLocationInformation()
+	if err != nil {
+		return nil, err
+	}
+
+	v := map[string]interface{}{}
+	if f.CGI != nil {
+		v["CGI"] = f.CGI
+	}
+	if f.SAI != nil {
+		v["SAI"] = f.SAI
+	}
+	if f.RAI != nil {
+		v["RAI"] = f.RAI
+	}
+	if f.TAI != nil {
+		v["TAI"] = f.TAI
+	}
+	if f.ECGI != nil {
+		v["ECGI"] = f.ECGI
+	}
+	if f.LAI != nil {
+		v["LAI"] = f.LAI
+	}
+	if f.MENBI != nil {
+		v["MENBI"] = f.MENBI
+	}
+	if f.EMENBI != nil {
+		v["EMENBI"] = f.EMENBI
+	}
+	return v, nil
+}
+
+func timeZoneValue(i *IE) (interface{}, error) {
+	tz, err := i.TimeZone()
+	if err != nil {
+		return nil, err
+	}
+	ds, err := i.DaylightSaving()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"TimeZone": tz.String(), "DaylightSaving": ds}, nil
+}
+
+func durationValue(d time.Duration, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return d.String(), nil
+}
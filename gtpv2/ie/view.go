@@ -0,0 +1,133 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ie
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// View is a read-only view of an IE on the buffer it is decoded from.
+//
+// Unlike Parse, View does not allocate anything, and the children of grouped IE
+// are not decoded until they are iterated with ChildIEs. Use IE to get *IE when
+// the value is needed to be retrieved with the type-specific methods, e.g., IMSI.
+type View []byte
+
+// Type returns the type of IE.
+func (v View) Type() uint8 {
+	return v[0]
+}
+
+// Length returns the value in the Length field.
+func (v View) Length() uint16 {
+	return binary.BigEndian.Uint16(v[1:3])
+}
+
+// Instance returns the instance value.
+func (v View) Instance() uint8 {
+	return v[3] & 0x0f
+}
+
+// Payload returns the payload of IE.
+func (v View) Payload() []byte {
+	return v[4:]
+}
+
+// IsGrouped reports whether an IE is grouped type or not.
+func (v View) IsGrouped() bool {
+	return isGroupedType(v.Type())
+}
+
+// ChildIEs returns the Iterator over the children of grouped IE.
+//
+// If the IE is not grouped, the Iterator returns no IE and ErrInvalidType.
+func (v View) ChildIEs() Iterator {
+	if !v.IsGrouped() {
+		return Iterator{err: ErrInvalidType}
+	}
+	return NewIterator(v.Payload())
+}
+
+// FindByType returns the first child IE looked up by type and instance.
+func (v View) FindByType(typ, instance uint8) (View, error) {
+	it := v.ChildIEs()
+	return it.FindByType(typ, instance)
+}
+
+// IE decodes the View as *IE. The Payload of it refers to the same buffer as View.
+func (v View) IE() (*IE, error) {
+	return Parse(v)
+}
+
+// Iterator iterates over the IEs in the buffer without allocations.
+//
+// It is used as below.
+//
+//	it := ie.NewIterator(b)
+//	for it.Next() {
+//		i := it.IE()
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type Iterator struct {
+	b   []byte
+	cur View
+	err error
+}
+
+// NewIterator creates a new Iterator over the IEs in b.
+func NewIterator(b []byte) Iterator {
+	return Iterator{b: b}
+}
+
+// Next advances the Iterator to the next IE, and reports whether there is one.
+// It returns false at the end of the buffer, or when the IE is malformed.
+func (it *Iterator) Next() bool {
+	if it.err != nil || len(it.b) == 0 {
+		it.cur = nil
+		return false
+	}
+	if len(it.b) < 4 {
+		it.cur, it.err = nil, io.ErrUnexpectedEOF
+		return false
+	}
+
+	l := 4 + int(binary.BigEndian.Uint16(it.b[1:3]))
+	if l > len(it.b) {
+		it.cur, it.err = nil, ErrInvalidLength
+		return false
+	}
+
+	it.cur = View(it.b[:l:l])
+	it.b = it.b[l:]
+	return true
+}
+
+// IE returns the current IE.
+func (it *Iterator) IE() View {
+	return it.cur
+}
+
+// Err returns the error occurred in iterating, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// FindByType advances the Iterator to the IE looked up by type and instance, and
+// returns it.
+func (it *Iterator) FindByType(typ, instance uint8) (View, error) {
+	for it.Next() {
+		if i := it.IE(); i.Type() == typ && i.Instance() == instance {
+			return i, nil
+		}
+	}
+	if it.err != nil {
+		return nil, it.err
+	}
+	return nil, ErrIENotFound
+}
@@ -0,0 +1,460 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net"
+	"sync"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/teid"
+)
+
+// UEAddress is the IP address(es) allocated to UE for a PDN connection.
+type UEAddress struct {
+	// PDNType is the PDN Type of the allocated address(es), which may differ from
+	// the requested one if the pool for the requested type is not available.
+	PDNType uint8
+
+	IPv4             net.IP
+	IPv6             net.IP
+	IPv6PrefixLength uint8
+}
+
+// PAA returns PDN Address Allocation IE with the address(es).
+func (a *UEAddress) PAA() *ie.IE {
+	switch a.PDNType {
+	case PDNTypeIPv4:
+		return ie.NewPDNAddressAllocationNetIP(a.IPv4, 0)
+	case PDNTypeIPv6:
+		return ie.NewPDNAddressAllocationNetIP(a.IPv6, a.IPv6PrefixLength)
+	case PDNTypeIPv4v6:
+		return ie.NewPDNAddressAllocationDualNetIP(a.IPv4, a.IPv6, a.IPv6PrefixLength)
+	default:
+		return ie.NewPDNAddressAllocationNetIP(nil, 0)
+	}
+}
+
+// String returns the address(es) in string. IPv4 address is preferred if both
+// are allocated, which is the format of Bearer.SubscriberIP.
+func (a *UEAddress) String() string {
+	if a.IPv4 != nil {
+		return a.IPv4.String()
+	}
+	if a.IPv6 != nil {
+		return a.IPv6.String()
+	}
+	return ""
+}
+
+// IPPool manages the pools of IP addresses allocated to UEs, per APN.
+//
+// Each APN can have an IPv4 pool and an IPv6 pool. IPv6 is allocated as a prefix
+// for each UE. The addresses can also be statically assigned to the subscriber by
+// IMSI, which are preferred to the dynamic ones.
+//
+// The allocation is identified by IMSI and APN, and allocating again for the same
+// pair returns the same address(es) until it is released.
+type IPPool struct {
+	mu          sync.Mutex
+	apns        map[string]*apnPool
+	statics     map[ipPoolKey]*UEAddress
+	allocations map[ipPoolKey]*allocation
+}
+
+type ipPoolKey struct {
+	imsi, apn string
+}
+
+type apnPool struct {
+	v4, v6 *addrPool
+}
+
+type allocation struct {
+	addr   *UEAddress
+	static bool
+	v4, v6 uint32
+}
+
+// NewIPPool creates an empty IPPool. Add pools with AddIPv4Pool and AddIPv6Pool.
+func NewIPPool() *IPPool {
+	return &IPPool{
+		apns:        map[string]*apnPool{},
+		statics:     map[ipPoolKey]*UEAddress{},
+		allocations: map[ipPoolKey]*allocation{},
+	}
+}
+
+// AddIPv4Pool adds the pool of IPv4 addresses for the APN with the prefix in CIDR
+// notation, e.g., "10.45.0.0/16". The network and broadcast addresses are not used.
+//
+// It returns error if the APN already has an IPv4 pool, as replacing it would lose
+// track of the addresses allocated from it.
+func (p *IPPool) AddIPv4Pool(apn, prefix string) error {
+	_, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return err
+	}
+	ones, size := ipnet.Mask.Size()
+	if size != 32 || ones > 30 {
+		return fmt.Errorf("invalid IPv4 prefix for pool: %s", prefix)
+	}
+
+	// excluding network and broadcast address.
+	pool, err := newAddrPool(ipnet, 32, 1, 1<<(32-ones)-2)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pools := p.apnPool(apn)
+	if pools.v4 != nil {
+		return fmt.Errorf("IPv4 pool for APN %s already exists: %s", apn, pools.v4.network)
+	}
+	pools.v4 = pool
+	p.reserveStatics(apn)
+	return nil
+}
+
+// AddIPv6Pool adds the pool of IPv6 prefixes for the APN. Each UE is given a prefix
+// with the length of prefixLen from the prefix in CIDR notation, e.g., the prefixes
+// 2001:db8:0:0::/64, 2001:db8:0:1::/64, ... are allocated from "2001:db8::/48" with
+// prefixLen=64.
+//
+// It returns error if the APN already has an IPv6 pool, as replacing it would lose
+// track of the prefixes allocated from it.
+func (p *IPPool) AddIPv6Pool(apn, prefix string, prefixLen uint8) error {
+	_, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return err
+	}
+	ones, size := ipnet.Mask.Size()
+	if size != 128 || int(prefixLen) < ones || prefixLen > 128 {
+		return fmt.Errorf("invalid IPv6 prefix for pool: %s, length: %d", prefix, prefixLen)
+	}
+
+	// limited to the number that teid.Allocator can handle.
+	var count uint64 = 0xffffffff
+	if bitsLen := int(prefixLen) - ones; bitsLen < 32 {
+		count = 1 << bitsLen
+	}
+	pool, err := newAddrPool(ipnet, prefixLen, 0, count-1)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pools := p.apnPool(apn)
+	if pools.v6 != nil {
+		return fmt.Errorf("IPv6 pool for APN %s already exists: %s", apn, pools.v6.network)
+	}
+	pools.v6 = pool
+	p.reserveStatics(apn)
+	return nil
+}
+
+// AddStaticAddress assigns the address(es) statically to the subscriber for the APN.
+//
+// Either of v4 or v6 can be nil. If the address is in the pool for the APN, it is
+// not allocated to the others dynamically.
+func (p *IPPool) AddStaticAddress(imsi, apn string, v4, v6 net.IP, v6PrefixLen uint8) error {
+	addr := &UEAddress{IPv6PrefixLength: v6PrefixLen}
+	switch {
+	case v4 != nil && v6 != nil:
+		addr.PDNType = PDNTypeIPv4v6
+	case v4 != nil:
+		addr.PDNType = PDNTypeIPv4
+	case v6 != nil:
+		addr.PDNType = PDNTypeIPv6
+	default:
+		return &RequiredParameterMissingError{"IP", "either IPv4 or IPv6 address is required"}
+	}
+	if v4 != nil {
+		addr.IPv4 = v4.To4()
+		if addr.IPv4 == nil {
+			return fmt.Errorf("invalid IPv4 address: %s", v4)
+		}
+	}
+	if v6 != nil {
+		addr.IPv6 = v6.To16()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statics[ipPoolKey{imsi, apn}] = addr
+	p.reserveStatics(apn)
+	return nil
+}
+
+// Allocate allocates the address(es) of the pdnType to the subscriber for the APN.
+//
+// If the pool of the requested type is not available for IPv4v6, the available one
+// is allocated and the PDNType in UEAddress tells which. It returns *IPAllocationError
+// with the Cause value to be used in the response if no address can be allocated.
+func (p *IPPool) Allocate(imsi, apn string, pdnType uint8) (*UEAddress, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := ipPoolKey{imsi, apn}
+	if a, ok := p.allocations[key]; ok {
+		return a.addr, nil
+	}
+
+	if static, ok := p.statics[key]; ok {
+		p.allocations[key] = &allocation{addr: static, static: true}
+		return static, nil
+	}
+
+	pools, ok := p.apns[apn]
+	if !ok {
+		return nil, &IPAllocationError{
+			IMSI: imsi, APN: apn, Cause: CauseMissingOrUnknownAPN, Msg: "no pool for APN",
+		}
+	}
+
+	var useV4, useV6 bool
+	switch pdnType {
+	case PDNTypeIPv4:
+		useV4 = true
+	case PDNTypeIPv6:
+		useV6 = true
+	case PDNTypeIPv4v6:
+		useV4, useV6 = pools.v4 != nil, pools.v6 != nil
+	}
+	if (useV4 && pools.v4 == nil) || (useV6 && pools.v6 == nil) || (!useV4 && !useV6) {
+		return nil, &IPAllocationError{
+			IMSI: imsi, APN: apn, Cause: CausePreferredPDNTypeNotSupported,
+			Msg: fmt.Sprintf("PDN Type %d is not available", pdnType),
+		}
+	}
+
+	a := &allocation{addr: &UEAddress{}}
+	if useV4 {
+		ip, idx, err := pools.v4.allocate()
+		if err != nil {
+			return nil, &IPAllocationError{
+				IMSI: imsi, APN: apn, Cause: CauseAllDynamicAddressesAreOccupied, Msg: err.Error(),
+			}
+		}
+		a.addr.IPv4, a.v4 = ip, idx
+		a.addr.PDNType = PDNTypeIPv4
+	}
+	if useV6 {
+		ip, idx, err := pools.v6.allocate()
+		if err != nil {
+			if useV4 {
+				pools.v4.release(a.v4)
+			}
+			return nil, &IPAllocationError{
+				IMSI: imsi, APN: apn, Cause: CauseAllDynamicAddressesAreOccupied, Msg: err.Error(),
+			}
+		}
+		a.addr.IPv6, a.v6 = ip, idx
+		a.addr.IPv6PrefixLength = pools.v6.prefixLen
+		if useV4 {
+			a.addr.PDNType = PDNTypeIPv4v6
+		} else {
+			a.addr.PDNType = PDNTypeIPv6
+		}
+	}
+
+	p.allocations[key] = a
+	return a.addr, nil
+}
+
+// AllocatePAA allocates the address(es) to the subscriber of the session, for
+// the APN of the default bearer, and returns PDN Address Allocation IE to be set
+// in Create Session Response. SubscriberIP of the default bearer is updated with
+// the allocated address.
+//
+// pdnType is typically the value in the PDN Type IE in Create Session Request.
+func (p *IPPool) AllocatePAA(session *Session, pdnType uint8) (*ie.IE, error) {
+	br := session.GetDefaultBearer()
+	if br == nil {
+		return nil, &BearerNotFoundError{IMSI: session.IMSI}
+	}
+
+	addr, err := p.Allocate(session.IMSI, br.APN, pdnType)
+	if err != nil {
+		return nil, err
+	}
+
+	br.SubscriberIP = addr.String()
+	return addr.PAA(), nil
+}
+
+// Release releases the address(es) allocated to the subscriber for the APN.
+// The static addresses are kept reserved.
+func (p *IPPool) Release(imsi, apn string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := ipPoolKey{imsi, apn}
+	a, ok := p.allocations[key]
+	if !ok {
+		return
+	}
+	delete(p.allocations, key)
+
+	pools, ok := p.apns[apn]
+	if a.static || !ok {
+		return
+	}
+	if a.addr.IPv4 != nil && pools.v4 != nil {
+		pools.v4.release(a.v4)
+	}
+	if a.addr.IPv6 != nil && pools.v6 != nil {
+		pools.v6.release(a.v6)
+	}
+}
+
+// ReleaseSession releases the address(es) allocated to the subscriber of the
+// session, for the APN of the default bearer.
+//
+// This is called automatically by Conn.RemoveSession if the IPPool is given to
+// Conn with SetIPPool.
+func (p *IPPool) ReleaseSession(session *Session) {
+	p.Release(session.IMSI, session.APN())
+}
+
+func (p *IPPool) apnPool(apn string) *apnPool {
+	pools, ok := p.apns[apn]
+	if !ok {
+		pools = &apnPool{}
+		p.apns[apn] = pools
+	}
+	return pools
+}
+
+// reserveStatics marks the static addresses for the APN as used in the pools.
+func (p *IPPool) reserveStatics(apn string) {
+	pools, ok := p.apns[apn]
+	if !ok {
+		return
+	}
+
+	for key, addr := range p.statics {
+		if key.apn != apn {
+			continue
+		}
+		if pools.v4 != nil && addr.IPv4 != nil {
+			pools.v4.reserve(addr.IPv4)
+		}
+		if pools.v6 != nil && addr.IPv6 != nil {
+			pools.v6.reserve(addr.IPv6)
+		}
+	}
+}
+
+// addrPool is a pool of addresses or prefixes in a network.
+//
+// The n-th address(prefix) in the network is tracked by teid.Allocator as n+1,
+// as it never allocates zero.
+type addrPool struct {
+	network   *net.IPNet
+	prefixLen uint8
+	allocator teid.Allocator
+}
+
+func newAddrPool(network *net.IPNet, prefixLen uint8, first, last uint64) (*addrPool, error) {
+	a, err := teid.NewSequentialAllocator(uint32(first+1), uint32(last+1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &addrPool{network: network, prefixLen: prefixLen, allocator: a}, nil
+}
+
+func (a *addrPool) allocate() (net.IP, uint32, error) {
+	v, err := a.allocator.Allocate()
+	if err != nil {
+		if errors.Is(err, teid.ErrExhausted) {
+			return nil, 0, fmt.Errorf("all addresses in %s are occupied", a.network)
+		}
+		return nil, 0, err
+	}
+	return a.nth(uint64(v - 1)), v, nil
+}
+
+func (a *addrPool) release(v uint32) {
+	a.allocator.Release(v)
+}
+
+func (a *addrPool) reserve(ip net.IP) {
+	if !a.network.Contains(ip) {
+		return
+	}
+	_ = a.allocator.Reserve(uint32(a.index(ip) + 1))
+}
+
+// shift returns the number of bits to shift the index to get the address.
+func (a *addrPool) shift() uint {
+	return uint(len(a.network.IP)*8) - uint(a.prefixLen)
+}
+
+// nth returns the n-th address(prefix) in the network.
+func (a *addrPool) nth(n uint64) net.IP {
+	base := a.network.IP
+	if v4 := base.To4(); v4 != nil {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(v4)+uint32(n))
+		return ip
+	}
+
+	hi, lo := binary.BigEndian.Uint64(base[:8]), binary.BigEndian.Uint64(base[8:])
+	offHi, offLo := shiftLeft128(n, a.shift())
+
+	var carry uint64
+	lo, carry = bits.Add64(lo, offLo, 0)
+	hi, _ = bits.Add64(hi, offHi, carry)
+
+	ip := make(net.IP, 16)
+	binary.BigEndian.PutUint64(ip[:8], hi)
+	binary.BigEndian.PutUint64(ip[8:], lo)
+	return ip
+}
+
+// index returns the index of ip in the network, which is the reverse of nth.
+func (a *addrPool) index(ip net.IP) uint64 {
+	base := a.network.IP
+	if v4 := base.To4(); v4 != nil {
+		return uint64(binary.BigEndian.Uint32(ip.To4()) - binary.BigEndian.Uint32(v4))
+	}
+
+	ip = ip.To16()
+	hi, lo := binary.BigEndian.Uint64(ip[:8]), binary.BigEndian.Uint64(ip[8:])
+	baseHi, baseLo := binary.BigEndian.Uint64(base[:8]), binary.BigEndian.Uint64(base[8:])
+
+	var borrow uint64
+	lo, borrow = bits.Sub64(lo, baseLo, 0)
+	hi, _ = bits.Sub64(hi, baseHi, borrow)
+
+	s := a.shift()
+	switch {
+	case s >= 64:
+		return hi >> (s - 64)
+	case s == 0:
+		return lo
+	default:
+		return lo>>s | hi<<(64-s)
+	}
+}
+
+func shiftLeft128(n uint64, s uint) (hi, lo uint64) {
+	switch {
+	case s >= 64:
+		return n << (s - 64), 0
+	case s == 0:
+		return 0, n
+	default:
+		return n >> (64 - s), n << s
+	}
+}
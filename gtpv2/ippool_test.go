@@ -0,0 +1,202 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+)
+
+func TestIPPool(t *testing.T) {
+	pool := gtpv2.NewIPPool()
+	if err := pool.AddIPv4Pool("internet", "10.0.0.0/30"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddIPv6Pool("internet", "2001:db8::/63", 64); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddIPv4Pool("v4only", "10.1.0.0/24"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddStaticAddress("001011234567899", "internet", net.ParseIP("10.0.0.2"), nil, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// static address is always given to the subscriber.
+	addr, err := pool.Allocate("001011234567899", "internet", gtpv2.PDNTypeIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.String() != "10.0.0.2" {
+		t.Errorf("wrong static address: %s", addr)
+	}
+
+	// the static one is not allocated dynamically.
+	addr, err = pool.Allocate("001011234567891", "internet", gtpv2.PDNTypeIPv4v6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.PDNType != gtpv2.PDNTypeIPv4v6 || addr.IPv4.String() != "10.0.0.1" ||
+		addr.IPv6.String() != "2001:db8::" || addr.IPv6PrefixLength != 64 {
+		t.Errorf("wrong address: %+v", addr)
+	}
+
+	// same address for the same subscriber and APN.
+	again, err := pool.Allocate("001011234567891", "internet", gtpv2.PDNTypeIPv4v6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != addr {
+		t.Errorf("got different address: %+v", again)
+	}
+
+	addr, err = pool.Allocate("001011234567892", "internet", gtpv2.PDNTypeIPv6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.IPv6.String() != "2001:db8:0:1::" {
+		t.Errorf("wrong address: %+v", addr)
+	}
+
+	checkCause := func(err error, cause uint8) {
+		t.Helper()
+
+		var ipErr *gtpv2.IPAllocationError
+		if !errors.As(err, &ipErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ipErr.Cause != cause {
+			t.Errorf("wrong Cause: want %d, got %d", cause, ipErr.Cause)
+		}
+	}
+
+	_, err = pool.Allocate("001011234567893", "internet", gtpv2.PDNTypeIPv4)
+	checkCause(err, gtpv2.CauseAllDynamicAddressesAreOccupied)
+	_, err = pool.Allocate("001011234567893", "unknown", gtpv2.PDNTypeIPv4)
+	checkCause(err, gtpv2.CauseMissingOrUnknownAPN)
+	_, err = pool.Allocate("001011234567893", "v4only", gtpv2.PDNTypeIPv6)
+	checkCause(err, gtpv2.CausePreferredPDNTypeNotSupported)
+
+	// only IPv4 is given to IPv4v6 request if IPv6 is not available.
+	addr, err = pool.Allocate("001011234567893", "v4only", gtpv2.PDNTypeIPv4v6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.PDNType != gtpv2.PDNTypeIPv4 || addr.IPv4.String() != "10.1.0.1" {
+		t.Errorf("wrong address: %+v", addr)
+	}
+
+	pool.Release("001011234567891", "internet")
+	addr, err = pool.Allocate("001011234567893", "internet", gtpv2.PDNTypeIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.String() != "10.0.0.1" {
+		t.Errorf("released address should be reused: %s", addr)
+	}
+}
+
+func TestIPPoolAllocatePAA(t *testing.T) {
+	pool := gtpv2.NewIPPool()
+	if err := pool.AddIPv4Pool("internet", "10.0.0.0/24"); err != nil {
+		t.Fatal(err)
+	}
+
+	sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+	sess.GetDefaultBearer().APN = "internet"
+
+	paa, err := pool.AllocatePAA(sess, gtpv2.PDNTypeIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paa.Type != ie.PDNAddressAllocation {
+		t.Errorf("wrong IE type: %d", paa.Type)
+	}
+	if got := paa.MustIPAddress(); got != "10.0.0.1" {
+		t.Errorf("wrong address in PAA: %s", got)
+	}
+	if got := sess.GetDefaultBearer().SubscriberIP; got != "10.0.0.1" {
+		t.Errorf("wrong SubscriberIP: %s", got)
+	}
+
+	pool.ReleaseSession(sess)
+	other := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567892"})
+	other.GetDefaultBearer().APN = "internet"
+	if _, err := pool.AllocatePAA(other, gtpv2.PDNTypeIPv4); err != nil {
+		t.Fatal(err)
+	}
+	if got := other.GetDefaultBearer().SubscriberIP; got != "10.0.0.2" {
+		t.Errorf("wrong SubscriberIP: %s", got)
+	}
+}
+
+func TestIPPoolAddTwice(t *testing.T) {
+	pool := gtpv2.NewIPPool()
+	if err := pool.AddIPv4Pool("internet", "10.0.0.0/24"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddIPv6Pool("internet", "2001:db8::/48", 64); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Allocate("001011234567891", "internet", gtpv2.PDNTypeIPv4v6); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pool.AddIPv4Pool("internet", "10.1.0.0/24"); err == nil {
+		t.Error("adding IPv4 pool twice should fail")
+	}
+	if err := pool.AddIPv6Pool("internet", "2001:db8:1::/48", 64); err == nil {
+		t.Error("adding IPv6 pool twice should fail")
+	}
+
+	// the allocated ones are still tracked in the existing pools.
+	addr, err := pool.Allocate("001011234567892", "internet", gtpv2.PDNTypeIPv4v6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.IPv4.String() != "10.0.0.2" || addr.IPv6.String() != "2001:db8:0:1::" {
+		t.Errorf("wrong address: %+v", addr)
+	}
+}
+
+func TestIPPoolRemoveSession(t *testing.T) {
+	pool := gtpv2.NewIPPool()
+	if err := pool.AddIPv4Pool("internet", "10.0.0.0/30"); err != nil {
+		t.Fatal(err)
+	}
+	conn := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS11S4SGWGTPC, 0)
+	conn.SetIPPool(pool)
+
+	var sessions []*gtpv2.Session
+	for i, imsi := range []string{"001011234567891", "001011234567892"} {
+		sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: imsi})
+		sess.GetDefaultBearer().APN = "internet"
+		sess.AddTEID(gtpv2.IFTypeS11S4SGWGTPC, uint32(i+1))
+		conn.RegisterSession(uint32(i+1), sess)
+		if _, err := pool.AllocatePAA(sess, gtpv2.PDNTypeIPv4); err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, sess)
+	}
+
+	third := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567893"})
+	third.GetDefaultBearer().APN = "internet"
+	if _, err := pool.AllocatePAA(third, gtpv2.PDNTypeIPv4); err == nil {
+		t.Fatal("pool should be exhausted")
+	}
+
+	// the address is released together with the session.
+	conn.RemoveSession(sessions[0])
+	if _, err := pool.AllocatePAA(third, gtpv2.PDNTypeIPv4); err != nil {
+		t.Fatal(err)
+	}
+	if got := third.GetDefaultBearer().SubscriberIP; got != "10.0.0.1" {
+		t.Errorf("wrong SubscriberIP: %s", got)
+	}
+}
@@ -680,6 +680,7 @@ func (c *CreateSessionRequest) MarshalTo(b []byte) error {
 		if err := ie.MarshalTo(c.Payload[offset:]); err != nil {
 			return err
 		}
+		offset += ie.MarshalLen()
 	}
 
 	for _, ie := range c.AdditionalIEs {
@@ -0,0 +1,376 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+)
+
+// The functions in this file edit the IEs in any type of Message by the path to
+// them, which is the list of the IEs from the top level to the target separated
+// by "/", e.g., "BearerContext[0]/F-TEID[1]".
+//
+// Each element of the path is the name of the IE type(the one returned by ie.Name,
+// the abbreviation accepted by ie.TypeByName, or the type value in decimal),
+// optionally followed by the instance in brackets. When there are multiple IEs with
+// the same type and instance, the n-th(from 0) one is specified by appending ":n"
+// to the instance, e.g., "BearerContext[0:1]" for the second Bearer Context with
+// instance 0.
+//
+// Generic and Ordered are edited in place, keeping the order of the IEs. The typed
+// messages are serialized, edited as Ordered and decoded again into the same
+// struct, so that the IEs retrieved from them before editing are no longer valid.
+// In any case, the lengths of the IEs, the grouped IEs that contain them and the
+// message are recomputed.
+
+// PathIE is an IE with the path to it in a message.
+type PathIE struct {
+	Path string
+	IE   *ie.IE
+}
+
+// ListIEs returns all the IEs in the message including the children of grouped
+// IEs, with the paths to them, in the order on the wire.
+func ListIEs(m Message) ([]*PathIE, error) {
+	ies, err := iesOf(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*PathIE
+	var walk func(prefix string, ies []*ie.IE)
+	walk = func(prefix string, ies []*ie.IE) {
+		seen := map[[2]uint8]int{}
+		for _, i := range ies {
+			if i == nil {
+				continue
+			}
+			key := [2]uint8{i.Type, i.Instance()}
+			path := prefix + formatPathElement(i, seen[key])
+			seen[key]++
+
+			list = append(list, &PathIE{Path: path, IE: i})
+			if i.IsGrouped() {
+				walk(path+"/", i.ChildIEs)
+			}
+		}
+	}
+	walk("", ies)
+	return list, nil
+}
+
+// FindIE returns the IE specified by path in the message.
+//
+// For the typed messages, the IE returned is a copy, and the changes made on it
+// are not reflected to the message. Use ReplaceIE to modify it.
+func FindIE(m Message, path string) (*ie.IE, error) {
+	ies, err := iesOf(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *ie.IE
+	if err := editIEs(&ies, path, false, func(list *[]*ie.IE, idx int) error {
+		found = (*list)[idx]
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// ReplaceIE replaces the IE specified by path in the message with i.
+func ReplaceIE(m Message, path string, i *ie.IE) error {
+	return edit(m, path, false, func(list *[]*ie.IE, idx int) error {
+		(*list)[idx] = i
+		return nil
+	})
+}
+
+// DeleteIE removes the IE specified by path from the message.
+func DeleteIE(m Message, path string) error {
+	return edit(m, path, false, func(list *[]*ie.IE, idx int) error {
+		*list = append((*list)[:idx], (*list)[idx+1:]...)
+		return nil
+	})
+}
+
+// InsertIE inserts i before the IE specified by path in the message.
+func InsertIE(m Message, path string, i *ie.IE) error {
+	return edit(m, path, false, func(list *[]*ie.IE, idx int) error {
+		*list = append((*list)[:idx], append([]*ie.IE{i}, (*list)[idx:]...)...)
+		return nil
+	})
+}
+
+// AppendIE adds i at the end of the grouped IE specified by parent in the message,
+// or at the end of the message if parent is empty.
+func AppendIE(m Message, parent string, i *ie.IE) error {
+	return edit(m, parent, true, func(list *[]*ie.IE, _ int) error {
+		*list = append(*list, i)
+		return nil
+	})
+}
+
+// iesOf returns the IEs in the message in the order on the wire.
+func iesOf(m Message) ([]*ie.IE, error) {
+	switch m := m.(type) {
+	case *Generic:
+		return m.IEs, nil
+	case *Ordered:
+		return m.IEs, nil
+	}
+
+	o, err := NewOrdered(m)
+	if err != nil {
+		return nil, err
+	}
+	return o.IEs, nil
+}
+
+func edit(m Message, path string, parent bool, fn func(list *[]*ie.IE, idx int) error) error {
+	switch m := m.(type) {
+	case *Generic:
+		if err := editIEs(&m.IEs, path, parent, fn); err != nil {
+			return err
+		}
+		for _, i := range m.IEs {
+			if i != nil {
+				setLengths(i)
+			}
+		}
+		m.SetLength()
+		return nil
+	case *Ordered:
+		if err := editIEs(&m.IEs, path, parent, fn); err != nil {
+			return err
+		}
+		m.SetLength()
+		return nil
+	}
+
+	o, err := NewOrdered(m)
+	if err != nil {
+		return err
+	}
+	if err := editIEs(&o.IEs, path, parent, fn); err != nil {
+		return err
+	}
+	b, err := o.Marshal()
+	if err != nil {
+		return err
+	}
+
+	// decode into the new one not to leave the removed IEs, and not to break m
+	// if it fails.
+	v := reflect.ValueOf(m).Elem()
+	decoded := reflect.New(v.Type())
+	if err := decoded.Interface().(Message).UnmarshalBinary(b); err != nil {
+		return err
+	}
+	v.Set(decoded.Elem())
+	return nil
+}
+
+// editIEs calls fn with the list that contains the IE specified by path and the
+// index of it in the list. If parent is true, the path specifies the grouped IE
+// that contains the list, and the index is the length of the list.
+//
+// The payloads and lengths of the grouped IEs that contain the list are updated.
+// If the edit makes any of the IEs or the whole message too long, it returns error
+// without changing anything.
+func editIEs(ies *[]*ie.IE, path string, parent bool, fn func(list *[]*ie.IE, idx int) error) error {
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if !parent && len(elems) == 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidPath, path)
+	}
+
+	var parents []*ie.IE
+	list := ies
+	for n, e := range elems {
+		idx := e.indexIn(*list)
+		if idx < 0 {
+			return fmt.Errorf("%s: %w", strings.Join(strings.Split(path, "/")[:n+1], "/"), ie.ErrIENotFound)
+		}
+		if n == len(elems)-1 && !parent {
+			break
+		}
+
+		p := (*list)[idx]
+		if !p.IsGrouped() {
+			return fmt.Errorf("%s: %w", strings.Join(strings.Split(path, "/")[:n+1], "/"), ie.ErrInvalidType)
+		}
+		parents = append(parents, p)
+		list = &p.ChildIEs
+	}
+
+	idx := len(*list)
+	if !parent {
+		idx = elems[len(elems)-1].indexIn(*list)
+	}
+	edited := append([]*ie.IE(nil), (*list)...)
+	if err := fn(&edited, idx); err != nil {
+		return err
+	}
+	if err := checkLengths(*ies, parents, *list, edited); err != nil {
+		return err
+	}
+	*list = edited
+
+	for n := len(parents) - 1; n >= 0; n-- {
+		p := parents[n]
+		p.Payload = nil
+		for _, c := range p.ChildIEs {
+			if c == nil {
+				continue
+			}
+			setLengths(c)
+			b, err := c.Marshal()
+			if err != nil {
+				return err
+			}
+			p.Payload = append(p.Payload, b...)
+		}
+		p.SetLength()
+	}
+	return nil
+}
+
+// maxIEsLength is the maximum total length of the IEs in a message, which is the
+// maximum value of the Length field minus TEID and Sequence Number.
+const maxIEsLength = 0xffff - 8
+
+// checkLengths checks if the IEs in edited, the grouped IEs in parents that contain
+// it in place of list, and all the IEs in the message fit in the Length fields.
+func checkLengths(ies, parents, list, edited []*ie.IE) error {
+	for _, i := range edited {
+		if err := checkIELength(i); err != nil {
+			return err
+		}
+	}
+
+	delta := totalLength(edited) - totalLength(list)
+	for _, p := range parents {
+		if p.MarshalLen()-4+delta > 0xffff {
+			return fmt.Errorf("%w: %s would be too long", ErrInvalidLength, p.Name())
+		}
+	}
+	if totalLength(ies)+delta > maxIEsLength {
+		return fmt.Errorf("%w: message would be too long", ErrInvalidLength)
+	}
+	return nil
+}
+
+func checkIELength(i *ie.IE) error {
+	if i == nil {
+		return nil
+	}
+	if i.MarshalLen()-4 > 0xffff {
+		return fmt.Errorf("%w: %s is too long", ErrInvalidLength, i.Name())
+	}
+	if i.IsGrouped() {
+		for _, c := range i.ChildIEs {
+			if err := checkIELength(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func totalLength(ies []*ie.IE) int {
+	l := 0
+	for _, i := range ies {
+		if i != nil {
+			l += i.MarshalLen()
+		}
+	}
+	return l
+}
+
+type pathElement struct {
+	typ, instance uint8
+	n             int
+}
+
+func (e *pathElement) indexIn(ies []*ie.IE) int {
+	n := 0
+	for idx, i := range ies {
+		if i == nil || i.Type != e.typ || i.Instance() != e.instance {
+			continue
+		}
+		if n == e.n {
+			return idx
+		}
+		n++
+	}
+	return -1
+}
+
+func parsePath(path string) ([]*pathElement, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	var elems []*pathElement
+	for _, s := range strings.Split(path, "/") {
+		e := &pathElement{}
+		name := s
+		if l := strings.IndexByte(s, '['); l >= 0 {
+			if !strings.HasSuffix(s, "]") {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+			}
+			name = s[:l]
+
+			ins, n := s[l+1:len(s)-1], ""
+			if c := strings.IndexByte(ins, ':'); c >= 0 {
+				ins, n = ins[:c], ins[c+1:]
+			}
+			v, err := strconv.ParseUint(ins, 10, 4)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: invalid instance %q", ErrInvalidPath, path, ins)
+			}
+			e.instance = uint8(v)
+			if n != "" {
+				v, err := strconv.ParseUint(n, 10, 31)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %q: invalid index %q", ErrInvalidPath, path, n)
+				}
+				e.n = int(v)
+			}
+		}
+
+		typ, ok := ie.TypeByName(name)
+		if !ok {
+			v, err := strconv.ParseUint(name, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: unknown IE %q", ErrInvalidPath, path, name)
+			}
+			typ = uint8(v)
+		}
+		e.typ = typ
+		elems = append(elems, e)
+	}
+	return elems, nil
+}
+
+func formatPathElement(i *ie.IE, n int) string {
+	name := i.Name()
+	if _, ok := ie.TypeByName(name); !ok {
+		name = strconv.Itoa(int(i.Type))
+	}
+	if n == 0 {
+		return fmt.Sprintf("%s[%d]", name, i.Instance())
+	}
+	return fmt.Sprintf("%s[%d:%d]", name, i.Instance(), n)
+}
@@ -0,0 +1,252 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func newEditedCSReq() *message.CreateSessionRequest {
+	return message.NewCreateSessionRequest(
+		0x11223344, 1,
+		ie.NewIMSI("123451234567890"),
+		ie.NewMSISDN("123450123456789"),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11MMEGTPC, 0x11111111, "1.1.1.1", ""),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPC, 0x22222222, "1.1.1.2", "").WithInstance(1),
+		ie.NewBearerContext(
+			ie.NewEPSBearerID(0x05),
+			ie.NewBearerQoS(1, 2, 1, 0xff, 0x1111111111, 0x2222222222, 0x1111111111, 0x2222222222),
+		),
+		ie.NewBearerContext(ie.NewEPSBearerID(0x06)).WithInstance(1),
+		ie.New(0xfa, 0x00, []byte{0xde, 0xad}),
+	)
+}
+
+func paths(t *testing.T, m message.Message) []string {
+	t.Helper()
+
+	list, err := message.ListIEs(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p []string
+	for _, i := range list {
+		p = append(p, i.Path)
+	}
+	return p
+}
+
+func TestListIEs(t *testing.T) {
+	g := message.NewGeneric(
+		message.MsgTypeCreateBearerRequest, 0, 1,
+		ie.NewEPSBearerID(0x05),
+		ie.NewBearerContext(ie.NewEPSBearerID(0x06)),
+		ie.NewBearerContext(ie.NewEPSBearerID(0x07)),
+	)
+	want := []string{
+		"EPSBearerID[0]",
+		"BearerContext[0]",
+		"BearerContext[0]/EPSBearerID[0]",
+		"BearerContext[0:1]",
+		"BearerContext[0:1]/EPSBearerID[0]",
+	}
+	if diff := cmp.Diff(paths(t, g), want); diff != "" {
+		t.Error(diff)
+	}
+
+	want = []string{
+		"IMSI[0]",
+		"MSISDN[0]",
+		"FullyQualifiedTEID[0]",
+		"FullyQualifiedTEID[1]",
+		"BearerContext[0]",
+		"BearerContext[0]/EPSBearerID[0]",
+		"BearerContext[0]/BearerQoS[0]",
+		"BearerContext[1]",
+		"BearerContext[1]/EPSBearerID[0]",
+		"250[0]",
+	}
+	if diff := cmp.Diff(paths(t, newEditedCSReq()), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEditTyped(t *testing.T) {
+	csReq := newEditedCSReq()
+
+	if err := message.ReplaceIE(
+		csReq, "F-TEID[1]",
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPC, 0x33333333, "1.1.1.3", "").WithInstance(1),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if teid, err := csReq.PGWS5S8FTEIDC.TEID(); err != nil || teid != 0x33333333 {
+		t.Errorf("got %#x, %v, want 0x33333333", teid, err)
+	}
+
+	if err := message.DeleteIE(csReq, "MSISDN"); err != nil {
+		t.Fatal(err)
+	}
+	if csReq.MSISDN != nil {
+		t.Errorf("MSISDN is not removed: %v", csReq.MSISDN)
+	}
+
+	if err := message.AppendIE(
+		csReq, "BearerContext[0]",
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS1UeNodeBGTPU, 0x44444444, "1.1.1.4", ""),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := message.InsertIE(csReq, "BearerContext[0]/EBI", ie.NewChargingID(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"IMSI[0]",
+		"FullyQualifiedTEID[0]",
+		"FullyQualifiedTEID[1]",
+		"BearerContext[0]",
+		"BearerContext[0]/ChargingID[0]",
+		"BearerContext[0]/EPSBearerID[0]",
+		"BearerContext[0]/BearerQoS[0]",
+		"BearerContext[0]/FullyQualifiedTEID[0]",
+		"BearerContext[1]",
+		"BearerContext[1]/EPSBearerID[0]",
+		"250[0]",
+	}
+	if diff := cmp.Diff(paths(t, csReq), want); diff != "" {
+		t.Error(diff)
+	}
+
+	fteid, err := message.FindIE(csReq, "BearerContext[0]/F-TEID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if teid, err := fteid.TEID(); err != nil || teid != 0x44444444 {
+		t.Errorf("got %#x, %v, want 0x44444444", teid, err)
+	}
+
+	// the lengths are recomputed.
+	b, err := message.Marshal(csReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := int(csReq.Length), len(b)-4; got != want {
+		t.Errorf("got length %d, want %d", got, want)
+	}
+	if got, want := int(csReq.BearerContextsToBeCreated.Length), csReq.BearerContextsToBeCreated.MarshalLen()-4; got != want {
+		t.Errorf("got length %d, want %d", got, want)
+	}
+}
+
+func TestEditGeneric(t *testing.T) {
+	g := message.NewGeneric(
+		message.MsgTypeCreateBearerRequest, 0, 1,
+		ie.NewEPSBearerID(0x05),
+		ie.NewBearerContext(ie.NewEPSBearerID(0x06)),
+		ie.NewBearerContext(ie.NewEPSBearerID(0x07)),
+	)
+
+	if err := message.ReplaceIE(g, "BearerContext[0:1]/EBI", ie.NewEPSBearerID(0x08)); err != nil {
+		t.Fatal(err)
+	}
+	if err := message.DeleteIE(g, "BearerContext[0]"); err != nil {
+		t.Fatal(err)
+	}
+	if err := message.AppendIE(g, "", ie.NewRecovery(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := message.NewGeneric(
+		message.MsgTypeCreateBearerRequest, 0, 1,
+		ie.NewEPSBearerID(0x05),
+		ie.NewBearerContext(ie.NewEPSBearerID(0x08)),
+		ie.NewRecovery(1),
+	)
+	got, err := g.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantb, err := want.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, wantb); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	cases := []struct {
+		path string
+		want error
+	}{
+		{"Recovery", ie.ErrIENotFound},
+		{"BearerContext[2]/EBI", ie.ErrIENotFound},
+		{"BearerContext[0:2]", ie.ErrIENotFound},
+		{"IMSI/EBI", ie.ErrInvalidType},
+		{"NoSuchIE", message.ErrInvalidPath},
+		{"IMSI[16]", message.ErrInvalidPath},
+		{"IMSI[0", message.ErrInvalidPath},
+		{"", message.ErrInvalidPath},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			if err := message.DeleteIE(newEditedCSReq(), c.path); !errors.Is(err, c.want) {
+				t.Errorf("got %v, want %v", err, c.want)
+			}
+		})
+	}
+}
+
+func TestEditTooLong(t *testing.T) {
+	t.Run("Typed", func(t *testing.T) {
+		csReq := newEditedCSReq()
+		want := paths(t, csReq)
+
+		err := message.InsertIE(csReq, "IMSI", ie.New(0xfa, 0x00, make([]byte, 70000)))
+		if !errors.Is(err, message.ErrInvalidLength) {
+			t.Fatalf("got %v, want %v", err, message.ErrInvalidLength)
+		}
+		if diff := cmp.Diff(paths(t, csReq), want); diff != "" {
+			t.Errorf("message is changed: %s", diff)
+		}
+		if csReq.IMSI == nil || csReq.BearerContextsToBeCreated == nil {
+			t.Error("message is broken")
+		}
+	})
+
+	t.Run("Grouped", func(t *testing.T) {
+		csReq := newEditedCSReq()
+		want := paths(t, csReq)
+
+		if err := message.AppendIE(
+			csReq, "BearerContext[0]", ie.New(0xfa, 0x00, make([]byte, 0xfff0)),
+		); !errors.Is(err, message.ErrInvalidLength) {
+			t.Fatalf("got %v, want %v", err, message.ErrInvalidLength)
+		}
+		if diff := cmp.Diff(paths(t, csReq), want); diff != "" {
+			t.Errorf("message is changed: %s", diff)
+		}
+	})
+
+	t.Run("Message", func(t *testing.T) {
+		msg := message.NewGeneric(message.MsgTypeEchoRequest, 0, 1, ie.New(0xfa, 0x00, make([]byte, 0x8000)))
+		if err := message.AppendIE(msg, "", ie.New(0xfa, 0x00, make([]byte, 0x8000))); !errors.Is(err, message.ErrInvalidLength) {
+			t.Fatalf("got %v, want %v", err, message.ErrInvalidLength)
+		}
+		if diff := cmp.Diff(paths(t, msg), []string{"250[0]"}); diff != "" {
+			t.Errorf("message is changed: %s", diff)
+		}
+	})
+}
@@ -10,4 +10,5 @@ import "errors"
 var (
 	ErrInvalidLength   = errors.New("length value is invalid")
 	ErrTooShortToParse = errors.New("too short to decode as GTP")
+	ErrInvalidPath     = errors.New("invalid path to IE")
 )
@@ -0,0 +1,143 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package message_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// The seed corpus is in testdata/fuzz, which is generated from the test vectors
+// in the other tests in this package. Parse calls all the other ParseXxx for the
+// typed messages by the message type in the input.
+
+func FuzzParse(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		m, err := message.Parse(b)
+		if err != nil {
+			return
+		}
+		_ = m.MessageTypeName()
+
+		// once serialized, the message should be decoded and serialized into
+		// the same bytes again.
+		b1, err := message.Marshal(m)
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", m, err)
+		}
+		m2, err := message.Parse(b1)
+		if err != nil {
+			t.Fatalf("failed to parse %x: %s", b1, err)
+		}
+		b2, err := message.Marshal(m2)
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", m2, err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("round trip mismatch:\n got: %x\nwant: %x", b2, b1)
+		}
+	})
+}
+
+func FuzzParseHeader(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		h, err := message.ParseHeader(b)
+		if err != nil {
+			return
+		}
+
+		b1, err := h.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", h, err)
+		}
+		h2, err := message.ParseHeader(b1)
+		if err != nil {
+			t.Fatalf("failed to parse %x: %s", b1, err)
+		}
+		b2, err := h2.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", h2, err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("round trip mismatch:\n got: %x\nwant: %x", b2, b1)
+		}
+	})
+}
+
+func FuzzParseGeneric(f *testing.F) {
+	f.Fuzz(func(t *testing.T, b []byte) {
+		g, err := message.ParseGeneric(b)
+		if err != nil {
+			return
+		}
+
+		b1, err := g.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", g, err)
+		}
+		g2, err := message.ParseGeneric(b1)
+		if err != nil {
+			t.Fatalf("failed to parse %x: %s", b1, err)
+		}
+		b2, err := g2.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", g2, err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("round trip mismatch:\n got: %x\nwant: %x", b2, b1)
+		}
+
+		// Ordered should serialize the message as it is, unless the length in the
+		// header exceeds the input. Generic ignores the payload shorter than an IE
+		// header, while Ordered does not.
+		if len(g.Header.Payload) < 2 {
+			return
+		}
+		o, err := message.ParseOrdered(b)
+		if err != nil {
+			t.Fatalf("failed to parse %x as Ordered: %s", b, err)
+		}
+		_ = o.MessageTypeName()
+		l := 4 + int(o.Length)
+		got, err := o.Marshal()
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %s", o, err)
+		}
+		if l <= len(b) && !bytes.Equal(got, b) {
+			t.Errorf("not identical:\n got: %x\nwant: %x", got, b)
+		}
+
+		// View should find the same IEs.
+		v, err := message.NewView(b)
+		if err != nil {
+			t.Fatalf("failed to parse %x as View: %s", b, err)
+		}
+		n := 0
+		it := v.IEs()
+		for it.Next() {
+			if i := it.IE(); i.IsGrouped() {
+				c := i.ChildIEs()
+				for c.Next() {
+					_ = c.IE().Type()
+				}
+				if c.Err() != nil {
+					t.Errorf("failed to iterate over %x: %s", []byte(i), c.Err())
+				}
+			}
+			n++
+		}
+		if it.Err() != nil {
+			t.Fatalf("failed to iterate over %x: %s", b, it.Err())
+		}
+		if n != len(g.IEs) {
+			t.Errorf("got %d IEs, want %d", n, len(g.IEs))
+		}
+	})
+}
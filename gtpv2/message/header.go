@@ -90,7 +90,7 @@ func ParseHeader(b []byte) (*Header, error) {
 // UnmarshalBinary sets the values retrieved from byte sequence in GTPv2 header.
 func (h *Header) UnmarshalBinary(b []byte) error {
 	l := len(b)
-	if l < 12 {
+	if l < noTEIDHeaderSize {
 		return ErrTooShortToParse
 	}
 	h.Flags = b[0]
@@ -100,7 +100,7 @@ func (h *Header) UnmarshalBinary(b []byte) error {
 		return ErrTooShortToParse
 	}
 	if h.HasTEID() {
-		if h.Length < seqSpareSize+teidSize {
+		if l < teidHeaderSize || h.Length < seqSpareSize+teidSize {
 			return ErrTooShortToParse
 		}
 		h.TEID = binary.BigEndian.Uint32(b[4:8])
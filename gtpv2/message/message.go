@@ -300,8 +300,11 @@ func Marshal(m Message) ([]byte, error) {
 
 // Parse decodes the given bytes as Message.
 func Parse(b []byte) (Message, error) {
-	var m Message
+	if len(b) < fixedHeaderSize {
+		return nil, ErrTooShortToParse
+	}
 
+	var m Message
 	switch b[1] {
 	case MsgTypeEchoRequest:
 		m = &EchoRequest{}
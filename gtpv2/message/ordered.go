@@ -0,0 +1,219 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message
+
+import (
+	"io"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+)
+
+// Ordered is a GTPv2 message that keeps the IEs in the order on the wire.
+//
+// The typed messages like CreateSessionRequest serialize the IEs in the order of
+// their fields, and put the ones without fields(unknown instances, duplicated
+// ones, etc.) at the end in AdditionalIEs. That is fine for endpoints, but not for
+// proxies that should forward what they don't understand as it is.
+//
+// Ordered keeps the flags and spare bits in the header, the IEs of any type and
+// instance including the ones with Type Extension(254), the spare bits in the
+// instance octet, and the bytes after the message(e.g., piggybacked message) as
+// they are, so that the serialized message is byte-identical to the parsed one
+// unless it is modified. The IEs can be modified in place, replaced, inserted or
+// removed, and the lengths of the IEs, the grouped IEs that contain them and the
+// message are recomputed when serialized.
+type Ordered struct {
+	*Header
+	IEs []*ie.IE
+
+	// Trailer is the bytes that follow the message in the same datagram, which
+	// is typically a piggybacked message. It is serialized as it is.
+	Trailer []byte
+}
+
+// NewOrdered creates a new Ordered from any type of Message.
+func NewOrdered(m Message) (*Ordered, error) {
+	b, err := Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return ParseOrdered(b)
+}
+
+// ParseOrdered decodes a given byte sequence as an Ordered.
+//
+// The IEs and Trailer refer to b, so b should not be reused while the Ordered is in use.
+func ParseOrdered(b []byte) (*Ordered, error) {
+	o := &Ordered{}
+	if err := o.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// UnmarshalBinary decodes a given byte sequence as an Ordered.
+func (o *Ordered) UnmarshalBinary(b []byte) error {
+	var err error
+	o.Header, err = ParseHeader(b)
+	if err != nil {
+		return err
+	}
+
+	o.IEs, err = ie.ParseMultiIEs(o.Header.Payload)
+	if err != nil {
+		return err
+	}
+
+	o.Trailer = nil
+	if l := fixedHeaderSize + int(o.Header.Length); l < len(b) {
+		o.Trailer = b[l:]
+	}
+	return nil
+}
+
+// Marshal returns the byte sequence generated from an Ordered.
+func (o *Ordered) Marshal() ([]byte, error) {
+	b := make([]byte, o.MarshalLen())
+	if err := o.MarshalTo(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// MarshalTo puts the byte sequence in the byte array given as b.
+func (o *Ordered) MarshalTo(b []byte) error {
+	if len(b) < o.MarshalLen() {
+		return io.ErrUnexpectedEOF
+	}
+
+	o.SetLength()
+	l := o.MarshalLen() - len(o.Trailer)
+	o.Header.Payload = nil
+	o.Header.Payload = make([]byte, l-o.Header.MarshalLen())
+
+	offset := 0
+	for _, i := range o.IEs {
+		if i == nil {
+			continue
+		}
+		if err := i.MarshalTo(o.Header.Payload[offset:]); err != nil {
+			return err
+		}
+		offset += i.MarshalLen()
+	}
+
+	if err := o.Header.MarshalTo(b); err != nil {
+		return err
+	}
+	copy(b[o.Header.MarshalLen():], o.Trailer)
+	return nil
+}
+
+// MarshalLen returns the serial length of Ordered, including Trailer.
+func (o *Ordered) MarshalLen() int {
+	l := o.Header.MarshalLen() - len(o.Header.Payload)
+	for _, i := range o.IEs {
+		if i == nil {
+			continue
+		}
+		l += i.MarshalLen()
+	}
+
+	return l + len(o.Trailer)
+}
+
+// SetLength sets the length in Length field of the message and all the IEs in it.
+func (o *Ordered) SetLength() {
+	l := o.Header.MarshalLen() - len(o.Header.Payload) - fixedHeaderSize
+	for _, i := range o.IEs {
+		if i == nil {
+			continue
+		}
+		setLengths(i)
+		l += i.MarshalLen()
+	}
+	o.Header.Length = uint16(l)
+}
+
+// setLengths sets the length of the IE and its children recursively.
+func setLengths(i *ie.IE) {
+	if i.IsGrouped() {
+		for _, c := range i.ChildIEs {
+			if c == nil {
+				continue
+			}
+			setLengths(c)
+		}
+	}
+	i.SetLength()
+}
+
+// MessageTypeName returns the name of the message type.
+func (o *Ordered) MessageTypeName() string {
+	b := make([]byte, teidHeaderSize)
+	b[0], b[1], b[3] = 0x48, o.Header.Type, 0x08
+	m, err := Parse(b)
+	if err != nil {
+		return (&Generic{Header: o.Header}).MessageTypeName()
+	}
+	return m.MessageTypeName()
+}
+
+// TEID returns the TEID in uint32.
+func (o *Ordered) TEID() uint32 {
+	return o.Header.teid()
+}
+
+// Typed returns the typed Message decoded from the Ordered, e.g., *CreateSessionRequest.
+//
+// The returned Message is a copy, so that the changes made on it are not reflected
+// to the Ordered. Trailer is not included.
+func (o *Ordered) Typed() (Message, error) {
+	b, err := o.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b[:len(b)-len(o.Trailer)])
+}
+
+// FindByType returns the first IE looked up by type and instance.
+func (o *Ordered) FindByType(typ, instance uint8) (*ie.IE, error) {
+	for _, i := range o.IEs {
+		if i != nil && i.Type == typ && i.Instance() == instance {
+			return i, nil
+		}
+	}
+	return nil, ie.ErrIENotFound
+}
+
+// AddIE adds IEs at the end of Ordered.
+func (o *Ordered) AddIE(ies ...*ie.IE) {
+	o.IEs = append(o.IEs, ies...)
+}
+
+// Replace replaces the first IE that has the same type and instance as the given
+// one, keeping its position. The IE is added at the end if there's no such IE.
+func (o *Ordered) Replace(i *ie.IE) {
+	for idx, old := range o.IEs {
+		if old != nil && old.Type == i.Type && old.Instance() == i.Instance() {
+			o.IEs[idx] = i
+			return
+		}
+	}
+	o.AddIE(i)
+}
+
+// Remove removes all the IEs looked up by type and instance.
+func (o *Ordered) Remove(typ, instance uint8) {
+	ies := o.IEs[:0]
+	for _, i := range o.IEs {
+		if i != nil && i.Type == typ && i.Instance() == instance {
+			continue
+		}
+		ies = append(ies, i)
+	}
+	o.IEs = ies
+}
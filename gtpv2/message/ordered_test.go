@@ -0,0 +1,153 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+var (
+	// Create Session Request with a spare bit in flags and message priority.
+	orderedHeader = []byte{0x49, 0x20, 0x00, 0x3c, 0x11, 0x22, 0x33, 0x44, 0x00, 0x00, 0x01, 0x30}
+	orderedBefore = []byte{
+		// IMSI
+		0x01, 0x00, 0x08, 0x00, 0x21, 0x43, 0x65, 0x87, 0x09, 0x21, 0x43, 0xf5,
+		// unknown type
+		0xfa, 0x00, 0x02, 0x00, 0xde, 0xad,
+		// Recovery with unknown instance
+		0x03, 0x00, 0x01, 0x01, 0x05,
+	}
+	orderedEBI   = []byte{0x49, 0x00, 0x01, 0x00, 0x05}
+	orderedFTEID = []byte{
+		// F-TEID with instance 2 and a spare bit
+		0x57, 0x00, 0x09, 0x12, 0x84, 0x11, 0x22, 0x33, 0x44, 0x0a, 0x00, 0x00, 0x01,
+	}
+	orderedAfter = []byte{
+		// Type Extension
+		0xfe, 0x00, 0x03, 0x00, 0x01, 0x00, 0xaa,
+	}
+)
+
+func concat(bs ...[]byte) []byte {
+	var b []byte
+	for _, s := range bs {
+		b = append(b, s...)
+	}
+	return b
+}
+
+func TestOrdered(t *testing.T) {
+	serialized := concat(
+		orderedHeader, orderedBefore,
+		[]byte{0x5d, 0x00, 0x12, 0x00}, orderedEBI, orderedFTEID,
+		orderedAfter,
+	)
+
+	t.Run("Unmodified", func(t *testing.T) {
+		for _, b := range [][]byte{
+			serialized,
+			// piggybacked message follows.
+			concat(serialized, []byte{0x48, 0x01, 0x00, 0x04, 0x00, 0x00, 0x01, 0x00}),
+		} {
+			o, err := message.ParseOrdered(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := o.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got, b); diff != "" {
+				t.Error(diff)
+			}
+		}
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		o, err := message.ParseOrdered(serialized)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fteid := ie.NewFullyQualifiedTEID(0x04, 0x55667788, "10.0.0.2", "2001:db8::2").WithInstance(2)
+		bc, err := o.FindByType(ie.BearerContext, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bc.ChildIEs[1] = fteid
+
+		b, err := fteid.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := concat(
+			orderedHeader[:3], []byte{0x3c + 16}, orderedHeader[4:], orderedBefore,
+			[]byte{0x5d, 0x00, 0x12 + 16, 0x00}, orderedEBI, b,
+			orderedAfter,
+		)
+
+		got, err := o.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Error(diff)
+		}
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		o, err := message.ParseOrdered(serialized)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := o.MessageTypeName(), "Create Session Request"; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+
+		m, err := o.Typed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		csReq, ok := m.(*message.CreateSessionRequest)
+		if !ok {
+			t.Fatalf("got %T, want *message.CreateSessionRequest", m)
+		}
+		imsi, err := csReq.IMSI.IMSI()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imsi != "123456789012345" {
+			t.Errorf("got %s, want %s", imsi, "123456789012345")
+		}
+	})
+
+	t.Run("Replace and Remove", func(t *testing.T) {
+		o, err := message.ParseOrdered(serialized)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.Replace(ie.NewRecovery(6).WithInstance(1))
+		o.Remove(0xfa, 0)
+
+		want := concat(
+			orderedHeader[:3], []byte{0x3c - 6}, orderedHeader[4:], orderedBefore[:12],
+			[]byte{0x03, 0x00, 0x01, 0x01, 0x06},
+			[]byte{0x5d, 0x00, 0x12, 0x00}, orderedEBI, orderedFTEID,
+			orderedAfter,
+		)
+		got, err := o.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message
+
+import (
+	"sync"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+)
+
+// View is a read-only view of a GTPv2 message on the buffer it is decoded from.
+//
+// Parse allocates the message and all the IEs in it including the children of
+// grouped IEs, which is costly when only a few IEs are looked into in many messages.
+// View decodes only the header, and the IEs are iterated over the buffer with
+// ie.Iterator and decoded on demand, without allocations. View can be reused by
+// calling Reset with another buffer, or taken from the pool with AcquireView.
+//
+// The buffer must not be modified while the View is in use.
+type View struct {
+	Header
+	b []byte
+}
+
+var viewPool = sync.Pool{
+	New: func() interface{} {
+		return &View{}
+	},
+}
+
+// AcquireView returns a View of b from the pool. The View should be returned to
+// the pool with ReleaseView when it is no longer used.
+func AcquireView(b []byte) (*View, error) {
+	v := viewPool.Get().(*View)
+	if err := v.Reset(b); err != nil {
+		ReleaseView(v)
+		return nil, err
+	}
+	return v, nil
+}
+
+// ReleaseView returns a View acquired with AcquireView to the pool.
+// The View and the IEs retrieved from it must not be used after that.
+func ReleaseView(v *View) {
+	v.Header = Header{}
+	v.b = nil
+	viewPool.Put(v)
+}
+
+// NewView creates a new View of b.
+func NewView(b []byte) (*View, error) {
+	v := &View{}
+	if err := v.Reset(b); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Reset decodes the header in b and makes the View refer to b.
+func (v *View) Reset(b []byte) error {
+	if err := v.Header.UnmarshalBinary(b); err != nil {
+		v.b = nil
+		return err
+	}
+	v.b = b[:v.Header.MarshalLen()]
+	return nil
+}
+
+// Bytes returns the message in the buffer. The bytes after the message in the
+// buffer given to Reset(e.g., piggybacked message) are not included.
+func (v *View) Bytes() []byte {
+	return v.b
+}
+
+// TEID returns the TEID in uint32.
+func (v *View) TEID() uint32 {
+	return v.Header.teid()
+}
+
+// IEs returns the Iterator over the IEs in the message.
+func (v *View) IEs() ie.Iterator {
+	return ie.NewIterator(v.Header.Payload)
+}
+
+// FindByType returns the first IE looked up by type and instance.
+func (v *View) FindByType(typ, instance uint8) (ie.View, error) {
+	it := v.IEs()
+	return it.FindByType(typ, instance)
+}
+
+// Message decodes the whole message in the View as the typed Message.
+func (v *View) Message() (Message, error) {
+	return Parse(v.b)
+}
@@ -0,0 +1,176 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package message_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func mustMarshalCSReq(t testing.TB) []byte {
+	t.Helper()
+
+	b, err := message.Marshal(message.NewCreateSessionRequest(
+		0x11223344, 1,
+		ie.NewIMSI("123451234567890"),
+		ie.NewMSISDN("123450123456789"),
+		ie.NewAccessPointName("some.apn.example"),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11MMEGTPC, 0xffffffff, "1.1.1.1", ""),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPC, 0xffffffff, "1.1.1.2", "").WithInstance(1),
+		ie.NewPDNType(gtpv2.PDNTypeIPv4),
+		ie.NewAggregateMaximumBitRate(0x11111111, 0x22222222),
+		ie.NewBearerContext(
+			ie.NewEPSBearerID(0x05),
+			ie.NewBearerQoS(1, 2, 1, 0xff, 0x1111111111, 0x2222222222, 0x1111111111, 0x2222222222),
+		),
+		ie.NewMobileEquipmentIdentity("123450123456789"),
+		ie.NewServingNetwork("123", "45"),
+		ie.NewPDNAddressAllocation("2.2.2.2"),
+		ie.NewUserLocationInformationLazy(
+			"123", "45",
+			-1, -1, -1, -1, 0x0001, 0x00000101, -1, -1,
+		),
+		ie.NewRATType(gtpv2.RATTypeEUTRAN),
+		ie.NewSelectionMode(gtpv2.SelectionModeMSorNetworkProvidedAPNSubscribedVerified),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestView(t *testing.T) {
+	b := mustMarshalCSReq(t)
+
+	v, err := message.NewView(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.MessageType() != message.MsgTypeCreateSessionRequest || v.TEID() != 0x11223344 || v.Sequence() != 1 {
+		t.Errorf("unexpected header: %s", &v.Header)
+	}
+
+	g, err := message.ParseGeneric(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := v.IEs()
+	n := 0
+	for it.Next() {
+		want, err := g.IEs[n].Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := it.IE(); !bytes.Equal(got, want) {
+			t.Errorf("IE #%d: got %x, want %x", n, []byte(got), want)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(g.IEs) {
+		t.Errorf("got %d IEs, want %d", n, len(g.IEs))
+	}
+
+	bc, err := v.FindByType(ie.BearerContext, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ebi, err := bc.FindByType(ie.EPSBearerID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ebi.Payload(); !bytes.Equal(got, []byte{0x05}) {
+		t.Errorf("got %x, want 05", got)
+	}
+
+	fteid, err := v.FindByType(ie.FullyQualifiedTEID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := fteid.IE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip, err := i.IPv4(); err != nil || ip.String() != "1.1.1.2" {
+		t.Errorf("got %v, %v, want 1.1.1.2", ip, err)
+	}
+
+	if _, err := v.FindByType(ie.Recovery, 0); err != ie.ErrIENotFound {
+		t.Errorf("got %v, want %v", err, ie.ErrIENotFound)
+	}
+
+	// truncated in the middle of the last IE.
+	v, err = message.NewView(b[:len(b)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.FindByType(ie.Recovery, 0); err != ie.ErrInvalidLength {
+		t.Errorf("got %v, want %v", err, ie.ErrInvalidLength)
+	}
+}
+
+func TestViewAllocs(t *testing.T) {
+	b := mustMarshalCSReq(t)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		v, err := message.AcquireView(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer message.ReleaseView(v)
+
+		bc, err := v.FindByType(ie.BearerContext, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.FindByType(ie.EPSBearerID, 0); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocs, want 0", allocs)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	buf := mustMarshalCSReq(b)
+
+	b.Run("Parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m, err := message.Parse(buf)
+			if err != nil {
+				b.Fatal(err)
+			}
+			csReq := m.(*message.CreateSessionRequest)
+			if _, err := csReq.BearerContextsToBeCreated.FindByType(ie.EPSBearerID, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("View", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			v, err := message.AcquireView(buf)
+			if err != nil {
+				b.Fatal(err)
+			}
+			bc, err := v.FindByType(ie.BearerContext, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := bc.FindByType(ie.EPSBearerID, 0); err != nil {
+				b.Fatal(err)
+			}
+			message.ReleaseView(v)
+		}
+	})
+}
@@ -0,0 +1,346 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package mutator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// maxMessageSize is the maximum size of a message that fits in a UDP datagram
+// over IPv4.
+const maxMessageSize = 65507
+
+// mutateFunc mutates the message given as Ordered, which is a copy and can be
+// modified freely. It returns ErrNotApplicable if the message has nothing to be
+// mutated in the way.
+type mutateFunc func(r *rand.Rand, o *message.Ordered) (*Mutation, error)
+
+var mutators = map[Kind]mutateFunc{
+	DropIE:             dropIE,
+	DuplicateIE:        duplicateIE,
+	ReorderIEs:         reorderIEs,
+	InvalidLength:      invalidLength,
+	OutOfRangeValue:    outOfRangeValue,
+	WrongInstance:      wrongInstance,
+	OversizedGroupedIE: oversizedGroupedIE,
+	FlipHeaderFlags:    flipHeaderFlags,
+}
+
+func dropIE(r *rand.Rand, o *message.Ordered) (*Mutation, error) {
+	p, err := pickIE(r, o, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := message.DeleteIE(o, p.Path); err != nil {
+		return nil, err
+	}
+	return marshal(o, &Mutation{Path: p.Path, Description: "removed"})
+}
+
+func duplicateIE(r *rand.Rand, o *message.Ordered) (*Mutation, error) {
+	p, err := pickIE(r, o, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := clone(p.IE)
+	if err != nil {
+		return nil, err
+	}
+	if err := message.InsertIE(o, p.Path, c); err != nil {
+		return nil, err
+	}
+	return marshal(o, &Mutation{Path: p.Path, Description: "inserted a copy before it"})
+}
+
+func reorderIEs(r *rand.Rand, o *message.Ordered) (*Mutation, error) {
+	list, err := message.ListIEs(o)
+	if err != nil {
+		return nil, err
+	}
+
+	type swap struct {
+		path string
+		ies  []*ie.IE
+		i, j int
+	}
+	var swaps []*swap
+	add := func(path string, ies []*ie.IE) {
+		// swapping the IEs of the same type and instance makes no difference
+		// for the peer that looks them up by type and instance.
+		for i := 0; i < len(ies); i++ {
+			for j := i + 1; j < len(ies); j++ {
+				if ies[i].Type != ies[j].Type || ies[i].Instance() != ies[j].Instance() {
+					swaps = append(swaps, &swap{path, ies, i, j})
+				}
+			}
+		}
+	}
+	add("", o.IEs)
+	for _, p := range list {
+		if p.IE.IsGrouped() {
+			add(p.Path, p.IE.ChildIEs)
+		}
+	}
+	if len(swaps) == 0 {
+		return nil, ErrNotApplicable
+	}
+
+	s := swaps[r.Intn(len(swaps))]
+	a, b := s.ies[s.i], s.ies[s.j]
+	s.ies[s.i], s.ies[s.j] = b, a
+	return marshal(o, &Mutation{
+		Path: s.path,
+		Description: fmt.Sprintf(
+			"swapped %s[%d] at %d and %s[%d] at %d",
+			a.Name(), a.Instance(), s.i, b.Name(), b.Instance(), s.j,
+		),
+	})
+}
+
+func invalidLength(r *rand.Rand, o *message.Ordered) (*Mutation, error) {
+	list, err := message.ListIEs(o)
+	if err != nil {
+		return nil, err
+	}
+	b, err := o.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// the lengths are overwritten in the serialized message, as the IEs and
+	// message with the wrong length cannot be serialized as they are.
+	offsets := map[*ie.IE]int{}
+	var walk func(offset int, ies []*ie.IE)
+	walk = func(offset int, ies []*ie.IE) {
+		for _, i := range ies {
+			offsets[i] = offset
+			if i.IsGrouped() {
+				walk(offset+4, i.ChildIEs)
+			}
+			offset += i.MarshalLen()
+		}
+	}
+	offset := 8
+	if o.HasTEID() {
+		offset = 12
+	}
+	walk(offset, o.IEs)
+
+	// the length of the message is also chosen as if it is an IE.
+	mut := &Mutation{}
+	offset, cur := 2, int(o.Length)
+	if n := r.Intn(len(list) + 1); n < len(list) {
+		p := list[n]
+		mut.Path = p.Path
+		offset, cur = offsets[p.IE]+1, int(p.IE.Length)
+	}
+
+	l := wrongLength(r, cur)
+	binary.BigEndian.PutUint16(b[offset:offset+2], l)
+	mut.Description = fmt.Sprintf("length %d -> %d", cur, l)
+	mut.Data = b
+	return mut, nil
+}
+
+// wrongLength returns a length different from cur, which is likely to be mishandled.
+func wrongLength(r *rand.Rand, cur int) uint16 {
+	for {
+		candidates := []int{0, cur - 1, cur + 1, 0xffff, r.Intn(0x10000)}
+		l := candidates[r.Intn(len(candidates))]
+		if l != cur && l >= 0 && l <= 0xffff {
+			return uint16(l)
+		}
+	}
+}
+
+// outOfRange is the list of the IEs that have an enumerated value in the first
+// octet, with the function that sets the value not defined in the spec.
+var outOfRange = map[uint8]func(r *rand.Rand, b byte) byte{
+	ie.Cause: func(r *rand.Rand, b byte) byte {
+		return outside(r, int(gtpv2.CauseLocalDetach), int(gtpv2.CauseS1UPathFailure), 0xff)
+	},
+	ie.RATType: func(r *rand.Rand, b byte) byte {
+		return outside(r, int(gtpv2.RATTypeUTRAN), int(gtpv2.RATTypeNR), 0xff)
+	},
+	ie.PDNType: func(r *rand.Rand, b byte) byte {
+		return b&^0x07 | outside(r, int(gtpv2.PDNTypeIPv4), int(gtpv2.PDNTypeNonIP), 0x07)
+	},
+	ie.PDNAddressAllocation: func(r *rand.Rand, b byte) byte {
+		return b&^0x07 | outside(r, int(gtpv2.PDNTypeIPv4), int(gtpv2.PDNTypeNonIP), 0x07)
+	},
+	ie.EPSBearerID: func(r *rand.Rand, b byte) byte {
+		// 0-4 are reserved.
+		return b&^0x0f | outside(r, 5, 15, 0x0f)
+	},
+	ie.FullyQualifiedTEID: func(r *rand.Rand, b byte) byte {
+		return b&^0x3f | outside(r, int(gtpv2.IFTypeS1UeNodeBGTPU), int(gtpv2.IFTypeS11SGWGTPU), 0x3f)
+	},
+	ie.NodeType: func(r *rand.Rand, b byte) byte {
+		return outside(r, int(gtpv2.NodeTypeSGSN), int(gtpv2.NodeTypeMME), 0xff)
+	},
+	ie.APNRestriction: func(r *rand.Rand, b byte) byte {
+		return outside(r, int(gtpv2.APNRestrictionNoExistingContextsorRestriction), int(gtpv2.APNRestrictionPrivate2), 0xff)
+	},
+}
+
+// outside returns a random value in [0, max] that is not in [lo, hi].
+func outside(r *rand.Rand, lo, hi, max int) byte {
+	v := r.Intn(max - hi + lo)
+	if v >= lo {
+		v += hi - lo + 1
+	}
+	return byte(v)
+}
+
+func outOfRangeValue(r *rand.Rand, o *message.Ordered) (*Mutation, error) {
+	p, err := pickIE(r, o, func(i *ie.IE) bool {
+		_, ok := outOfRange[i.Type]
+		return ok && len(i.Payload) > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	old := p.IE.Payload[0]
+	p.IE.Payload[0] = outOfRange[p.IE.Type](r, old)
+	return marshal(o, &Mutation{
+		Path:        p.Path,
+		Description: fmt.Sprintf("first octet %#02x -> %#02x", old, p.IE.Payload[0]),
+	})
+}
+
+func wrongInstance(r *rand.Rand, o *message.Ordered) (*Mutation, error) {
+	p, err := pickIE(r, o, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	old := p.IE.Instance()
+	p.IE.SetInstance(old + 1 + uint8(r.Intn(15)))
+	return marshal(o, &Mutation{
+		Path:        p.Path,
+		Description: fmt.Sprintf("instance %d -> %d", old, p.IE.Instance()),
+	})
+}
+
+func oversizedGroupedIE(r *rand.Rand, o *message.Ordered) (*Mutation, error) {
+	p, err := pickIE(r, o, func(i *ie.IE) bool {
+		return i.IsGrouped()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the IE header and the Enterprise ID of the filler.
+	const fillerHeaderSize = 6
+
+	room := maxMessageSize - (o.MarshalLen() - len(o.Trailer))
+	if room < fillerHeaderSize {
+		return nil, ErrNotApplicable
+	}
+	grow := room/2 + r.Intn(room/2+1)
+
+	before := p.IE.MarshalLen()
+	children := make([]*ie.IE, len(p.IE.ChildIEs))
+	copy(children, p.IE.ChildIEs)
+	for n := 0; len(children) > 0; n++ {
+		c, err := clone(children[n%len(children)])
+		if err != nil {
+			return nil, err
+		}
+		if c.MarshalLen() > grow {
+			break
+		}
+		p.IE.ChildIEs = append(p.IE.ChildIEs, c)
+		grow -= c.MarshalLen()
+	}
+	if grow >= fillerHeaderSize {
+		filler := make([]byte, grow-fillerHeaderSize)
+		_, _ = r.Read(filler)
+		p.IE.ChildIEs = append(p.IE.ChildIEs, ie.NewPrivateExtension(uint16(r.Intn(0x10000)), filler))
+	}
+
+	return marshal(o, &Mutation{
+		Path:        p.Path,
+		Description: fmt.Sprintf("length %d -> %d", before-4, p.IE.MarshalLen()-4),
+	})
+}
+
+var headerFlags = []struct {
+	mask uint8
+	name string
+}{
+	{0xe0, "version"},
+	{0x10, "piggybacking flag"},
+	{0x08, "TEID flag"},
+	{0x04, "message priority flag"},
+	{0x03, "spare bits"},
+}
+
+func flipHeaderFlags(r *rand.Rand, o *message.Ordered) (*Mutation, error) {
+	b, err := o.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	f := headerFlags[r.Intn(len(headerFlags))]
+	old := b[0]
+	if f.mask == 0xe0 {
+		v := (old>>5 + 1 + uint8(r.Intn(7))) & 0x07
+		b[0] = old&^f.mask | v<<5
+	} else {
+		b[0] ^= f.mask
+	}
+
+	return &Mutation{
+		Description: fmt.Sprintf("%s: flags %#02x -> %#02x", f.name, old, b[0]),
+		Data:        b,
+	}, nil
+}
+
+// pickIE picks an IE randomly from the ones that fn returns true, including the
+// children of grouped IEs. All the IEs are the candidates if fn is nil.
+func pickIE(r *rand.Rand, o *message.Ordered, fn func(*ie.IE) bool) (*message.PathIE, error) {
+	list, err := message.ListIEs(o)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*message.PathIE
+	for _, p := range list {
+		if fn == nil || fn(p.IE) {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNotApplicable
+	}
+	return candidates[r.Intn(len(candidates))], nil
+}
+
+func clone(i *ie.IE) (*ie.IE, error) {
+	b, err := i.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return ie.Parse(b)
+}
+
+func marshal(o *message.Ordered, mut *Mutation) (*Mutation, error) {
+	b, err := o.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	mut.Data = b
+	return mut, nil
+}
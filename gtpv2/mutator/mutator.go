@@ -0,0 +1,179 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package mutator provides the mutation engine that generates malformed GTPv2
+messages from valid ones, to test the robustness of the peer implementations.
+
+The mutations are structured, i.e., they are aware of the header and IEs, so that
+the peer receives the messages that are mostly valid but broken in a specific way,
+e.g., a missing or duplicated IE, a wrong length or an undefined value.
+
+The mutations are generated with a pseudo-random number generator with the seed
+given, so that the same sequence of mutations is generated from the same seed
+and messages, and each of them can be reproduced later with Replay.
+*/
+package mutator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// Kind is the kind of the mutation.
+type Kind uint8
+
+// Kind definitions.
+const (
+	// DropIE removes an IE from the message or a grouped IE.
+	DropIE Kind = iota
+	// DuplicateIE puts a copy of an IE next to it.
+	DuplicateIE
+	// ReorderIEs swaps two IEs in the message or a grouped IE.
+	ReorderIEs
+	// InvalidLength sets a wrong value in the length field of an IE or the message.
+	InvalidLength
+	// OutOfRangeValue sets an undefined value in an IE with enumerated values,
+	// e.g., Cause, RAT Type, PDN Type or the interface type in F-TEID.
+	OutOfRangeValue
+	// WrongInstance changes the instance of an IE.
+	WrongInstance
+	// OversizedGroupedIE grows a grouped IE with the copies of its children and
+	// filler, up to the maximum size of a message.
+	OversizedGroupedIE
+	// FlipHeaderFlags flips the flags or changes the version in the header.
+	FlipHeaderFlags
+
+	numKinds
+)
+
+var kindNames = [...]string{
+	DropIE:             "DropIE",
+	DuplicateIE:        "DuplicateIE",
+	ReorderIEs:         "ReorderIEs",
+	InvalidLength:      "InvalidLength",
+	OutOfRangeValue:    "OutOfRangeValue",
+	WrongInstance:      "WrongInstance",
+	OversizedGroupedIE: "OversizedGroupedIE",
+	FlipHeaderFlags:    "FlipHeaderFlags",
+}
+
+// String returns the name of Kind.
+func (k Kind) String() string {
+	if k >= numKinds {
+		return fmt.Sprintf("Kind(%d)", uint8(k))
+	}
+	return kindNames[k]
+}
+
+// ErrNotApplicable indicates that none of the mutations can be applied to the
+// message, e.g., ReorderIEs to a message with only one IE.
+var ErrNotApplicable = errors.New("no mutation applicable to the message")
+
+// Mutation is a mutated message.
+type Mutation struct {
+	Kind Kind
+
+	// Seed is the seed used for the mutation, which can be given to Replay to
+	// reproduce it.
+	Seed int64
+
+	// Path is the path to the IE mutated, in the same format as the one in
+	// gtpv2/message package, e.g., "BearerContext[0]/EPSBearerID[0]". For the
+	// mutations on the list of IEs(DropIE, DuplicateIE and ReorderIEs), it is
+	// the IE removed or duplicated, or the grouped IE that contains the IEs
+	// reordered. It is empty for the mutations on the header or the top level.
+	Path string
+
+	// Description describes what is changed in human readable form.
+	Description string
+
+	// Data is the serialized message mutated, which is ready to be sent to the
+	// peer, e.g., with (*gtpv2.Conn).WriteTo.
+	Data []byte
+}
+
+// String returns the Mutation in human readable form.
+func (m *Mutation) String() string {
+	if m.Path == "" {
+		return fmt.Sprintf("%s(seed: %d): %s", m.Kind, m.Seed, m.Description)
+	}
+	return fmt.Sprintf("%s(seed: %d) at %s: %s", m.Kind, m.Seed, m.Path, m.Description)
+}
+
+// Mutator generates the mutations of messages.
+//
+// Mutator is not safe for concurrent use.
+type Mutator struct {
+	rand  *rand.Rand
+	kinds []Kind
+}
+
+// New creates a new Mutator with the seed and the kinds of the mutation to be
+// generated. All the kinds are generated if none is given.
+func New(seed int64, kinds ...Kind) *Mutator {
+	if len(kinds) == 0 {
+		for k := Kind(0); k < numKinds; k++ {
+			kinds = append(kinds, k)
+		}
+	}
+
+	return &Mutator{
+		rand:  rand.New(rand.NewSource(seed)),
+		kinds: kinds,
+	}
+}
+
+// Mutate generates a mutation of the message with the kind chosen randomly from
+// the ones applicable to the message.
+//
+// The message is not modified. It returns ErrNotApplicable if none of the kinds
+// given to New can be applied to the message.
+func (m *Mutator) Mutate(msg message.Message) (*Mutation, error) {
+	return m.Replay(msg, m.rand.Int63())
+}
+
+// Replay generates the mutation of the message with the seed, which is the one
+// in Mutation generated by Mutate with the same kinds and message.
+//
+// Replay does not affect the mutations generated by Mutate afterwards.
+func (m *Mutator) Replay(msg message.Message, seed int64) (*Mutation, error) {
+	r := rand.New(rand.NewSource(seed))
+
+	kinds := make([]Kind, len(m.kinds))
+	copy(kinds, m.kinds)
+	r.Shuffle(len(kinds), func(i, j int) {
+		kinds[i], kinds[j] = kinds[j], kinds[i]
+	})
+
+	for _, k := range kinds {
+		fn, ok := mutators[k]
+		if !ok {
+			continue
+		}
+
+		// the message is copied for every try, as it may be modified partially
+		// even if the mutation turns out to be not applicable.
+		o, err := message.NewOrdered(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		mut, err := fn(r, o)
+		if err != nil {
+			if errors.Is(err, ErrNotApplicable) {
+				continue
+			}
+			return nil, err
+		}
+		mut.Kind = k
+		mut.Seed = seed
+		return mut, nil
+	}
+
+	return nil, ErrNotApplicable
+}
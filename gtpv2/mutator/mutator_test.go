@@ -0,0 +1,198 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package mutator_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+	"github.com/wmnsk/go-gtp/gtpv2/mutator"
+)
+
+func newCSReq() *message.CreateSessionRequest {
+	return message.NewCreateSessionRequest(
+		0x11223344, 1,
+		ie.NewIMSI("123451234567890"),
+		ie.NewRATType(gtpv2.RATTypeEUTRAN),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11MMEGTPC, 0x11111111, "1.1.1.1", ""),
+		ie.NewAccessPointName("some.apn.example"),
+		ie.NewPDNType(gtpv2.PDNTypeIPv4),
+		ie.NewBearerContext(
+			ie.NewEPSBearerID(0x05),
+			ie.NewBearerQoS(1, 2, 1, 0xff, 0x1111111111, 0x2222222222, 0x1111111111, 0x2222222222),
+		),
+	)
+}
+
+func countIEs(t *testing.T, b []byte) int {
+	t.Helper()
+
+	o, err := message.ParseOrdered(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := message.ListIEs(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(list)
+}
+
+func TestMutate(t *testing.T) {
+	orig, err := message.Marshal(newCSReq())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := countIEs(t, orig)
+
+	cases := []struct {
+		kind  mutator.Kind
+		check func(t *testing.T, mut *mutator.Mutation)
+	}{
+		{
+			mutator.DropIE,
+			func(t *testing.T, mut *mutator.Mutation) {
+				if got := countIEs(t, mut.Data); got >= n {
+					t.Errorf("got %d IEs, want less than %d", got, n)
+				}
+			},
+		}, {
+			mutator.DuplicateIE,
+			func(t *testing.T, mut *mutator.Mutation) {
+				if got := countIEs(t, mut.Data); got <= n {
+					t.Errorf("got %d IEs, want more than %d", got, n)
+				}
+			},
+		}, {
+			mutator.ReorderIEs,
+			func(t *testing.T, mut *mutator.Mutation) {
+				if got := countIEs(t, mut.Data); got != n {
+					t.Errorf("got %d IEs, want %d", got, n)
+				}
+			},
+		}, {
+			mutator.InvalidLength,
+			func(t *testing.T, mut *mutator.Mutation) {
+				if len(mut.Data) != len(orig) {
+					t.Errorf("got %d bytes, want %d", len(mut.Data), len(orig))
+				}
+			},
+		}, {
+			mutator.OutOfRangeValue,
+			func(t *testing.T, mut *mutator.Mutation) {
+				if len(mut.Data) != len(orig) {
+					t.Errorf("got %d bytes, want %d", len(mut.Data), len(orig))
+				}
+			},
+		}, {
+			mutator.WrongInstance,
+			func(t *testing.T, mut *mutator.Mutation) {
+				if got := countIEs(t, mut.Data); got != n {
+					t.Errorf("got %d IEs, want %d", got, n)
+				}
+			},
+		}, {
+			mutator.OversizedGroupedIE,
+			func(t *testing.T, mut *mutator.Mutation) {
+				if mut.Path != "BearerContext[0]" {
+					t.Errorf("got %s, want BearerContext[0]", mut.Path)
+				}
+				if len(mut.Data) <= 0xffff/2 {
+					t.Errorf("got %d bytes, want more than %d", len(mut.Data), 0xffff/2)
+				}
+				if _, err := message.ParseOrdered(mut.Data); err != nil {
+					t.Error(err)
+				}
+			},
+		}, {
+			mutator.FlipHeaderFlags,
+			func(t *testing.T, mut *mutator.Mutation) {
+				if !bytes.Equal(mut.Data[1:], orig[1:]) {
+					t.Errorf("got %x, want only the first octet changed from %x", mut.Data, orig)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.kind.String(), func(t *testing.T) {
+			m := mutator.New(1, c.kind)
+			for i := 0; i < 20; i++ {
+				csReq := newCSReq()
+				mut, err := m.Mutate(csReq)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if mut.Kind != c.kind {
+					t.Errorf("got %s, want %s", mut.Kind, c.kind)
+				}
+				if bytes.Equal(mut.Data, orig) {
+					t.Errorf("not mutated: %s", mut)
+				}
+				c.check(t, mut)
+
+				// the original message should be kept as it is.
+				b, err := message.Marshal(csReq)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(b, orig); diff != "" {
+					t.Error(diff)
+				}
+			}
+		})
+	}
+}
+
+func TestReproducible(t *testing.T) {
+	csReq := newCSReq()
+	m1, m2 := mutator.New(42), mutator.New(42)
+	for i := 0; i < 20; i++ {
+		mut1, err := m1.Mutate(csReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mut2, err := m2.Mutate(csReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(mut1, mut2); diff != "" {
+			t.Fatal(diff)
+		}
+
+		replayed, err := mutator.New(0).Replay(csReq, mut1.Seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(replayed, mut1); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+}
+
+func TestNotApplicable(t *testing.T) {
+	echo := message.NewEchoRequest(1, ie.NewRecovery(1))
+
+	m := mutator.New(1, mutator.ReorderIEs, mutator.OutOfRangeValue, mutator.OversizedGroupedIE)
+	if _, err := m.Mutate(echo); !errors.Is(err, mutator.ErrNotApplicable) {
+		t.Errorf("got %v, want %v", err, mutator.ErrNotApplicable)
+	}
+
+	// the applicable one is chosen.
+	m = mutator.New(1, mutator.ReorderIEs, mutator.WrongInstance)
+	mut, err := m.Mutate(echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mut.Kind != mutator.WrongInstance {
+		t.Errorf("got %s, want %s", mut.Kind, mutator.WrongInstance)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"net"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// responseTypes is the types of the triggered messages, keyed by the types of
+// the initial messages that trigger them.
+var responseTypes = map[uint8]uint8{
+	message.MsgTypeDirectTransferRequest:                     message.MsgTypeDirectTransferResponse,
+	message.MsgTypeNotificationRequest:                       message.MsgTypeNotificationResponse,
+	message.MsgTypeSRVCCPsToCsRequest:                        message.MsgTypeSRVCCPsToCsResponse,
+	message.MsgTypeSRVCCPsToCsCompleteNotification:           message.MsgTypeSRVCCPsToCsCompleteAcknowledge,
+	message.MsgTypeSRVCCPsToCsCancelNotification:             message.MsgTypeSRVCCPsToCsCancelAcknowledge,
+	message.MsgTypeSRVCCCsToPsRequest:                        message.MsgTypeSRVCCCsToPsResponse,
+	message.MsgTypeCreateSessionRequest:                      message.MsgTypeCreateSessionResponse,
+	message.MsgTypeModifyBearerRequest:                       message.MsgTypeModifyBearerResponse,
+	message.MsgTypeDeleteSessionRequest:                      message.MsgTypeDeleteSessionResponse,
+	message.MsgTypeChangeNotificationRequest:                 message.MsgTypeChangeNotificationResponse,
+	message.MsgTypeRemoteUEReportNotification:                message.MsgTypeRemoteUEReportAcknowledge,
+	message.MsgTypeModifyBearerCommand:                       message.MsgTypeModifyBearerFailureIndication,
+	message.MsgTypeDeleteBearerCommand:                       message.MsgTypeDeleteBearerFailureIndication,
+	message.MsgTypeBearerResourceCommand:                     message.MsgTypeBearerResourceFailureIndication,
+	message.MsgTypeCreateBearerRequest:                       message.MsgTypeCreateBearerResponse,
+	message.MsgTypeUpdateBearerRequest:                       message.MsgTypeUpdateBearerResponse,
+	message.MsgTypeDeleteBearerRequest:                       message.MsgTypeDeleteBearerResponse,
+	message.MsgTypeDeletePDNConnectionSetRequest:             message.MsgTypeDeletePDNConnectionSetResponse,
+	message.MsgTypePGWDownlinkTriggeringNotification:         message.MsgTypePGWDownlinkTriggeringAcknowledge,
+	message.MsgTypeIdentificationRequest:                     message.MsgTypeIdentificationResponse,
+	message.MsgTypeContextRequest:                            message.MsgTypeContextResponse,
+	message.MsgTypeForwardRelocationRequest:                  message.MsgTypeForwardRelocationResponse,
+	message.MsgTypeForwardRelocationCompleteNotification:     message.MsgTypeForwardRelocationCompleteAcknowledge,
+	message.MsgTypeForwardAccessContextNotification:          message.MsgTypeForwardAccessContextAcknowledge,
+	message.MsgTypeRelocationCancelRequest:                   message.MsgTypeRelocationCancelResponse,
+	message.MsgTypeDetachNotification:                        message.MsgTypeDetachAcknowledge,
+	message.MsgTypeAlertMMENotification:                      message.MsgTypeAlertMMEAcknowledge,
+	message.MsgTypeUEActivityNotification:                    message.MsgTypeUEActivityAcknowledge,
+	message.MsgTypeUERegistrationQueryRequest:                message.MsgTypeUERegistrationQueryResponse,
+	message.MsgTypeCreateForwardingTunnelRequest:             message.MsgTypeCreateForwardingTunnelResponse,
+	message.MsgTypeSuspendNotification:                       message.MsgTypeSuspendAcknowledge,
+	message.MsgTypeResumeNotification:                        message.MsgTypeResumeAcknowledge,
+	message.MsgTypeCreateIndirectDataForwardingTunnelRequest: message.MsgTypeCreateIndirectDataForwardingTunnelResponse,
+	message.MsgTypeDeleteIndirectDataForwardingTunnelRequest: message.MsgTypeDeleteIndirectDataForwardingTunnelResponse,
+	message.MsgTypeReleaseAccessBearersRequest:               message.MsgTypeReleaseAccessBearersResponse,
+	message.MsgTypeDownlinkDataNotification:                  message.MsgTypeDownlinkDataNotificationAcknowledge,
+	message.MsgTypePGWRestartNotification:                    message.MsgTypePGWRestartNotificationAcknowledge,
+	message.MsgTypeUpdatePDNConnectionSetRequest:             message.MsgTypeUpdatePDNConnectionSetResponse,
+	message.MsgTypeModifyAccessBearersRequest:                message.MsgTypeModifyAccessBearersResponse,
+	message.MsgTypeMBMSSessionStartRequest:                   message.MsgTypeMBMSSessionStartResponse,
+	message.MsgTypeMBMSSessionUpdateRequest:                  message.MsgTypeMBMSSessionUpdateResponse,
+	message.MsgTypeMBMSSessionStopRequest:                    message.MsgTypeMBMSSessionStopResponse,
+	message.MsgTypeSRVCCCsToPsCompleteNotification:           message.MsgTypeSRVCCCsToPsCompleteAcknowledge,
+	message.MsgTypeSRVCCCsToPsCancelNotification:             message.MsgTypeSRVCCCsToPsCancelAcknowledge,
+}
+
+// RejectRequest responds to the request received with the response message that
+// contains the Cause IE built from causeErr.
+//
+// This is called automatically when HandlerFunc returns *CauseError. It returns
+// *UnexpectedTypeError if received is not an initial message that has the response.
+func (c *Conn) RejectRequest(raddr net.Addr, received message.Message, causeErr *CauseError) error {
+	resType, ok := responseTypes[received.MessageType()]
+	if !ok {
+		return &UnexpectedTypeError{Msg: received}
+	}
+
+	teid := causeErr.TEID
+	if teid == 0 {
+		teid = c.responseTEID(raddr, received)
+	}
+
+	cause := ie.NewCause(causeErr.Cause, 0, 0, 0, causeErr.OffendingIE)
+	res := newResponse(resType, teid, cause)
+	if err := c.lint(res); err != nil {
+		return err
+	}
+
+	// the state is reverted only if the request has changed it, as the one rejected
+	// due to the state should not affect the pending procedure.
+	if sess := c.respondingSession(raddr, received, res); sess != nil && sess.transitioned(received) {
+		if err := sess.Transition(res); err != nil {
+			return err
+		}
+	}
+	return c.writeResponse(raddr, received, res)
+}
+
+// newResponse creates the message of msgType. The typed message is used if available,
+// otherwise it is created as Generic.
+func newResponse(msgType uint8, teid uint32, ies ...*ie.IE) message.Message {
+	switch msgType {
+	case message.MsgTypeCreateSessionResponse:
+		return message.NewCreateSessionResponse(teid, 0, ies...)
+	case message.MsgTypeModifyBearerResponse:
+		return message.NewModifyBearerResponse(teid, 0, ies...)
+	case message.MsgTypeDeleteSessionResponse:
+		return message.NewDeleteSessionResponse(teid, 0, ies...)
+	case message.MsgTypeModifyBearerFailureIndication:
+		return message.NewModifyBearerFailureIndication(teid, 0, ies...)
+	case message.MsgTypeDeleteBearerFailureIndication:
+		return message.NewDeleteBearerFailureIndication(teid, 0, ies...)
+	case message.MsgTypeCreateBearerResponse:
+		return message.NewCreateBearerResponse(teid, 0, ies...)
+	case message.MsgTypeUpdateBearerResponse:
+		return message.NewUpdateBearerResponse(teid, 0, ies...)
+	case message.MsgTypeDeleteBearerResponse:
+		return message.NewDeleteBearerResponse(teid, 0, ies...)
+	case message.MsgTypeDeletePDNConnectionSetResponse:
+		return message.NewDeletePDNConnectionSetResponse(teid, 0, ies...)
+	case message.MsgTypeContextResponse:
+		return message.NewContextResponse(teid, 0, ies...)
+	case message.MsgTypeDetachAcknowledge:
+		return message.NewDetachAcknowledge(teid, 0, ies...)
+	case message.MsgTypeReleaseAccessBearersResponse:
+		return message.NewReleaseAccessBearersResponse(teid, 0, ies...)
+	case message.MsgTypeDownlinkDataNotificationAcknowledge:
+		return message.NewDownlinkDataNotificationAcknowledge(teid, 0, ies...)
+	case message.MsgTypePGWRestartNotificationAcknowledge:
+		return message.NewPGWRestartNotificationAcknowledge(teid, 0, ies...)
+	case message.MsgTypeUpdatePDNConnectionSetResponse:
+		return message.NewUpdatePDNConnectionSetResponse(teid, 0, ies...)
+	case message.MsgTypeModifyAccessBearersResponse:
+		return message.NewModifyAccessBearersResponse(teid, 0, ies...)
+	default:
+		return message.NewGeneric(msgType, teid, 0, ies...)
+	}
+}
+
+// peerIfTypes is the interface types of the C-Plane F-TEIDs of the peer, keyed by
+// the ones of the local node.
+var peerIfTypes = map[uint8][]uint8{
+	IFTypeS5S8SGWGTPC:  {IFTypeS5S8PGWGTPC},
+	IFTypeS5S8PGWGTPC:  {IFTypeS5S8SGWGTPC},
+	IFTypeS11MMEGTPC:   {IFTypeS11S4SGWGTPC},
+	IFTypeS11S4SGWGTPC: {IFTypeS11MMEGTPC, IFTypeS4SGSNGTPC},
+	IFTypeS4SGSNGTPC:   {IFTypeS11S4SGWGTPC},
+	IFTypeS3MMEGTPC:    {IFTypeS3SGSNGTPC},
+	IFTypeS3SGSNGTPC:   {IFTypeS3MMEGTPC},
+	IFTypeSmMBMSGWGTPC: {IFTypeSmMMEGTPC},
+	IFTypeSmMMEGTPC:    {IFTypeSmMBMSGWGTPC},
+	IFTypeSnMBMSGWGTPC: {IFTypeSnSGSNGTPC},
+	IFTypeSnSGSNGTPC:   {IFTypeSnMBMSGWGTPC},
+	IFTypeS2bePDGGTPC:  {IFTypeS2bPGWGTPC},
+	IFTypeS2bPGWGTPC:   {IFTypeS2bePDGGTPC},
+	IFTypeS2aTWANGTPC:  {IFTypeS2aPGWGTPC},
+	IFTypeS2aPGWGTPC:   {IFTypeS2aTWANGTPC},
+}
+
+// responseTEID returns the TEID to be set in the response to received.
+//
+// It is the C-Plane TEID of the peer in the Session looked up by the TEID in the
+// request. For the initial requests without TEID, e.g., Create Session Request, the
+// one in Sender F-TEID for Control Plane is used. It returns zero if not found.
+func (c *Conn) responseTEID(raddr net.Addr, received message.Message) uint32 {
+	if teid := received.TEID(); teid != 0 {
+		sess, err := c.GetSessionByTEID(teid, raddr)
+		if err != nil {
+			return 0
+		}
+		for _, it := range peerIfTypes[c.localIfType] {
+			if teid, err := sess.GetTEID(it); err == nil {
+				return teid
+			}
+		}
+		return 0
+	}
+	return senderCTEID(received)
+}
+
+// senderCTEID returns the TEID in the Sender F-TEID for Control Plane in msg, or
+// zero if not found.
+func senderCTEID(msg message.Message) uint32 {
+	var fteid *ie.IE
+	switch m := msg.(type) {
+	case *message.CreateSessionRequest:
+		fteid = m.SenderFTEIDC
+	case *message.ContextRequest:
+		fteid = m.AddressAndTEIDForCPlane
+	case *message.Generic:
+		for _, i := range m.IEs {
+			if i.Type == ie.FullyQualifiedTEID && i.Instance() == 0 {
+				fteid = i
+				break
+			}
+		}
+	}
+	if fteid == nil {
+		return 0
+	}
+
+	teid, err := fteid.TEID()
+	if err != nil {
+		return 0
+	}
+	return teid
+}
@@ -0,0 +1,282 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// Presence is the presence requirement of an IE in a message.
+type Presence uint8
+
+// Presence definitions.
+const (
+	PresenceOptional Presence = iota
+	PresenceConditional
+	PresenceMandatory
+)
+
+// String returns the name of Presence.
+func (p Presence) String() string {
+	switch p {
+	case PresenceMandatory:
+		return "Mandatory"
+	case PresenceConditional:
+		return "Conditional"
+	default:
+		return "Optional"
+	}
+}
+
+// IESchema is the definition of an IE in a message or in a grouped IE.
+type IESchema struct {
+	Type, Instance uint8
+	Presence       Presence
+
+	// Cond is the condition of a conditional IE. The IE is validated as a mandatory
+	// one if Cond returns true for the message. Cond is ignored if Presence is not
+	// PresenceConditional.
+	Cond func(msg message.Message) bool
+
+	// Children is the definitions of the IEs in the grouped IE.
+	Children []*IESchema
+}
+
+// WithCond sets the condition of a conditional IE and returns IESchema itself.
+func (s *IESchema) WithCond(cond func(msg message.Message) bool) *IESchema {
+	s.Cond = cond
+	return s
+}
+
+// isMandatory reports whether the IE is mandatory in msg.
+func (s *IESchema) isMandatory(msg message.Message) bool {
+	switch s.Presence {
+	case PresenceMandatory:
+		return true
+	case PresenceConditional:
+		return s.Cond != nil && s.Cond(msg)
+	default:
+		return false
+	}
+}
+
+// MessageSchema is the definition of the IEs in a message.
+//
+// Only the IEs that matter for the validation need to be listed; the IEs not in
+// the schema are just ignored.
+type MessageSchema struct {
+	MsgType uint8
+	IEs     []*IESchema
+}
+
+// Mandatory returns IESchema of a mandatory IE.
+func Mandatory(itype, instance uint8, children ...*IESchema) *IESchema {
+	return &IESchema{Type: itype, Instance: instance, Presence: PresenceMandatory, Children: children}
+}
+
+// Conditional returns IESchema of a conditional IE.
+func Conditional(itype, instance uint8, children ...*IESchema) *IESchema {
+	return &IESchema{Type: itype, Instance: instance, Presence: PresenceConditional, Children: children}
+}
+
+// Optional returns IESchema of an optional IE.
+func Optional(itype, instance uint8, children ...*IESchema) *IESchema {
+	return &IESchema{Type: itype, Instance: instance, Presence: PresenceOptional, Children: children}
+}
+
+var schemas = struct {
+	sync.RWMutex
+	m map[uint8]*MessageSchema
+}{m: map[uint8]*MessageSchema{}}
+
+// RegisterSchema registers the MessageSchema used by ValidateMessage. The existing
+// one for the same message type is replaced.
+func RegisterSchema(s *MessageSchema) {
+	schemas.Lock()
+	defer schemas.Unlock()
+	schemas.m[s.MsgType] = s
+}
+
+// LookupSchema returns the MessageSchema registered for the message type.
+func LookupSchema(msgType uint8) (*MessageSchema, bool) {
+	schemas.RLock()
+	defer schemas.RUnlock()
+	s, ok := schemas.m[msgType]
+	return s, ok
+}
+
+// ValidateMessage validates msg against the MessageSchema registered for its type,
+// and returns *CauseError with the Cause and the offending IE as defined in TS 29.274
+// §7.7 if msg is invalid. It returns nil if no schema is registered for the type.
+//
+// A mandatory IE that is not present is reported with "Mandatory IE missing", and the
+// one with the payload that cannot be decoded is reported with "Mandatory IE incorrect".
+// A conditional IE is validated in the same way as a mandatory one if its condition
+// (IESchema.Cond) is met. The IEs in the grouped IEs present in msg are validated in
+// the same way.
+//
+// If the Cause IE in msg indicates the rejection, only the Cause IE is validated.
+//
+// This can be used to validate the incoming messages, and to lint the outgoing ones.
+func ValidateMessage(msg message.Message) error {
+	s, ok := LookupSchema(msg.MessageType())
+	if !ok {
+		return nil
+	}
+	return s.Validate(msg)
+}
+
+// Validate validates msg against MessageSchema. See ValidateMessage.
+func (s *MessageSchema) Validate(msg message.Message) error {
+	b, err := message.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	h, err := message.ParseHeader(b)
+	if err != nil {
+		return err
+	}
+	ies, err := ie.ParseMultiIEs(h.Payload)
+	if err != nil {
+		return NewCauseError(CauseInvalidMessageFormat, nil, err.Error())
+	}
+
+	defs := s.IEs
+	if isRejection(ies) {
+		// only the Cause is required in the rejection response.
+		defs = nil
+		for _, def := range s.IEs {
+			if def.Type == ie.Cause {
+				defs = append(defs, def)
+			}
+		}
+	}
+
+	if err := validateIEs(msg, defs, ies); err != nil {
+		err.Msg = fmt.Sprintf("%s in %s", err.Msg, msg.MessageTypeName())
+		return err
+	}
+	return nil
+}
+
+func validateIEs(msg message.Message, defs []*IESchema, ies []*ie.IE) *CauseError {
+	for _, def := range defs {
+		mandatory := def.isMandatory(msg)
+
+		var found bool
+		for _, i := range ies {
+			if i.Type != def.Type || i.Instance() != def.Instance {
+				continue
+			}
+			found = true
+
+			if err := decodeIE(i); err != nil {
+				if !mandatory {
+					continue
+				}
+				return NewCauseError(
+					CauseMandatoryIEIncorrect, ie.New(def.Type, def.Instance, nil),
+					fmt.Sprintf("IE type %d, instance %d is incorrect: %v", def.Type, def.Instance, err),
+				)
+			}
+			if len(def.Children) > 0 {
+				if err := validateIEs(msg, def.Children, i.ChildIEs); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !found && mandatory {
+			return NewCauseError(
+				CauseMandatoryIEMissing, ie.New(def.Type, def.Instance, nil),
+				fmt.Sprintf("IE type %d, instance %d is missing", def.Type, def.Instance),
+			)
+		}
+	}
+	return nil
+}
+
+// isRejection reports whether ies has the Cause IE that indicates the rejection.
+func isRejection(ies []*ie.IE) bool {
+	for _, i := range ies {
+		if i.Type != ie.Cause || i.Instance() != 0 {
+			continue
+		}
+		cause, err := i.Cause()
+		return err == nil && CauseCategoryOf(cause) == CauseCategoryRejection
+	}
+	return false
+}
+
+// decodeIE checks if the payload of i can be decoded.
+func decodeIE(i *ie.IE) error {
+	if len(i.Payload) < ieMinLengths[i.Type] {
+		return fmt.Errorf("payload too short: %d", len(i.Payload))
+	}
+	if decode, ok := ieDecoders[i.Type]; ok {
+		return decode(i)
+	}
+	return nil
+}
+
+// ieDecoders is the typed accessors of IEs used to check if the payload can be decoded.
+var ieDecoders = map[uint8]func(i *ie.IE) error{
+	ie.IMSI:                    func(i *ie.IE) error { _, err := i.IMSI(); return err },
+	ie.Cause:                   func(i *ie.IE) error { _, err := i.Cause(); return err },
+	ie.Recovery:                func(i *ie.IE) error { _, err := i.Recovery(); return err },
+	ie.AccessPointName:         func(i *ie.IE) error { _, err := i.AccessPointName(); return err },
+	ie.AggregateMaximumBitRate: func(i *ie.IE) error { _, err := i.AggregateMaximumBitRate(); return err },
+	ie.EPSBearerID:             func(i *ie.IE) error { _, err := i.EPSBearerID(); return err },
+	ie.MobileEquipmentIdentity: func(i *ie.IE) error { _, err := i.MobileEquipmentIdentity(); return err },
+	ie.MSISDN:                  func(i *ie.IE) error { _, err := i.MSISDN(); return err },
+	ie.PDNAddressAllocation: func(i *ie.IE) error {
+		_, err := ie.ParsePDNAddressAllocationFields(i.Payload)
+		return err
+	},
+	ie.BearerQoS:               func(i *ie.IE) error { _, err := i.BearerQoS(); return err },
+	ie.RATType:                 func(i *ie.IE) error { _, err := i.RATType(); return err },
+	ie.ServingNetwork:          func(i *ie.IE) error { _, err := i.ServingNetwork(); return err },
+	ie.UserLocationInformation: func(i *ie.IE) error { _, err := i.UserLocationInformation(); return err },
+	ie.FullyQualifiedTEID: func(i *ie.IE) error {
+		_, err := ie.ParseFullyQualifiedTEIDFields(i.Payload)
+		return err
+	},
+	ie.ChargingID:             func(i *ie.IE) error { _, err := i.ChargingID(); return err },
+	ie.PDNType:                func(i *ie.IE) error { _, err := i.PDNType(); return err },
+	ie.SelectionMode:          func(i *ie.IE) error { _, err := i.SelectionMode(); return err },
+	ie.APNRestriction:         func(i *ie.IE) error { _, err := i.APNRestriction(); return err },
+	ie.DelayValue:             func(i *ie.IE) error { _, err := i.DelayValue(); return err },
+	ie.ProcedureTransactionID: func(i *ie.IE) error { _, err := i.ProcedureTransactionID(); return err },
+}
+
+// ieMinLengths is the minimum length of the payload of IEs to be decoded.
+var ieMinLengths = map[uint8]int{
+	ie.IMSI:                    1,
+	ie.Cause:                   2,
+	ie.Recovery:                1,
+	ie.AccessPointName:         1,
+	ie.AggregateMaximumBitRate: 8,
+	ie.EPSBearerID:             1,
+	ie.MobileEquipmentIdentity: 1,
+	ie.MSISDN:                  1,
+	ie.Indication:              1,
+	ie.PDNAddressAllocation:    1,
+	ie.BearerQoS:               22,
+	ie.RATType:                 1,
+	ie.ServingNetwork:          3,
+	ie.BearerTFT:               1,
+	ie.UserLocationInformation: 1,
+	ie.FullyQualifiedTEID:      9,
+	ie.ChargingID:              4,
+	ie.PDNType:                 1,
+	ie.SelectionMode:           1,
+	ie.APNRestriction:          1,
+	ie.DelayValue:              1,
+	ie.ProcedureTransactionID:  1,
+}
@@ -0,0 +1,217 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func validCSReqIEs() []*ie.IE {
+	return []*ie.IE{
+		ie.NewIMSI("123451234567890"),
+		ie.NewRATType(gtpv2.RATTypeEUTRAN),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11MMEGTPC, 0xffffffff, "127.0.0.1", ""),
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPC, 0, "127.0.0.2", "").WithInstance(1),
+		ie.NewAccessPointName("some.apn.example"),
+	}
+}
+
+func TestValidateMessage(t *testing.T) {
+	bearerQoS := ie.NewBearerQoS(1, 2, 1, 0xff, 0, 0, 0, 0)
+	cases := []struct {
+		description string
+		msg         message.Message
+		cause       uint8
+		offending   uint8
+	}{
+		{
+			"Valid",
+			message.NewCreateSessionRequest(0, 0, append(validCSReqIEs(),
+				ie.NewBearerContext(ie.NewEPSBearerID(5), bearerQoS),
+			)...),
+			0, 0,
+		}, {
+			"MandatoryIEMissing",
+			message.NewCreateSessionRequest(0, 0,
+				ie.NewRATType(gtpv2.RATTypeEUTRAN),
+				ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11MMEGTPC, 0xffffffff, "127.0.0.1", ""),
+				ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8PGWGTPC, 0, "127.0.0.2", "").WithInstance(1),
+				ie.NewBearerContext(ie.NewEPSBearerID(5), bearerQoS),
+			),
+			gtpv2.CauseMandatoryIEMissing, ie.AccessPointName,
+		}, {
+			"MandatoryChildIEMissing",
+			message.NewCreateSessionRequest(0, 0, append(validCSReqIEs(),
+				ie.NewBearerContext(ie.NewEPSBearerID(5)),
+			)...),
+			gtpv2.CauseMandatoryIEMissing, ie.BearerQoS,
+		}, {
+			"MandatoryIEIncorrect",
+			message.NewCreateSessionRequest(0, 0,
+				ie.NewIMSI("123451234567890"),
+				ie.NewRATType(gtpv2.RATTypeEUTRAN),
+				ie.New(ie.FullyQualifiedTEID, 0, []byte{0x8a, 0x00}),
+				ie.NewAccessPointName("some.apn.example"),
+				ie.NewBearerContext(ie.NewEPSBearerID(5), bearerQoS),
+			),
+			gtpv2.CauseMandatoryIEIncorrect, ie.FullyQualifiedTEID,
+		}, {
+			"MandatoryIEUndecodable",
+			message.NewCreateSessionRequest(0, 0,
+				ie.NewIMSI("123451234567890"),
+				ie.NewRATType(gtpv2.RATTypeEUTRAN),
+				// IPv6 flag is set but the IPv6 address is too short.
+				ie.New(ie.FullyQualifiedTEID, 0, []byte{0x4a, 0xff, 0xff, 0xff, 0xff, 0x7f, 0x00, 0x00, 0x01}),
+				ie.NewAccessPointName("some.apn.example"),
+				ie.NewBearerContext(ie.NewEPSBearerID(5), bearerQoS),
+			),
+			gtpv2.CauseMandatoryIEIncorrect, ie.FullyQualifiedTEID,
+		}, {
+			"ConditionalIEMissing",
+			message.NewCreateSessionRequest(0, 0,
+				ie.NewIMSI("123451234567890"),
+				ie.NewRATType(gtpv2.RATTypeEUTRAN),
+				ie.NewFullyQualifiedTEID(gtpv2.IFTypeS11MMEGTPC, 0xffffffff, "127.0.0.1", ""),
+				ie.NewAccessPointName("some.apn.example"),
+				ie.NewBearerContext(ie.NewEPSBearerID(5), bearerQoS),
+			),
+			gtpv2.CauseMandatoryIEMissing, ie.FullyQualifiedTEID,
+		}, {
+			"ConditionNotMet",
+			message.NewCreateSessionRequest(0, 0,
+				ie.NewIMSI("123451234567890"),
+				ie.NewRATType(gtpv2.RATTypeEUTRAN),
+				ie.NewFullyQualifiedTEID(gtpv2.IFTypeS5S8SGWGTPC, 0xffffffff, "127.0.0.1", ""),
+				ie.NewAccessPointName("some.apn.example"),
+				ie.NewBearerContext(ie.NewEPSBearerID(5), bearerQoS),
+			),
+			0, 0,
+		}, {
+			"ConditionalEBIMissing",
+			message.NewDeleteBearerRequest(0, 0),
+			gtpv2.CauseMandatoryIEMissing, ie.EPSBearerID,
+		}, {
+			"Rejection",
+			message.NewCreateBearerResponse(0, 0, ie.NewCause(gtpv2.CauseNoResourcesAvailable, 0, 0, 0, nil)),
+			0, 0,
+		}, {
+			"NoSchema",
+			message.NewGeneric(message.MsgTypeContextRequest, 0, 0),
+			0, 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := gtpv2.ValidateMessage(c.msg)
+			if c.cause == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var causeErr *gtpv2.CauseError
+			if !errors.As(err, &causeErr) {
+				t.Fatalf("got unexpected error: %v", err)
+			}
+			if causeErr.Cause != c.cause {
+				t.Errorf("wrong Cause: %d", causeErr.Cause)
+			}
+			if causeErr.OffendingIE.Type != c.offending {
+				t.Errorf("wrong offending IE: %d", causeErr.OffendingIE.Type)
+			}
+		})
+	}
+}
+
+func TestSchemaValidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handled := make(chan struct{}, 1)
+	srvConn := gtpv2.NewConn(&net.UDPAddr{IP: net.IP{127, 0, 0, 1}}, gtpv2.IFTypeS11S4SGWGTPC, 0)
+	srvConn.EnableSchemaValidation()
+	srvConn.AddHandler(
+		message.MsgTypeCreateSessionRequest,
+		func(c *gtpv2.Conn, cliAddr net.Addr, msg message.Message) error {
+			handled <- struct{}{}
+			return nil
+		},
+	)
+	if err := srvConn.Listen(ctx); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if err := srvConn.Serve(ctx); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	cliConn := gtpv2.NewConn(&net.UDPAddr{IP: net.IP{127, 0, 0, 1}}, gtpv2.IFTypeS11MMEGTPC, 0)
+	if err := cliConn.Listen(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer cliConn.Close()
+
+	// the TEID in the response is not known as no Session is created on the client.
+	cliConn.DisableValidation()
+
+	// BearerContext is missing.
+	req := message.NewCreateSessionRequest(0, 0, validCSReqIEs()...)
+
+	cliConn.EnableLint()
+	_, err := cliConn.SendMessageTo(req, srvConn.LocalAddr())
+	var causeErr *gtpv2.CauseError
+	if !errors.As(err, &causeErr) {
+		t.Fatalf("lint did not fail: %v", err)
+	}
+	cliConn.DisableLint()
+
+	rspCh := make(chan message.Message, 1)
+	cliConn.AddHandler(
+		message.MsgTypeCreateSessionResponse,
+		func(c *gtpv2.Conn, srvAddr net.Addr, msg message.Message) error {
+			rspCh <- msg
+			return nil
+		},
+	)
+	go func() {
+		if err := cliConn.Serve(ctx); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	if _, err := cliConn.SendMessageTo(req, srvConn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-rspCh:
+		csRsp := msg.(*message.CreateSessionResponse)
+		if got := csRsp.Cause.MustCause(); got != gtpv2.CauseMandatoryIEMissing {
+			t.Errorf("wrong Cause: %d", got)
+		}
+		offending, err := csRsp.Cause.OffendingIE()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offending.Type != ie.BearerContext {
+			t.Errorf("wrong offending IE: %d", offending.Type)
+		}
+	case <-handled:
+		t.Fatal("invalid message is passed to HandlerFunc")
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out")
+	}
+}
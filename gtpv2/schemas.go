@@ -0,0 +1,176 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// defaultSchemas is the MessageSchemas registered by default, as defined in TS 29.274 §7.
+//
+// Only the IEs that are mandatory, or the conditional ones that are commonly used
+// are listed. Use RegisterSchema to replace them if stricter validation is needed.
+var defaultSchemas = []*MessageSchema{
+	{
+		MsgType: message.MsgTypeEchoRequest,
+		IEs:     []*IESchema{Mandatory(ie.Recovery, 0)},
+	}, {
+		MsgType: message.MsgTypeEchoResponse,
+		IEs:     []*IESchema{Mandatory(ie.Recovery, 0)},
+	}, {
+		MsgType: message.MsgTypeCreateSessionRequest,
+		IEs: []*IESchema{
+			Conditional(ie.IMSI, 0),
+			Conditional(ie.MSISDN, 0),
+			Conditional(ie.MobileEquipmentIdentity, 0),
+			Conditional(ie.UserLocationInformation, 0),
+			Conditional(ie.ServingNetwork, 0),
+			Mandatory(ie.RATType, 0),
+			Mandatory(ie.FullyQualifiedTEID, 0),
+			Conditional(ie.FullyQualifiedTEID, 1).WithCond(isSentByMMEOrSGSN),
+			Mandatory(ie.AccessPointName, 0),
+			Conditional(ie.SelectionMode, 0),
+			Conditional(ie.PDNType, 0),
+			Conditional(ie.PDNAddressAllocation, 0),
+			Conditional(ie.AggregateMaximumBitRate, 0),
+			Mandatory(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+				Mandatory(ie.BearerQoS, 0),
+				Conditional(ie.BearerTFT, 0),
+			),
+		},
+	}, {
+		MsgType: message.MsgTypeCreateSessionResponse,
+		IEs: []*IESchema{
+			Mandatory(ie.Cause, 0),
+			Conditional(ie.FullyQualifiedTEID, 0).WithCond(isAccepted),
+			Conditional(ie.FullyQualifiedTEID, 1),
+			Conditional(ie.PDNAddressAllocation, 0),
+			Mandatory(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+				Mandatory(ie.Cause, 0),
+				Conditional(ie.BearerQoS, 0),
+				Conditional(ie.ChargingID, 0),
+			),
+		},
+	}, {
+		MsgType: message.MsgTypeModifyBearerRequest,
+		IEs: []*IESchema{
+			Conditional(ie.FullyQualifiedTEID, 0),
+			Conditional(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+			),
+			Conditional(ie.BearerContext, 1,
+				Mandatory(ie.EPSBearerID, 0),
+			),
+		},
+	}, {
+		MsgType: message.MsgTypeModifyBearerResponse,
+		IEs: []*IESchema{
+			Mandatory(ie.Cause, 0),
+			Conditional(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+				Mandatory(ie.Cause, 0),
+			),
+		},
+	}, {
+		MsgType: message.MsgTypeDeleteSessionRequest,
+		IEs: []*IESchema{
+			Conditional(ie.EPSBearerID, 0),
+		},
+	}, {
+		MsgType: message.MsgTypeDeleteSessionResponse,
+		IEs:     []*IESchema{Mandatory(ie.Cause, 0)},
+	}, {
+		MsgType: message.MsgTypeCreateBearerRequest,
+		IEs: []*IESchema{
+			Mandatory(ie.EPSBearerID, 0),
+			Mandatory(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+				Mandatory(ie.BearerTFT, 0),
+				Mandatory(ie.FullyQualifiedTEID, 0),
+				Mandatory(ie.BearerQoS, 0),
+			),
+		},
+	}, {
+		MsgType: message.MsgTypeCreateBearerResponse,
+		IEs: []*IESchema{
+			Mandatory(ie.Cause, 0),
+			Mandatory(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+				Mandatory(ie.Cause, 0),
+			),
+		},
+	}, {
+		MsgType: message.MsgTypeUpdateBearerRequest,
+		IEs: []*IESchema{
+			Mandatory(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+				Conditional(ie.BearerTFT, 0),
+				Conditional(ie.BearerQoS, 0),
+			),
+			Mandatory(ie.AggregateMaximumBitRate, 0),
+		},
+	}, {
+		MsgType: message.MsgTypeUpdateBearerResponse,
+		IEs: []*IESchema{
+			Mandatory(ie.Cause, 0),
+			Mandatory(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+				Mandatory(ie.Cause, 0),
+			),
+		},
+	}, {
+		MsgType: message.MsgTypeDeleteBearerRequest,
+		IEs: []*IESchema{
+			Conditional(ie.EPSBearerID, 0).WithCond(hasNoEBI),
+			Conditional(ie.EPSBearerID, 1),
+		},
+	}, {
+		MsgType: message.MsgTypeDeleteBearerResponse,
+		IEs: []*IESchema{
+			Mandatory(ie.Cause, 0),
+			Conditional(ie.BearerContext, 0,
+				Mandatory(ie.EPSBearerID, 0),
+				Mandatory(ie.Cause, 0),
+			),
+		},
+	}, {
+		MsgType: message.MsgTypeReleaseAccessBearersResponse,
+		IEs:     []*IESchema{Mandatory(ie.Cause, 0)},
+	}, {
+		MsgType: message.MsgTypeDownlinkDataNotificationAcknowledge,
+		IEs:     []*IESchema{Mandatory(ie.Cause, 0)},
+	},
+}
+
+// isSentByMMEOrSGSN reports whether the Create Session Request is sent by MME or SGSN,
+// in which case the PGW S5/S8 F-TEID for C-plane is required.
+func isSentByMMEOrSGSN(msg message.Message) bool {
+	m, ok := msg.(*message.CreateSessionRequest)
+	if !ok || m.SenderFTEIDC == nil {
+		return false
+	}
+	switch m.SenderFTEIDC.MustInterfaceType() {
+	case IFTypeS11MMEGTPC, IFTypeS4SGSNGTPC:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasNoEBI reports whether the Delete Bearer Request has no EBIs to be deleted,
+// in which case the Linked EBI is required to delete the PDN connection.
+func hasNoEBI(msg message.Message) bool {
+	m, ok := msg.(*message.DeleteBearerRequest)
+	return ok && m.EBI == nil
+}
+
+func init() {
+	for _, s := range defaultSchemas {
+		RegisterSchema(s)
+	}
+}
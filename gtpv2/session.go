@@ -5,6 +5,7 @@
 package gtpv2
 
 import (
+	"encoding/json"
 	"net"
 	"sync"
 	"time"
@@ -28,14 +29,18 @@ type Subscriber struct {
 
 // Session is a GTPv2 Session.
 type Session struct {
-	mu       sync.Mutex
-	isActive bool
+	mu sync.Mutex
+	*sessionFSM
 	*teidMap
 	*bearerMap
 
 	// channel to store message passed by other Sessions
 	msgQueue chan message.Message
 
+	// bearerProcedures is the dedicated bearer procedures waiting for the
+	// response, keyed by the sequence number of the request.
+	bearerProcedures map[uint32]*bearerProcedure
+
 	// peerAddr is a net.Addr of the peer associated with Session.
 	// To avoid calling String() many times, peerAddrString is set when NewSession
 	// and UpdatePeerAddr is called.
@@ -55,6 +60,7 @@ func NewSession(peerAddr net.Addr, sub *Subscriber) *Session {
 		mu:             sync.Mutex{},
 		peerAddr:       peerAddr,
 		peerAddrString: peerAddr.String(),
+		sessionFSM:     &sessionFSM{},
 		teidMap:        newTeidMap(),
 		bearerMap:      newBearerMap("default", &Bearer{QoSProfile: &QoSProfile{}}),
 		Subscriber:     sub,
@@ -64,34 +70,119 @@ func NewSession(peerAddr net.Addr, sub *Subscriber) *Session {
 	return s
 }
 
+// sessionJSON is the serialised format of Session.
+type sessionJSON struct {
+	IMSI     string             `json:"imsi"`
+	MSISDN   string             `json:"msisdn,omitempty"`
+	IMEI     string             `json:"imei,omitempty"`
+	Location *Location          `json:"location,omitempty"`
+	Active   bool               `json:"active"`
+	State    SessionState       `json:"state"`
+	PeerAddr string             `json:"peerAddr,omitempty"`
+	TEIDs    map[uint8]uint32   `json:"teids,omitempty"`
+	Bearers  map[string]*Bearer `json:"bearers,omitempty"`
+}
+
+// MarshalJSON serialises Session into JSON, including the subscriber information,
+// TEIDs, Bearers and the address of the peer.
+//
+// The messages queued in Session are not included.
+func (s *Session) MarshalJSON() ([]byte, error) {
+	v := &sessionJSON{
+		Active:   s.IsActive(),
+		State:    s.State(),
+		PeerAddr: s.peerAddrString,
+		TEIDs:    map[uint8]uint32{},
+		Bearers:  map[string]*Bearer{},
+	}
+	if s.Subscriber != nil {
+		v.IMSI, v.MSISDN, v.IMEI = s.IMSI, s.MSISDN, s.IMEI
+		v.Location = s.Location
+	}
+
+	s.teidMap.rangeWithFunc(func(k, t interface{}) bool {
+		v.TEIDs[k.(uint8)] = t.(uint32)
+		return true
+	})
+	s.bearerMap.rangeWithFunc(func(k, br interface{}) bool {
+		v.Bearers[k.(string)] = br.(*Bearer)
+		return true
+	})
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON restores Session from JSON serialised by MarshalJSON.
+//
+// The address of the peer is restored as *net.UDPAddr.
+func (s *Session) UnmarshalJSON(b []byte) error {
+	v := &sessionJSON{}
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+
+	if v.PeerAddr != "" {
+		raddr, err := net.ResolveUDPAddr("udp", v.PeerAddr)
+		if err != nil {
+			return err
+		}
+		s.peerAddr = raddr
+		s.peerAddrString = v.PeerAddr
+	}
+
+	s.sessionFSM = &sessionFSM{state: v.State}
+	if v.State == SessionStateCreating && v.Active {
+		s.state = SessionStateActive
+	}
+	s.Subscriber = &Subscriber{
+		IMSI: v.IMSI, MSISDN: v.MSISDN, IMEI: v.IMEI, Location: v.Location,
+	}
+	s.msgQueue = make(chan message.Message, 1000)
+
+	s.teidMap = newTeidMap()
+	for ifType, teid := range v.TEIDs {
+		s.teidMap.store(ifType, teid)
+	}
+	s.bearerMap = &bearerMap{}
+	for name, br := range v.Bearers {
+		s.bearerMap.store(name, br)
+	}
+
+	return nil
+}
+
 // Activate marks a Session active.
+//
+// The state of Session is set to SessionStateActive regardless of the current state.
+// Use Transition to update the state with the validation.
 func (s *Session) Activate() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if s.IMSI == "" {
 		return &RequiredParameterMissingError{"IMSI", "Session must have IMSI set"}
 	}
 
-	s.isActive = true
+	s.setState(SessionStateActive, 0)
 	return nil
 }
 
 // Deactivate marks a Session inactive.
+//
+// The state of Session is set to SessionStateDeleted regardless of the current state.
 func (s *Session) Deactivate() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.isActive = false
+	s.setState(SessionStateDeleted, 0)
 	return nil
 }
 
 // IsActive reports whether a Session is active or not.
+//
+// Session is considered to be active in SessionStateActive, SessionStateModifying,
+// SessionStateIdle and SessionStateDeleting.
 func (s *Session) IsActive() bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	return s.isActive
+	switch s.State() {
+	case SessionStateActive, SessionStateModifying, SessionStateIdle, SessionStateDeleting:
+		return true
+	default:
+		return false
+	}
 }
 
 // PeerAddr returns the address of the peer node associated with Session.
@@ -199,6 +290,25 @@ func (s *Session) SetDefaultBearer(bearer *Bearer) {
 	s.bearerMap.store("default", bearer)
 }
 
+// LinkedEBI returns the EBI of the default bearer, which identifies the PDN
+// connection among the ones of the same subscriber. It returns 0 if not known yet.
+func (s *Session) LinkedEBI() uint8 {
+	bearer := s.GetDefaultBearer()
+	if bearer == nil {
+		return 0
+	}
+	return bearer.EBI
+}
+
+// APN returns the APN of the default bearer.
+func (s *Session) APN() string {
+	bearer := s.GetDefaultBearer()
+	if bearer == nil {
+		return ""
+	}
+	return bearer.APN
+}
+
 // LookupBearerByName looks up Bearer registered in Session by name.
 func (s *Session) LookupBearerByName(name string) (*Bearer, error) {
 	if br, ok := s.bearerMap.load(name); ok {
@@ -287,6 +397,10 @@ func (t *teidMap) store(ifType uint8, teid uint32) {
 	t.syncMap.Store(ifType, teid)
 }
 
+func (t *teidMap) rangeWithFunc(fn func(ifType, teid interface{}) bool) {
+	t.syncMap.Range(fn)
+}
+
 func (t *teidMap) load(ifType uint8) (uint32, bool) {
 	teid, ok := t.syncMap.Load(ifType)
 	if !ok {
@@ -0,0 +1,127 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// snapshotVersion is the version of the format of snapshot.
+const snapshotVersion = 1
+
+// snapshot is the serialised state of Conn.
+type snapshot struct {
+	Version        int        `json:"version"`
+	LocalIFType    uint8      `json:"localIFType"`
+	RestartCounter uint8      `json:"restartCounter"`
+	Sequence       uint32     `json:"sequence"`
+	Sessions       []*Session `json:"sessions"`
+}
+
+// Snapshot writes the state of Conn to w in JSON format, which can be loaded
+// with Restore later.
+//
+// The snapshot includes all the Sessions with their Bearers, TEIDs and the address
+// of peer, as well as RestartCounter and the last SequenceNumber used.
+func (c *Conn) Snapshot(w io.Writer) error {
+	c.mu.Lock()
+	v := &snapshot{
+		Version:        snapshotVersion,
+		LocalIFType:    c.localIfType,
+		RestartCounter: c.RestartCounter,
+		Sequence:       c.sequence,
+	}
+	c.mu.Unlock()
+
+	if err := c.store.Range(func(sess *Session) bool {
+		v.Sessions = append(v.Sessions, sess)
+		return true
+	}); err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(v)
+}
+
+// RestoreOption is an option for Restore.
+type RestoreOption func(*restoreConfig)
+
+type restoreConfig struct {
+	bumpRestartCounter bool
+}
+
+// BumpRestartCounter makes Restore increment RestartCounter from the one in the
+// snapshot, so that the peers see the restart in Recovery IE.
+func BumpRestartCounter() RestoreOption {
+	return func(c *restoreConfig) {
+		c.bumpRestartCounter = true
+	}
+}
+
+// Restore loads the state of Conn written by Snapshot from r, and registers the
+// Sessions in it to Conn in the same way as RegisterSession.
+//
+// By default, RestartCounter is set to the one in the snapshot, which is the value
+// the peers know. As the Sessions are preserved, this is not counted as a restart
+// in the sense of TS 23.007 and the peers see the same value in Recovery IE as
+// before. If the restart should be told to the peers anyway, give BumpRestartCounter
+// to increment it.
+//
+// The snapshot must be taken with the Conn of the same local interface type, and
+// is meant to be restored to a new Conn. Restore does nothing and returns error if
+// any of the Sessions in the snapshot does not have the incoming TEID for it. If
+// SessionStore fails to store any of the Sessions, the ones registered so far are
+// removed and the error is returned.
+func (c *Conn) Restore(r io.Reader, opts ...RestoreOption) error {
+	cfg := &restoreConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	v := &snapshot{}
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return err
+	}
+
+	if v.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", v.Version)
+	}
+	if v.LocalIFType != c.localIfType {
+		return fmt.Errorf(
+			"local interface type mismatch: snapshot: %d, conn: %d", v.LocalIFType, c.localIfType,
+		)
+	}
+
+	iteis := make([]uint32, len(v.Sessions))
+	for i, sess := range v.Sessions {
+		itei, err := sess.GetTEID(c.localIfType)
+		if err != nil {
+			return &InvalidSessionError{IMSI: sess.IMSI}
+		}
+		iteis[i] = itei
+	}
+
+	for i, sess := range v.Sessions {
+		if err := c.registerSession(iteis[i], sess); err != nil {
+			for j := 0; j <= i; j++ {
+				_ = c.store.Delete(iteis[j], v.Sessions[j])
+				c.teidAllocator.Release(iteis[j])
+			}
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.RestartCounter = v.RestartCounter
+	if cfg.bumpRestartCounter {
+		c.RestartCounter++
+	}
+	c.sequence = v.Sequence
+	c.mu.Unlock()
+
+	return nil
+}
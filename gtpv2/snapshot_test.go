@@ -0,0 +1,149 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	peer := &net.UDPAddr{IP: net.IP{127, 0, 0, 2}, Port: 2123}
+	conn := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS5S8PGWGTPC, 3)
+	conn.IncSequence()
+	conn.IncSequence()
+
+	for i, apn := range []string{"internet", "ims"} {
+		sess := gtpv2.NewSession(peer, &gtpv2.Subscriber{IMSI: "001011234567891"})
+		_ = sess.Activate()
+		sess.AddTEID(gtpv2.IFTypeS5S8SGWGTPC, uint32(0x100+i))
+
+		br := sess.GetDefaultBearer()
+		br.EBI = uint8(5 + i)
+		br.APN = apn
+		br.SetIncomingTEID(uint32(0x200 + i))
+		br.SetOutgoingTEID(uint32(0x300 + i))
+		br.SetRemoteAddress(&net.UDPAddr{IP: net.IP{127, 0, 0, 3}, Port: 2152})
+		conn.RegisterSession(uint32(i+1), sess)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := conn.Snapshot(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS5S8PGWGTPC, 0)
+	if err := restored.Restore(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.RestartCounter != 3 {
+		t.Errorf("wrong RestartCounter: %d", restored.RestartCounter)
+	}
+	if restored.SequenceNumber() != 2 {
+		t.Errorf("wrong SequenceNumber: %d", restored.SequenceNumber())
+	}
+	if got := restored.SessionCount(); got != 2 {
+		t.Errorf("SessionCount is invalid. want: 2, got: %d", got)
+	}
+
+	sess, err := restored.GetSessionByTEID(2, peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.APN() != "ims" || sess.LinkedEBI() != 6 {
+		t.Errorf("got wrong session: %s, %d", sess.APN(), sess.LinkedEBI())
+	}
+	if teid, err := sess.GetTEID(gtpv2.IFTypeS5S8SGWGTPC); err != nil || teid != 0x101 {
+		t.Errorf("wrong TEID: %#x, %v", teid, err)
+	}
+
+	got, ebi, err := restored.GetSessionByBearerTEID(0x301, &net.UDPAddr{IP: net.IP{127, 0, 0, 3}, Port: 2152})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != sess || ebi != 6 {
+		t.Errorf("got wrong session or EBI by bearer TEID: %s, %d", got.APN(), ebi)
+	}
+
+	mismatch := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS11S4SGWGTPC, 0)
+	if err := mismatch.Restore(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("should fail with different interface type")
+	}
+}
+
+func TestRestoreOptions(t *testing.T) {
+	peer := &net.UDPAddr{IP: net.IP{127, 0, 0, 2}, Port: 2123}
+	conn := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS5S8PGWGTPC, 3)
+	for i := 0; i < 2; i++ {
+		sess := gtpv2.NewSession(peer, &gtpv2.Subscriber{IMSI: "001011234567891"})
+		_ = sess.Activate()
+		sess.GetDefaultBearer().EBI = uint8(5 + i)
+		conn.RegisterSession(uint32(i+1), sess)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := conn.Snapshot(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("bump RestartCounter", func(t *testing.T) {
+		restored := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS5S8PGWGTPC, 0)
+		var changes int
+		restored.OnSessionStateChange(func(*gtpv2.Session, *gtpv2.StateChange) {
+			changes++
+		})
+		if err := restored.Restore(bytes.NewReader(buf.Bytes()), gtpv2.BumpRestartCounter()); err != nil {
+			t.Fatal(err)
+		}
+		if restored.RestartCounter != 4 {
+			t.Errorf("wrong RestartCounter: %d", restored.RestartCounter)
+		}
+
+		// registered in the same way as RegisterSession.
+		sess, err := restored.GetSessionByTEID(1, peer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sess.Deactivate()
+		if changes != 1 {
+			t.Errorf("state change handler is not called: %d", changes)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		restored := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS5S8PGWGTPC, 0)
+		restored.SetSessionStore(&failingStore{SessionStore: gtpv2.NewMemorySessionStore(), failAt: 2})
+		if err := restored.Restore(bytes.NewReader(buf.Bytes())); !errors.Is(err, errStoreFailed) {
+			t.Fatalf("got %v, want %v", err, errStoreFailed)
+		}
+		if got := restored.SessionCount(); got != 0 {
+			t.Errorf("SessionCount is invalid. want: 0, got: %d", got)
+		}
+		if restored.RestartCounter != 0 {
+			t.Errorf("wrong RestartCounter: %d", restored.RestartCounter)
+		}
+	})
+}
+
+var errStoreFailed = errors.New("store failed")
+
+// failingStore fails to store the failAt-th Session.
+type failingStore struct {
+	gtpv2.SessionStore
+	failAt, n int
+}
+
+func (s *failingStore) Store(teid uint32, session *gtpv2.Session) error {
+	s.n++
+	if s.n == s.failAt {
+		return errStoreFailed
+	}
+	return s.SessionStore.Store(teid, session)
+}
@@ -0,0 +1,358 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+// SessionState is the state of the procedures on a Session.
+type SessionState uint8
+
+// SessionState definitions.
+//
+// A Session starts with SessionStateCreating, and becomes SessionStateActive when
+// the creation is accepted. While the modification of the bearers is in progress,
+// it is SessionStateModifying, and it returns to SessionStateActive, or becomes
+// SessionStateIdle if the procedure is Release Access Bearers. The deletion goes
+// through SessionStateDeleting to SessionStateDeleted.
+const (
+	SessionStateCreating SessionState = iota
+	SessionStateActive
+	SessionStateModifying
+	SessionStateIdle
+	SessionStateDeleting
+	SessionStateDeleted
+)
+
+// String returns the name of SessionState.
+func (s SessionState) String() string {
+	switch s {
+	case SessionStateCreating:
+		return "Creating"
+	case SessionStateActive:
+		return "Active"
+	case SessionStateModifying:
+		return "Modifying"
+	case SessionStateIdle:
+		return "Idle"
+	case SessionStateDeleting:
+		return "Deleting"
+	case SessionStateDeleted:
+		return "Deleted"
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint8(s))
+	}
+}
+
+// StateChange is the event of the transition of SessionState.
+type StateChange struct {
+	From, To SessionState
+
+	// MsgType is the type of the message that caused the transition.
+	// It is zero if the state is changed by Activate or Deactivate.
+	MsgType uint8
+}
+
+// sessionFSM holds the state of Session.
+type sessionFSM struct {
+	mu    sync.Mutex
+	state SessionState
+
+	// prev is the state to go back to when the pending request is rejected.
+	prev SessionState
+	// pending is the type of the request that is waiting for the response, and
+	// pendingSeq and pendingFrom are its sequence number and sender, which are used
+	// to tell the retransmission from the colliding request. pendingFrom is nil if
+	// the request is sent by the local node or given to Transition.
+	pending     uint8
+	pendingSeq  uint32
+	pendingFrom net.Addr
+	// last is the message that caused the last transition, which is not applied
+	// twice when the same message is given again.
+	last message.Message
+
+	handler func(session *Session, change *StateChange)
+	// connHandler is the one registered with (*Conn).OnSessionStateChange, which
+	// is called after handler.
+	connHandler func(session *Session, change *StateChange)
+}
+
+// requestTypes is the types of the requests for modification, keyed by the
+// types of their responses.
+var requestTypes = map[uint8]uint8{
+	message.MsgTypeModifyBearerResponse:         message.MsgTypeModifyBearerRequest,
+	message.MsgTypeReleaseAccessBearersResponse: message.MsgTypeReleaseAccessBearersRequest,
+	message.MsgTypeModifyAccessBearersResponse:  message.MsgTypeModifyAccessBearersRequest,
+	message.MsgTypeCreateBearerResponse:         message.MsgTypeCreateBearerRequest,
+	message.MsgTypeUpdateBearerResponse:         message.MsgTypeUpdateBearerRequest,
+	message.MsgTypeDeleteBearerResponse:         message.MsgTypeDeleteBearerRequest,
+}
+
+// State returns the current SessionState.
+func (s *Session) State() SessionState {
+	s.sessionFSM.mu.Lock()
+	defer s.sessionFSM.mu.Unlock()
+
+	return s.state
+}
+
+// OnStateChange registers fn to be called when the SessionState is changed.
+//
+// fn is called after the state is updated, in the goroutine that caused the change.
+// (*Conn).OnSessionStateChange can be used to register it to all the Sessions, which
+// is called after fn.
+func (s *Session) OnStateChange(fn func(session *Session, change *StateChange)) {
+	s.sessionFSM.mu.Lock()
+	s.handler = fn
+	s.sessionFSM.mu.Unlock()
+}
+
+// onConnStateChange registers fn given to (*Conn).OnSessionStateChange, which does
+// not replace the one registered with OnStateChange.
+func (s *Session) onConnStateChange(fn func(session *Session, change *StateChange)) {
+	s.sessionFSM.mu.Lock()
+	s.connHandler = fn
+	s.sessionFSM.mu.Unlock()
+}
+
+// Transition validates msg sent or received on Session against the current state
+// and updates the state.
+//
+// It returns *InvalidStateError if msg is not allowed in the current state, e.g., a
+// Modify Bearer Request during the deletion. The Cause in it is the value to be set
+// in the response to reject the request as defined in TS 29.274. For the requests
+// sent by the local node, the error means that it should not be sent.
+//
+// Conn calls this with the messages received on and sent by its methods for the
+// Sessions registered to it, so it is not necessary to call this in HandlerFunc.
+// Giving the same message again, or the retransmission of the pending request (the
+// one with the same type and sequence number), does nothing. The messages that are
+// not related to the state of Session are always allowed.
+func (s *Session) Transition(msg message.Message) error {
+	_, err := s.transition(msg, nil)
+	return err
+}
+
+// transition is Transition with the sender of msg, which is nil if msg is sent by
+// the local node. It returns true without updating the state if msg is the
+// retransmission of the pending request from the same sender.
+func (s *Session) transition(msg message.Message, sender net.Addr) (bool, error) {
+	msgType := msg.MessageType()
+
+	s.sessionFSM.mu.Lock()
+	if msg == s.last {
+		s.sessionFSM.mu.Unlock()
+		return false, nil
+	}
+	if s.isRetransmission(msg, sender) {
+		s.sessionFSM.mu.Unlock()
+		return true, nil
+	}
+	from := s.state
+	to, err := s.nextState(msg)
+	if err != nil {
+		s.sessionFSM.mu.Unlock()
+		return false, err
+	}
+	if s.pending == msgType {
+		s.pendingSeq, s.pendingFrom = msg.Sequence(), sender
+	}
+	s.state = to
+	s.last = msg
+	fns := s.handlers()
+	s.sessionFSM.mu.Unlock()
+
+	s.notify(fns, from, to, msgType)
+	return false, nil
+}
+
+// isRetransmission reports whether msg is the retransmission of the pending request.
+// This should be called with lock.
+func (s *Session) isRetransmission(msg message.Message, sender net.Addr) bool {
+	if s.pending == 0 || s.pending != msg.MessageType() || s.pendingSeq != msg.Sequence() {
+		return false
+	}
+	if s.pendingFrom == nil || sender == nil {
+		return s.pendingFrom == sender
+	}
+	return s.pendingFrom.String() == sender.String()
+}
+
+// transitioned reports whether msg has caused the last transition.
+func (s *Session) transitioned(msg message.Message) bool {
+	s.sessionFSM.mu.Lock()
+	defer s.sessionFSM.mu.Unlock()
+
+	return msg == s.last
+}
+
+// cancel reverts the transition caused by the request msg that could not be sent,
+// or the one received that the handler failed to respond to.
+func (s *Session) cancel(msg message.Message) {
+	s.sessionFSM.mu.Lock()
+	if msg != s.last || s.pending != msg.MessageType() {
+		s.sessionFSM.mu.Unlock()
+		return
+	}
+	from, to := s.state, s.prev
+	s.state, s.pending, s.last = to, 0, nil
+	fns := s.handlers()
+	s.sessionFSM.mu.Unlock()
+
+	s.notify(fns, from, to, msg.MessageType())
+}
+
+// setState sets the state without validation.
+func (s *Session) setState(to SessionState, msgType uint8) {
+	s.sessionFSM.mu.Lock()
+	from := s.state
+	s.state = to
+	s.pending = 0
+	fns := s.handlers()
+	s.sessionFSM.mu.Unlock()
+
+	s.notify(fns, from, to, msgType)
+}
+
+// handlers returns the handlers to be notified. This should be called with lock.
+func (s *Session) handlers() []func(session *Session, change *StateChange) {
+	return []func(session *Session, change *StateChange){s.handler, s.connHandler}
+}
+
+func (s *Session) notify(fns []func(session *Session, change *StateChange), from, to SessionState, msgType uint8) {
+	if from == to {
+		return
+	}
+	for _, fn := range fns {
+		if fn != nil {
+			fn(s, &StateChange{From: from, To: to, MsgType: msgType})
+		}
+	}
+}
+
+// nextState returns the state after msg. This should be called with lock.
+func (s *Session) nextState(msg message.Message) (SessionState, error) {
+	msgType := msg.MessageType()
+	switch msgType {
+	case message.MsgTypeCreateSessionRequest:
+		if s.state != SessionStateCreating {
+			return 0, s.invalidState(msgType, CauseRequestRejectedReasonNotSpecified)
+		}
+		s.prev, s.pending = s.state, msgType
+		return SessionStateCreating, nil
+	case message.MsgTypeCreateSessionResponse:
+		if s.state != SessionStateCreating || s.pending != message.MsgTypeCreateSessionRequest {
+			return 0, s.invalidState(msgType, 0)
+		}
+		s.pending = 0
+		if isAccepted(msg) {
+			return SessionStateActive, nil
+		}
+		return SessionStateDeleted, nil
+	case message.MsgTypeModifyBearerRequest, message.MsgTypeReleaseAccessBearersRequest,
+		message.MsgTypeModifyAccessBearersRequest, message.MsgTypeCreateBearerRequest,
+		message.MsgTypeUpdateBearerRequest, message.MsgTypeDeleteBearerRequest:
+		switch s.state {
+		case SessionStateActive, SessionStateIdle:
+			s.prev, s.pending = s.state, msgType
+			return SessionStateModifying, nil
+		case SessionStateModifying:
+			return 0, s.invalidState(msgType, s.collisionCause(msgType))
+		default:
+			return 0, s.invalidState(msgType, CauseContextNotFound)
+		}
+	case message.MsgTypeModifyBearerResponse, message.MsgTypeReleaseAccessBearersResponse,
+		message.MsgTypeModifyAccessBearersResponse, message.MsgTypeCreateBearerResponse,
+		message.MsgTypeUpdateBearerResponse, message.MsgTypeDeleteBearerResponse:
+		if s.state != SessionStateModifying || s.pending != requestTypes[msgType] {
+			return 0, s.invalidState(msgType, 0)
+		}
+		s.pending = 0
+		if !isAccepted(msg) {
+			return s.prev, nil
+		}
+		if msgType == message.MsgTypeReleaseAccessBearersResponse {
+			return SessionStateIdle, nil
+		}
+		return SessionStateActive, nil
+	case message.MsgTypeDeleteSessionRequest:
+		switch s.state {
+		case SessionStateDeleted:
+			return 0, s.invalidState(msgType, CauseContextNotFound)
+		case SessionStateDeleting: // retransmission
+			return SessionStateDeleting, nil
+		}
+		// deletion takes precedence over the modification in progress.
+		s.prev, s.pending = s.state, msgType
+		return SessionStateDeleting, nil
+	case message.MsgTypeDeleteSessionResponse:
+		if s.state != SessionStateDeleting {
+			return 0, s.invalidState(msgType, 0)
+		}
+		s.pending = 0
+		return SessionStateDeleted, nil
+	default:
+		return s.state, nil
+	}
+}
+
+// collisionCause returns the Cause to reject the request that collides with
+// the pending one.
+func (s *Session) collisionCause(msgType uint8) uint8 {
+	switch msgType {
+	case message.MsgTypeCreateBearerRequest, message.MsgTypeUpdateBearerRequest, message.MsgTypeDeleteBearerRequest:
+		// network initiated request during the mobility procedure.
+		if s.pending == message.MsgTypeModifyBearerRequest {
+			return CauseTemporarilyRejectedDueToHandoverTAURAUProcedureInProgress
+		}
+	}
+	return CauseCollisionWithNetworkInitiatedRequest
+}
+
+func (s *Session) invalidState(msgType, cause uint8) error {
+	var imsi string
+	if s.Subscriber != nil {
+		imsi = s.IMSI
+	}
+	return &InvalidStateError{IMSI: imsi, State: s.state, MsgType: msgType, Cause: cause}
+}
+
+// isAccepted reports whether the Cause in the response is the acceptance.
+func isAccepted(msg message.Message) bool {
+	var cause *ie.IE
+	switch m := msg.(type) {
+	case *message.CreateSessionResponse:
+		cause = m.Cause
+	case *message.ModifyBearerResponse:
+		cause = m.Cause
+	case *message.ReleaseAccessBearersResponse:
+		cause = m.Cause
+	case *message.ModifyAccessBearersResponse:
+		cause = m.Cause
+	case *message.CreateBearerResponse:
+		cause = m.Cause
+	case *message.UpdateBearerResponse:
+		cause = m.Cause
+	case *message.DeleteBearerResponse:
+		cause = m.Cause
+	default:
+		return false
+	}
+
+	if cause == nil {
+		return false
+	}
+	v, err := cause.Cause()
+	if err != nil {
+		return false
+	}
+	return IsAcceptedCause(v)
+}
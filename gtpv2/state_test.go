@@ -0,0 +1,355 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+	"github.com/wmnsk/go-gtp/gtpv2/ie"
+	"github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func TestSessionStateTransition(t *testing.T) {
+	conn := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS5S8PGWGTPC, 0)
+	var changes []gtpv2.StateChange
+	conn.OnSessionStateChange(func(session *gtpv2.Session, change *gtpv2.StateChange) {
+		changes = append(changes, *change)
+	})
+
+	sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+	conn.RegisterSession(1, sess)
+
+	accepted := ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil)
+	rejected := ie.NewCause(gtpv2.CauseNoResourcesAvailable, 0, 0, 0, nil)
+
+	steps := []struct {
+		msg  message.Message
+		want gtpv2.SessionState
+	}{
+		{message.NewCreateSessionRequest(0, 1), gtpv2.SessionStateCreating},
+		{message.NewCreateSessionResponse(1, 1, accepted), gtpv2.SessionStateActive},
+		{message.NewReleaseAccessBearersRequest(1, 2), gtpv2.SessionStateModifying},
+		{message.NewReleaseAccessBearersResponse(1, 2, accepted), gtpv2.SessionStateIdle},
+		{message.NewModifyBearerRequest(1, 3), gtpv2.SessionStateModifying},
+		{message.NewModifyBearerResponse(1, 3, rejected), gtpv2.SessionStateIdle},
+		{message.NewModifyBearerRequest(1, 4), gtpv2.SessionStateModifying},
+		{message.NewModifyBearerResponse(1, 4, accepted), gtpv2.SessionStateActive},
+		{message.NewEchoRequest(5), gtpv2.SessionStateActive},
+		{message.NewDeleteSessionRequest(1, 6), gtpv2.SessionStateDeleting},
+		{message.NewDeleteSessionResponse(1, 6, accepted), gtpv2.SessionStateDeleted},
+	}
+	for _, s := range steps {
+		if err := sess.Transition(s.msg); err != nil {
+			t.Fatalf("%s: %v", s.msg.MessageTypeName(), err)
+		}
+		if got := sess.State(); got != s.want {
+			t.Fatalf("%s: wrong state: want %s, got %s", s.msg.MessageTypeName(), s.want, got)
+		}
+	}
+
+	want := []gtpv2.StateChange{
+		{From: gtpv2.SessionStateCreating, To: gtpv2.SessionStateActive, MsgType: message.MsgTypeCreateSessionResponse},
+		{From: gtpv2.SessionStateActive, To: gtpv2.SessionStateModifying, MsgType: message.MsgTypeReleaseAccessBearersRequest},
+		{From: gtpv2.SessionStateModifying, To: gtpv2.SessionStateIdle, MsgType: message.MsgTypeReleaseAccessBearersResponse},
+		{From: gtpv2.SessionStateIdle, To: gtpv2.SessionStateModifying, MsgType: message.MsgTypeModifyBearerRequest},
+		{From: gtpv2.SessionStateModifying, To: gtpv2.SessionStateIdle, MsgType: message.MsgTypeModifyBearerResponse},
+		{From: gtpv2.SessionStateIdle, To: gtpv2.SessionStateModifying, MsgType: message.MsgTypeModifyBearerRequest},
+		{From: gtpv2.SessionStateModifying, To: gtpv2.SessionStateActive, MsgType: message.MsgTypeModifyBearerResponse},
+		{From: gtpv2.SessionStateActive, To: gtpv2.SessionStateDeleting, MsgType: message.MsgTypeDeleteSessionRequest},
+		{From: gtpv2.SessionStateDeleting, To: gtpv2.SessionStateDeleted, MsgType: message.MsgTypeDeleteSessionResponse},
+	}
+	if diff := cmp.Diff(changes, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestSessionStateCollision(t *testing.T) {
+	checkCause := func(err error, cause uint8) {
+		t.Helper()
+
+		var stateErr *gtpv2.InvalidStateError
+		if !errors.As(err, &stateErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stateErr.Cause != cause {
+			t.Errorf("wrong Cause: want %d, got %d", cause, stateErr.Cause)
+		}
+	}
+
+	sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+	_ = sess.Activate()
+
+	// network initiated request during handover.
+	if err := sess.Transition(message.NewModifyBearerRequest(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	checkCause(
+		sess.Transition(message.NewCreateBearerRequest(1, 2)),
+		gtpv2.CauseTemporarilyRejectedDueToHandoverTAURAUProcedureInProgress,
+	)
+
+	// response without request.
+	checkCause(sess.Transition(message.NewCreateBearerResponse(1, 2)), 0)
+
+	// deletion takes precedence over the modification, and the modification
+	// is not allowed during the deletion.
+	if err := sess.Transition(message.NewDeleteSessionRequest(1, 3)); err != nil {
+		t.Fatal(err)
+	}
+	checkCause(sess.Transition(message.NewModifyBearerRequest(1, 4)), gtpv2.CauseContextNotFound)
+	if got := sess.State(); got != gtpv2.SessionStateDeleting {
+		t.Errorf("state should not be changed: %s", got)
+	}
+}
+
+func TestSessionStateOnConn(t *testing.T) {
+	conn, sess, peer := setupBearerPeer(t)
+
+	var sessChanges, connChanges int
+	sess.OnStateChange(func(*gtpv2.Session, *gtpv2.StateChange) { sessChanges++ })
+	conn.OnSessionStateChange(func(*gtpv2.Session, *gtpv2.StateChange) { connChanges++ })
+	sess.AddTEID(gtpv2.IFTypeS5S8SGWGTPC, 0x11)
+	conn.RegisterSession(0x100, sess)
+
+	mbReqHandled := make(chan struct{}, 1)
+	conn.AddHandler(message.MsgTypeModifyBearerRequest, func(*gtpv2.Conn, net.Addr, message.Message) error {
+		mbReqHandled <- struct{}{}
+		return nil
+	})
+	applied := make(chan error, 1)
+	conn.AddHandler(message.MsgTypeCreateBearerResponse, func(c *gtpv2.Conn, _ net.Addr, msg message.Message) error {
+		_, err := sess.ApplyCreateBearerResponse(msg.(*message.CreateBearerResponse))
+		applied <- err
+		return err
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = conn.Serve(ctx)
+	}()
+
+	tft := ie.New(ie.BearerTFT, 0x00, []byte{0x21, 0x10, 0x04, 0x10, 0x0a, 0x00, 0x00, 0x01})
+	seq, err := conn.CreateBearer(0x11, sess, 5, []*gtpv2.DedicatedBearer{
+		gtpv2.NewDedicatedBearer(0, "", &gtpv2.QoSProfile{PL: 2, QCI: 1}, tft),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = readRequest(t, peer)
+	if got := sess.State(); got != gtpv2.SessionStateModifying {
+		t.Fatalf("wrong state: %s", got)
+	}
+
+	// the request colliding with the pending one is rejected by Conn.
+	send := func(msg message.Message) {
+		t.Helper()
+
+		b, err := message.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := peer.WriteTo(b, conn.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send(message.NewModifyBearerRequest(0x100, 0x200,
+		ie.NewFullyQualifiedTEID(gtpv2.IFTypeS1UeNodeBGTPU, 0x33, "127.0.0.3", ""),
+	))
+	mbRsp, ok := readRequest(t, peer).(*message.ModifyBearerResponse)
+	if !ok {
+		t.Fatal("Modify Bearer Request should be rejected")
+	}
+	// the TEID of the peer in Session, not the one in the F-TEID in the request.
+	if got := mbRsp.TEID(); got != 0x11 {
+		t.Errorf("wrong TEID: %#x", got)
+	}
+	if got := mbRsp.Cause.MustCause(); got != gtpv2.CauseCollisionWithNetworkInitiatedRequest {
+		t.Errorf("wrong Cause: %d", got)
+	}
+	select {
+	case <-mbReqHandled:
+		t.Error("rejected request should not be passed to the handler")
+	default:
+	}
+
+	send(message.NewCreateBearerResponse(0x100, seq,
+		ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil),
+		ie.NewBearerContext(
+			ie.NewEPSBearerID(6), ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil),
+		),
+	))
+	select {
+	case err := <-applied:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if got := sess.State(); got != gtpv2.SessionStateActive {
+		t.Errorf("wrong state: %s", got)
+	}
+
+	// both the handlers are called, for Modifying and Active.
+	if sessChanges != 2 || connChanges != 2 {
+		t.Errorf("wrong number of changes: Session: %d, Conn: %d", sessChanges, connChanges)
+	}
+}
+
+func TestInvalidStateErrorAsCauseError(t *testing.T) {
+	err := error(&gtpv2.InvalidStateError{
+		State: gtpv2.SessionStateDeleting, MsgType: message.MsgTypeModifyBearerRequest,
+		Cause: gtpv2.CauseContextNotFound,
+	})
+
+	var causeErr *gtpv2.CauseError
+	if !errors.As(err, &causeErr) || causeErr.Cause != gtpv2.CauseContextNotFound {
+		t.Errorf("should be converted to CauseError: %v", causeErr)
+	}
+
+	// response cannot be rejected.
+	err = &gtpv2.InvalidStateError{State: gtpv2.SessionStateActive, MsgType: message.MsgTypeModifyBearerResponse}
+	var rspErr *gtpv2.CauseError
+	if errors.As(err, &rspErr) {
+		t.Errorf("should not be converted to CauseError: %v", rspErr)
+	}
+}
+
+func TestSessionStateRetransmission(t *testing.T) {
+	sess := gtpv2.NewSession(dummyAddr, &gtpv2.Subscriber{IMSI: "001011234567891"})
+	_ = sess.Activate()
+
+	// the same request decoded again does not collide with itself.
+	if err := sess.Transition(message.NewModifyBearerRequest(1, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.Transition(message.NewModifyBearerRequest(1, 10)); err != nil {
+		t.Fatalf("retransmission should be allowed: %v", err)
+	}
+	if got := sess.State(); got != gtpv2.SessionStateModifying {
+		t.Errorf("wrong state: %s", got)
+	}
+	var stateErr *gtpv2.InvalidStateError
+	if err := sess.Transition(message.NewModifyBearerRequest(1, 11)); !errors.As(err, &stateErr) {
+		t.Errorf("request with another sequence number should collide: %v", err)
+	}
+
+	// the retransmission received on Conn is discarded while the original one is handled.
+	conn, sess, peer := setupBearerPeer(t)
+	conn.RegisterSession(0x100, sess)
+
+	handled := make(chan message.Message, 2)
+	conn.AddHandler(message.MsgTypeModifyBearerRequest, func(_ *gtpv2.Conn, _ net.Addr, msg message.Message) error {
+		handled <- msg
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = conn.Serve(ctx)
+	}()
+
+	b, err := message.Marshal(message.NewModifyBearerRequest(0x100, 0x200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := peer.WriteTo(b, conn.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var req message.Message
+	select {
+	case req = <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if err := peer.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := peer.ReadFrom(make([]byte, 1500)); err == nil {
+		t.Error("retransmission should not be rejected")
+	}
+	select {
+	case <-handled:
+		t.Error("retransmission should not be passed to the handler")
+	default:
+	}
+
+	if err := conn.RespondTo(peer.LocalAddr(), req, message.NewModifyBearerResponse(
+		0x11, 0, ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil),
+	)); err != nil {
+		t.Fatal(err)
+	}
+	if got := sess.State(); got != gtpv2.SessionStateActive {
+		t.Errorf("wrong state: %s", got)
+	}
+}
+
+func TestSessionStateHandlerFailure(t *testing.T) {
+	conn, sess, peer := setupBearerPeer(t)
+	conn.RegisterSession(0x100, sess)
+
+	failed := make(chan struct{}, 1)
+	conn.AddHandler(message.MsgTypeModifyBearerRequest, func(c *gtpv2.Conn, raddr net.Addr, msg message.Message) error {
+		if msg.Sequence() == 0x200 {
+			failed <- struct{}{}
+			return errors.New("failed to handle")
+		}
+		return c.RespondTo(raddr, msg, message.NewModifyBearerResponse(
+			0x11, 0, ie.NewCause(gtpv2.CauseRequestAccepted, 0, 0, 0, nil),
+		))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = conn.Serve(ctx)
+	}()
+
+	send := func(msg message.Message) {
+		t.Helper()
+
+		b, err := message.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := peer.WriteTo(b, conn.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the state goes back to Active as the handler does not respond.
+	send(message.NewModifyBearerRequest(0x100, 0x200))
+	select {
+	case <-failed:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	deadline := time.Now().Add(time.Second)
+	for sess.State() != gtpv2.SessionStateActive {
+		if time.Now().After(deadline) {
+			t.Fatalf("wrong state: %s", sess.State())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the following request is not rejected as the collision.
+	send(message.NewModifyBearerRequest(0x100, 0x201))
+	mbRsp, ok := readRequest(t, peer).(*message.ModifyBearerResponse)
+	if !ok {
+		t.Fatal("got unexpected message")
+	}
+	if got := mbRsp.Cause.MustCause(); got != gtpv2.CauseRequestAccepted {
+		t.Errorf("wrong Cause: %d", got)
+	}
+}
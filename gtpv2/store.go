@@ -0,0 +1,296 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2
+
+import (
+	"net"
+	"sync"
+)
+
+// SessionStore is the storage of Sessions used by Conn.
+//
+// The default one is the in-memory store created by NewMemorySessionStore, and
+// it can be replaced with the other implementation such as the one backed by disk
+// or key-value store with (*Conn).SetSessionStore, to share Sessions between the
+// processes or to keep them across restarts. Such implementation can use the JSON
+// format provided by Session and Bearer to serialise them.
+//
+// Sessions are looked up by the incoming TEID, IMSI and the address of the peer.
+// A subscriber may have multiple Sessions as PDN connections, which are the same
+// if the EBIs of the default bearers are the same, or if the APNs are the same when
+// the EBI is unknown in any of them.
+//
+// The methods are called concurrently, and the Load* methods should return
+// ErrSessionNotFound when no Session is found.
+type SessionStore interface {
+	// Store stores session with its incoming TEID. If the Session for the same
+	// PDN connection of the subscriber exists, it should be replaced.
+	Store(teid uint32, session *Session) error
+
+	// Reserve marks teid as used without associating any Session with it.
+	// It returns false if teid is already used.
+	Reserve(teid uint32) (bool, error)
+
+	// LoadByTEID returns the Session associated with the incoming TEID.
+	LoadByTEID(teid uint32) (*Session, error)
+
+	// LoadByIMSI returns all the Sessions of the subscriber, in the order of
+	// registration.
+	LoadByIMSI(imsi string) ([]*Session, error)
+
+	// LoadByPeer returns all the Sessions with the peer.
+	LoadByPeer(peer net.Addr) ([]*Session, error)
+
+	// Delete removes session stored with teid. If teid is not associated with
+	// session, e.g., when Conn failed to find the incoming TEID in session, all
+	// the TEIDs associated with session should be removed.
+	Delete(teid uint32, session *Session) error
+
+	// Range calls fn for each Session in the store until fn returns false.
+	Range(fn func(session *Session) bool) error
+}
+
+// memorySessionStore is the default SessionStore that keeps the Sessions in memory.
+type memorySessionStore struct {
+	*imsiSessionMap
+	*iteiSessionMap
+}
+
+// NewMemorySessionStore creates a new SessionStore that keeps the Sessions in memory.
+//
+// This is used by Conn by default.
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{
+		imsiSessionMap: newimsiSessionMap(),
+		iteiSessionMap: newiteiSessionMap(),
+	}
+}
+
+// Store stores session with its incoming TEID.
+func (m *memorySessionStore) Store(teid uint32, session *Session) error {
+	m.iteiSessionMap.store(teid, session)
+	m.imsiSessionMap.store(session.IMSI, session)
+	return nil
+}
+
+// Reserve marks teid as used.
+func (m *memorySessionStore) Reserve(teid uint32) (bool, error) {
+	return m.iteiSessionMap.tryStore(teid, nil), nil
+}
+
+// LoadByTEID returns the Session associated with the incoming TEID.
+func (m *memorySessionStore) LoadByTEID(teid uint32) (*Session, error) {
+	session, ok := m.iteiSessionMap.load(teid)
+	if !ok || session == nil {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// LoadByIMSI returns all the Sessions of the subscriber.
+func (m *memorySessionStore) LoadByIMSI(imsi string) ([]*Session, error) {
+	sessions, ok := m.imsiSessionMap.loadAll(imsi)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return sessions, nil
+}
+
+// LoadByPeer returns all the Sessions with the peer.
+func (m *memorySessionStore) LoadByPeer(peer net.Addr) ([]*Session, error) {
+	var sessions []*Session
+	addr := peer.String()
+	m.imsiSessionMap.rangeWithFunc(func(k, v interface{}) bool {
+		sess := v.(*Session)
+		if sess.peerAddrString == addr {
+			sessions = append(sessions, sess)
+		}
+		return true
+	})
+
+	if len(sessions) == 0 {
+		return nil, ErrSessionNotFound
+	}
+	return sessions, nil
+}
+
+// Delete removes session stored with teid.
+func (m *memorySessionStore) Delete(teid uint32, session *Session) error {
+	m.imsiSessionMap.deleteSession(session.IMSI, session)
+
+	if s, ok := m.iteiSessionMap.load(teid); ok && s == session {
+		m.iteiSessionMap.delete(teid)
+		return nil
+	}
+
+	m.iteiSessionMap.rangeWithFunc(func(k, v interface{}) bool {
+		if s, ok := v.(*Session); ok && s == session {
+			m.iteiSessionMap.delete(k.(uint32))
+		}
+		return true
+	})
+	return nil
+}
+
+// Range calls fn for each Session in the store.
+func (m *memorySessionStore) Range(fn func(session *Session) bool) error {
+	m.imsiSessionMap.rangeWithFunc(func(k, v interface{}) bool {
+		return fn(v.(*Session))
+	})
+	return nil
+}
+
+// imsiSessionMap holds Sessions by IMSI.
+//
+// A subscriber may have several PDN connections at the same time, each of which
+// is a Session distinguished by the EBI of the default bearer or APN. The Sessions
+// are kept in the order of registration.
+type imsiSessionMap struct {
+	mu sync.RWMutex
+	m  map[string][]*Session
+}
+
+func newimsiSessionMap() *imsiSessionMap {
+	return &imsiSessionMap{m: map[string][]*Session{}}
+}
+
+// store adds session to the PDN connections of the subscriber, or replaces the
+// existing one if it is considered as the same PDN connection.
+func (i *imsiSessionMap) store(imsi string, session *Session) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	ss := i.m[imsi]
+	for n, s := range ss {
+		if isSamePDNConnection(s, session) {
+			ss[n] = session
+			return
+		}
+	}
+	i.m[imsi] = append(ss, session)
+}
+
+// load returns the first Session registered with imsi.
+func (i *imsiSessionMap) load(imsi string) (*Session, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	ss := i.m[imsi]
+	if len(ss) == 0 {
+		return nil, false
+	}
+	return ss[0], true
+}
+
+// loadAll returns all the Sessions registered with imsi.
+func (i *imsiSessionMap) loadAll(imsi string) ([]*Session, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	ss := i.m[imsi]
+	if len(ss) == 0 {
+		return nil, false
+	}
+	return append([]*Session(nil), ss...), true
+}
+
+// deleteSession removes only the given session from the ones registered with imsi.
+func (i *imsiSessionMap) deleteSession(imsi string, session *Session) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	ss := i.m[imsi]
+	for n, s := range ss {
+		if s != session {
+			continue
+		}
+
+		ss = append(ss[:n:n], ss[n+1:]...)
+		if len(ss) == 0 {
+			delete(i.m, imsi)
+		} else {
+			i.m[imsi] = ss
+		}
+		return
+	}
+}
+
+// rangeWithFunc calls fn for each Session, with IMSI as a key.
+//
+// fn is called without holding the lock, so it is safe to manipulate the map in fn.
+func (i *imsiSessionMap) rangeWithFunc(fn func(imsi, session interface{}) bool) {
+	i.mu.RLock()
+	var (
+		imsis []string
+		ss    []*Session
+	)
+	for imsi, sessions := range i.m {
+		for _, s := range sessions {
+			imsis = append(imsis, imsi)
+			ss = append(ss, s)
+		}
+	}
+	i.mu.RUnlock()
+
+	for n, s := range ss {
+		if !fn(imsis[n], s) {
+			return
+		}
+	}
+}
+
+// isSamePDNConnection reports whether a and b are considered to be the same PDN
+// connection of a subscriber.
+//
+// They are compared by the EBI of the default bearer if it is known in both,
+// and by APN otherwise. This means that Sessions without any of them are always
+// considered to be the same, as it was when only one PDN connection per
+// subscriber was supported.
+func isSamePDNConnection(a, b *Session) bool {
+	if a == b {
+		return true
+	}
+
+	ebiA, ebiB := a.LinkedEBI(), b.LinkedEBI()
+	if ebiA != 0 && ebiB != 0 {
+		return ebiA == ebiB
+	}
+	return a.APN() == b.APN()
+}
+
+type iteiSessionMap struct {
+	syncMap sync.Map
+}
+
+func newiteiSessionMap() *iteiSessionMap {
+	return &iteiSessionMap{}
+}
+
+func (t *iteiSessionMap) store(teid uint32, session *Session) {
+	t.syncMap.Store(teid, session)
+}
+
+func (t *iteiSessionMap) tryStore(teid uint32, session *Session) bool {
+	_, loaded := t.syncMap.LoadOrStore(teid, session)
+	return !loaded
+}
+
+// load returns the Session associated with teid. It returns nil Session with
+// true if teid is reserved but no Session is associated with it yet.
+func (t *iteiSessionMap) load(teid uint32) (*Session, bool) {
+	session, ok := t.syncMap.Load(teid)
+	if ok && session != nil {
+		return session.(*Session), true
+	}
+	return nil, ok
+}
+
+func (t *iteiSessionMap) delete(teid uint32) {
+	t.syncMap.Delete(teid)
+}
+
+func (t *iteiSessionMap) rangeWithFunc(fn func(imsi, session interface{}) bool) {
+	t.syncMap.Range(fn)
+}
@@ -0,0 +1,241 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/gtpv2"
+)
+
+// kvSessionStore is a stand-in for the key-value store, which holds the Sessions
+// only in the serialised format.
+type kvSessionStore struct {
+	mu sync.Mutex
+	kv map[uint32][]byte
+}
+
+func newKVSessionStore() *kvSessionStore {
+	return &kvSessionStore{kv: map[uint32][]byte{}}
+}
+
+func (k *kvSessionStore) Store(teid uint32, session *gtpv2.Session) error {
+	b, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.kv[teid] = b
+	return nil
+}
+
+func (k *kvSessionStore) Reserve(teid uint32) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.kv[teid]; ok {
+		return false, nil
+	}
+	k.kv[teid] = nil
+	return true, nil
+}
+
+func (k *kvSessionStore) LoadByTEID(teid uint32) (*gtpv2.Session, error) {
+	k.mu.Lock()
+	b, ok := k.kv[teid]
+	k.mu.Unlock()
+	if !ok || b == nil {
+		return nil, gtpv2.ErrSessionNotFound
+	}
+
+	sess := &gtpv2.Session{}
+	if err := json.Unmarshal(b, sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (k *kvSessionStore) loadIf(fn func(*gtpv2.Session) bool) ([]*gtpv2.Session, error) {
+	var sessions []*gtpv2.Session
+	if err := k.Range(func(sess *gtpv2.Session) bool {
+		if fn(sess) {
+			sessions = append(sessions, sess)
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(sessions) == 0 {
+		return nil, gtpv2.ErrSessionNotFound
+	}
+	return sessions, nil
+}
+
+func (k *kvSessionStore) LoadByIMSI(imsi string) ([]*gtpv2.Session, error) {
+	return k.loadIf(func(sess *gtpv2.Session) bool {
+		return sess.IMSI == imsi
+	})
+}
+
+func (k *kvSessionStore) LoadByPeer(peer net.Addr) ([]*gtpv2.Session, error) {
+	return k.loadIf(func(sess *gtpv2.Session) bool {
+		return sess.PeerAddr().String() == peer.String()
+	})
+}
+
+func (k *kvSessionStore) Delete(teid uint32, session *gtpv2.Session) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.kv, teid)
+	return nil
+}
+
+func (k *kvSessionStore) Range(fn func(session *gtpv2.Session) bool) error {
+	k.mu.Lock()
+	var bs [][]byte
+	for _, b := range k.kv {
+		if b != nil {
+			bs = append(bs, b)
+		}
+	}
+	k.mu.Unlock()
+
+	for _, b := range bs {
+		sess := &gtpv2.Session{}
+		if err := json.Unmarshal(b, sess); err != nil {
+			return err
+		}
+		if !fn(sess) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func TestSessionJSON(t *testing.T) {
+	peer := &net.UDPAddr{IP: net.IP{127, 0, 0, 2}, Port: 2123}
+	sess := gtpv2.NewSession(peer, &gtpv2.Subscriber{
+		IMSI: "001011234567891", MSISDN: "8130900000000", IMEI: "123456786543210",
+		Location: &gtpv2.Location{MCC: "001", MNC: "01", TAI: 1, ECI: 2},
+	})
+	_ = sess.Activate()
+	sess.AddTEID(gtpv2.IFTypeS11MMEGTPC, 0x11111111)
+	sess.AddTEID(gtpv2.IFTypeS11S4SGWGTPC, 0x22222222)
+
+	br := sess.GetDefaultBearer()
+	br.EBI = 5
+	br.APN = "some.apn.example"
+	br.SubscriberIP = "10.10.10.10"
+	br.ChargingID = 1
+	br.QoSProfile = &gtpv2.QoSProfile{PL: 2, QCI: 9, MBRUL: 0x1111, MBRDL: 0x2222}
+	br.SetIncomingTEID(0x33333333)
+	br.SetOutgoingTEID(0x44444444)
+	br.SetRemoteAddress(&net.UDPAddr{IP: net.IP{127, 0, 0, 3}, Port: 2152})
+	sess.AddBearer("dedicated", gtpv2.NewBearer(6, "some.apn.example", &gtpv2.QoSProfile{QCI: 1}))
+
+	b, err := json.Marshal(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := &gtpv2.Session{}
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(got.Subscriber, sess.Subscriber); diff != "" {
+		t.Error(diff)
+	}
+	if !got.IsActive() {
+		t.Error("session should be active")
+	}
+	if got.PeerAddr().String() != peer.String() {
+		t.Errorf("wrong peer: %s", got.PeerAddr())
+	}
+	for _, ifType := range []uint8{gtpv2.IFTypeS11MMEGTPC, gtpv2.IFTypeS11S4SGWGTPC} {
+		want, _ := sess.GetTEID(ifType)
+		if teid, err := got.GetTEID(ifType); err != nil || teid != want {
+			t.Errorf("wrong TEID for %d: %#x, %v", ifType, teid, err)
+		}
+	}
+
+	gotBr := got.GetDefaultBearer()
+	if gotBr == nil {
+		t.Fatal("default bearer not found")
+	}
+	if diff := cmp.Diff(
+		[]interface{}{gotBr.EBI, gotBr.APN, gotBr.SubscriberIP, gotBr.ChargingID, gotBr.QoSProfile},
+		[]interface{}{br.EBI, br.APN, br.SubscriberIP, br.ChargingID, br.QoSProfile},
+	); diff != "" {
+		t.Error(diff)
+	}
+	if gotBr.IncomingTEID() != br.IncomingTEID() || gotBr.OutgoingTEID() != br.OutgoingTEID() {
+		t.Errorf("wrong bearer TEIDs: %#x, %#x", gotBr.IncomingTEID(), gotBr.OutgoingTEID())
+	}
+	if gotBr.RemoteAddress().String() != br.RemoteAddress().String() {
+		t.Errorf("wrong bearer remote address: %s", gotBr.RemoteAddress())
+	}
+	if _, err := got.LookupBearerByEBI(6); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	peer := &net.UDPAddr{IP: net.IP{127, 0, 0, 2}, Port: 2123}
+	conn := gtpv2.NewConn(dummyAddr, gtpv2.IFTypeS11S4SGWGTPC, 0)
+	conn.SetSessionStore(newKVSessionStore())
+
+	for i, imsi := range []string{"001011234567891", "001011234567892"} {
+		sess := gtpv2.NewSession(peer, &gtpv2.Subscriber{IMSI: imsi})
+		_ = sess.Activate()
+		conn.RegisterSession(uint32(i+1), sess)
+	}
+
+	if got := conn.SessionCount(); got != 2 {
+		t.Errorf("SessionCount is invalid. want: 2, got: %d", got)
+	}
+
+	sess, err := conn.GetSessionByTEID(2, peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.IMSI != "001011234567892" {
+		t.Errorf("got wrong session: %s", sess.IMSI)
+	}
+	if _, err := conn.GetSessionByTEID(2, dummyAddr); err == nil {
+		t.Error("should fail with unknown peer")
+	}
+	if _, err := conn.GetSessionByIMSI("001011234567891"); err != nil {
+		t.Error(err)
+	}
+
+	fteid := conn.NewSenderFTEID("127.0.0.1", "")
+	teid, err := fteid.TEID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.GetSessionByTEID(teid, peer); err == nil {
+		t.Error("reserved TEID should not have Session")
+	}
+
+	conn.RemoveSession(sess)
+	if _, err := conn.GetSessionByTEID(2, peer); err == nil {
+		t.Error("TEID of removed session should not be found")
+	}
+
+	conn.RemoveSessionsByPeer(peer)
+	if ss, err := conn.GetSessionsByPeer(peer); err != nil || len(ss) != 0 {
+		t.Errorf("sessions should be removed: %v, %v", ss, err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtpv2_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp"
+	"github.com/wmnsk/go-gtp/gtpv2"
+)
+
+// tapped is the comparable part of gtp.Capture.
+type tapped struct {
+	Direction gtp.Direction
+	Remote    string
+	Name      string
+	HasErr    bool
+}
+
+func waitCaptures(t *testing.T, rec *gtp.Recorder, n int) []tapped {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(rec.Captures()) < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var got []tapped
+	for _, c := range rec.Captures() {
+		tp := tapped{Direction: c.Direction, Remote: c.RemoteAddr.String(), HasErr: c.Err != nil}
+		if c.Message != nil {
+			tp.Name = c.Message.MessageTypeName()
+		}
+		got = append(got, tp)
+	}
+	return got
+}
+
+func TestTap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srvRec := gtp.NewRecorder()
+	srvConn := gtpv2.NewConn(&net.UDPAddr{IP: net.IP{127, 0, 0, 1}}, gtpv2.IFTypeS11S4SGWGTPC, 0)
+	srvConn.SetTap(srvRec.Tap)
+	if err := srvConn.Listen(ctx); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if err := srvConn.Serve(ctx); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	cliRec := gtp.NewRecorder()
+	cliConn := gtpv2.NewConn(&net.UDPAddr{IP: net.IP{127, 0, 0, 1}}, gtpv2.IFTypeS11MMEGTPC, 0)
+	cliConn.SetTap(cliRec.Tap)
+	if err := cliConn.Listen(ctx); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if err := cliConn.Serve(ctx); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	if _, err := cliConn.EchoRequest(srvConn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	srvAddr, cliAddr := srvConn.LocalAddr().String(), cliConn.LocalAddr().String()
+	want := []tapped{
+		{gtp.DirectionSent, srvAddr, "Echo Request", false},
+		{gtp.DirectionReceived, srvAddr, "Echo Response", false},
+	}
+	if diff := cmp.Diff(waitCaptures(t, cliRec, 2), want); diff != "" {
+		t.Error(diff)
+	}
+
+	// the datagram that cannot be decoded is also passed to the tap.
+	if _, err := cliConn.WriteTo([]byte{0x48, 0x01}, srvConn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	want = []tapped{
+		{gtp.DirectionReceived, cliAddr, "Echo Request", false},
+		{gtp.DirectionSent, cliAddr, "Echo Response", false},
+		{gtp.DirectionReceived, cliAddr, "", true},
+	}
+	if diff := cmp.Diff(waitCaptures(t, srvRec, 3), want); diff != "" {
+		t.Error(diff)
+	}
+
+	srvConn.SetTap(nil)
+	if _, err := cliConn.EchoRequest(srvConn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	waitCaptures(t, cliRec, 5)
+	if got := len(srvRec.Captures()); got != 3 {
+		t.Errorf("tap is called after removed: %d", got)
+	}
+}
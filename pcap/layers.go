@@ -0,0 +1,247 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+const (
+	etherTypeIPv4  = 0x0800
+	etherTypeIPv6  = 0x86dd
+	etherTypeVLAN  = 0x8100
+	etherTypeQinQ  = 0x88a8
+	etherTypeVLAN2 = 0x9100
+
+	ipProtoUDP = 17
+
+	ethernetHeaderLen = 14
+	ipv4HeaderLen     = 20
+	ipv6HeaderLen     = 40
+	udpHeaderLen      = 8
+)
+
+// datagram is the decoded UDP datagram.
+type datagram struct {
+	src, dst *net.UDPAddr
+	payload  []byte
+}
+
+// decodeLink decodes the frame in the link type and returns the UDP datagram in
+// it. It returns false if the frame does not contain a complete UDP datagram.
+func decodeLink(linkType uint16, b []byte) (*datagram, bool) {
+	switch linkType {
+	case LinkTypeEthernet:
+		if len(b) < ethernetHeaderLen {
+			return nil, false
+		}
+		etherType := binary.BigEndian.Uint16(b[12:14])
+		b = b[ethernetHeaderLen:]
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ || etherType == etherTypeVLAN2 {
+			if len(b) < 4 {
+				return nil, false
+			}
+			etherType = binary.BigEndian.Uint16(b[2:4])
+			b = b[4:]
+		}
+		return decodeEtherType(etherType, b)
+	case LinkTypeLinuxSLL:
+		if len(b) < 16 {
+			return nil, false
+		}
+		return decodeEtherType(binary.BigEndian.Uint16(b[14:16]), b[16:])
+	case LinkTypeLinuxSLL2:
+		if len(b) < 20 {
+			return nil, false
+		}
+		return decodeEtherType(binary.BigEndian.Uint16(b[0:2]), b[20:])
+	case LinkTypeNull, LinkTypeLoop:
+		// the address family differs by platform, so the IP version is used instead.
+		if len(b) < 4 {
+			return nil, false
+		}
+		return decodeIP(b[4:])
+	case LinkTypeRaw, LinkTypeIPv4, LinkTypeIPv6:
+		return decodeIP(b)
+	default:
+		return nil, false
+	}
+}
+
+func decodeEtherType(etherType uint16, b []byte) (*datagram, bool) {
+	switch etherType {
+	case etherTypeIPv4, etherTypeIPv6:
+		return decodeIP(b)
+	default:
+		return nil, false
+	}
+}
+
+// decodeIP decodes IPv4 or IPv6 packet. The fragmented packets are ignored, as
+// they cannot be decoded without reassembly.
+func decodeIP(b []byte) (*datagram, bool) {
+	if len(b) < 1 {
+		return nil, false
+	}
+
+	var (
+		src, dst net.IP
+		payload  []byte
+	)
+	switch b[0] >> 4 {
+	case 4:
+		if len(b) < ipv4HeaderLen {
+			return nil, false
+		}
+		hl := int(b[0]&0x0f) * 4
+		l := int(binary.BigEndian.Uint16(b[2:4]))
+		if hl < ipv4HeaderLen || l < hl || len(b) < l {
+			return nil, false
+		}
+		// MF flag or fragment offset
+		if binary.BigEndian.Uint16(b[6:8])&0x3fff != 0 {
+			return nil, false
+		}
+		if b[9] != ipProtoUDP {
+			return nil, false
+		}
+		src, dst = net.IP(b[12:16]), net.IP(b[16:20])
+		payload = b[hl:l]
+	case 6:
+		if len(b) < ipv6HeaderLen {
+			return nil, false
+		}
+		l := ipv6HeaderLen + int(binary.BigEndian.Uint16(b[4:6]))
+		if len(b) < l {
+			return nil, false
+		}
+		src, dst = net.IP(b[8:24]), net.IP(b[24:40])
+
+		next, offset := b[6], ipv6HeaderLen
+		for next != ipProtoUDP {
+			switch next {
+			case 0, 43, 60: // Hop-by-Hop, Routing, Destination Options
+				if l < offset+8 {
+					return nil, false
+				}
+				next = b[offset]
+				offset += (int(b[offset+1]) + 1) * 8
+			default: // including Fragment
+				return nil, false
+			}
+			if l < offset {
+				return nil, false
+			}
+		}
+		payload = b[offset:l]
+	default:
+		return nil, false
+	}
+
+	return decodeUDP(src, dst, payload)
+}
+
+func decodeUDP(src, dst net.IP, b []byte) (*datagram, bool) {
+	if len(b) < udpHeaderLen {
+		return nil, false
+	}
+	l := int(binary.BigEndian.Uint16(b[4:6]))
+	if l < udpHeaderLen || len(b) < l {
+		return nil, false
+	}
+
+	return &datagram{
+		src:     &net.UDPAddr{IP: copyIP(src), Port: int(binary.BigEndian.Uint16(b[0:2]))},
+		dst:     &net.UDPAddr{IP: copyIP(dst), Port: int(binary.BigEndian.Uint16(b[2:4]))},
+		payload: b[udpHeaderLen:l],
+	}, true
+}
+
+func copyIP(ip net.IP) net.IP {
+	c := make(net.IP, len(ip))
+	copy(c, ip)
+	return c
+}
+
+// encodeEthernet encodes the UDP datagram into Ethernet frame. The MAC addresses
+// are left zero, as they are not known from the datagram.
+func encodeEthernet(src, dst *net.UDPAddr, payload []byte) ([]byte, bool) {
+	src4, dst4 := src.IP.To4(), dst.IP.To4()
+	isV4 := src4 != nil && dst4 != nil
+
+	l := ethernetHeaderLen + udpHeaderLen + len(payload)
+	if isV4 {
+		l += ipv4HeaderLen
+	} else {
+		l += ipv6HeaderLen
+	}
+	b := make([]byte, l)
+
+	offset := ethernetHeaderLen
+	var udp []byte
+	if isV4 {
+		binary.BigEndian.PutUint16(b[12:14], etherTypeIPv4)
+		ip := b[offset : offset+ipv4HeaderLen]
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(l-offset))
+		ip[8] = 64
+		ip[9] = ipProtoUDP
+		copy(ip[12:16], src4)
+		copy(ip[16:20], dst4)
+		binary.BigEndian.PutUint16(ip[10:12], ^checksum(0, ip))
+		udp = b[offset+ipv4HeaderLen:]
+	} else {
+		src16, dst16 := src.IP.To16(), dst.IP.To16()
+		if src16 == nil || dst16 == nil {
+			return nil, false
+		}
+		binary.BigEndian.PutUint16(b[12:14], etherTypeIPv6)
+		ip := b[offset : offset+ipv6HeaderLen]
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:6], uint16(udpHeaderLen+len(payload)))
+		ip[6] = ipProtoUDP
+		ip[7] = 64
+		copy(ip[8:24], src16)
+		copy(ip[24:40], dst16)
+		udp = b[offset+ipv6HeaderLen:]
+	}
+
+	binary.BigEndian.PutUint16(udp[0:2], uint16(src.Port))
+	binary.BigEndian.PutUint16(udp[2:4], uint16(dst.Port))
+	binary.BigEndian.PutUint16(udp[4:6], uint16(len(udp)))
+	copy(udp[udpHeaderLen:], payload)
+
+	// checksum with pseudo header.
+	var pseudo []byte
+	if isV4 {
+		pseudo = append(append(append(pseudo, src4...), dst4...), 0, ipProtoUDP)
+	} else {
+		pseudo = append(append(append(pseudo, src.IP.To16()...), dst.IP.To16()...), 0, 0, 0, ipProtoUDP)
+	}
+	pseudo = append(pseudo, udp[4:6]...)
+	sum := ^checksum(checksum(0, pseudo), udp)
+	if sum == 0 {
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(udp[6:8], sum)
+
+	return b, true
+}
+
+// checksum adds b to the ones' complement sum.
+func checksum(sum uint16, b []byte) uint16 {
+	s := uint32(sum)
+	for i := 0; i+1 < len(b); i += 2 {
+		s += uint32(binary.BigEndian.Uint16(b[i : i+2]))
+	}
+	if len(b)%2 == 1 {
+		s += uint32(b[len(b)-1]) << 8
+	}
+	for s > 0xffff {
+		s = (s >> 16) + (s & 0xffff)
+	}
+	return uint16(s)
+}
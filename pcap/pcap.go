@@ -0,0 +1,101 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package pcap provides the reader and writer of GTP messages in pcap and pcapng
+// files, implemented in pure Go without libpcap.
+//
+// Reader reads the UDP datagrams on the GTP ports over Ethernet, Linux cooked
+// capture(SLL and SLL2), loopback or raw IP link in both IPv4 and IPv6, and
+// decodes them as any version of gtp.Message. Writer writes the messages into pcap
+// file in Ethernet link type so that they can be opened in Wireshark and others.
+package pcap
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/wmnsk/go-gtp"
+)
+
+// The UDP ports that GTP uses.
+const (
+	PortGTPC  uint16 = 2123
+	PortGTPU  uint16 = 2152
+	PortGTPv0 uint16 = 3386
+)
+
+// DefaultPorts is the list of UDP ports on which Reader looks for GTP messages by default.
+var DefaultPorts = []uint16{PortGTPC, PortGTPU, PortGTPv0}
+
+// The link types supported by Reader.
+//
+// See https://www.tcpdump.org/linktypes.html for the complete list.
+const (
+	LinkTypeNull      uint16 = 0
+	LinkTypeEthernet  uint16 = 1
+	LinkTypeRaw       uint16 = 101
+	LinkTypeLoop      uint16 = 108
+	LinkTypeLinuxSLL  uint16 = 113
+	LinkTypeIPv4      uint16 = 228
+	LinkTypeIPv6      uint16 = 229
+	LinkTypeLinuxSLL2 uint16 = 276
+)
+
+var (
+	// ErrInvalidFormat indicates that the file is neither pcap nor pcapng.
+	ErrInvalidFormat = errors.New("not a pcap or pcapng file")
+
+	// ErrTruncated indicates that the file ends in the middle of a record.
+	ErrTruncated = errors.New("file is truncated")
+)
+
+// UnsupportedLinkTypeError indicates that the link type of the capture is not supported.
+type UnsupportedLinkTypeError struct {
+	LinkType uint16
+}
+
+// Error returns error message with the link type.
+func (e *UnsupportedLinkTypeError) Error() string {
+	return fmt.Sprintf("unsupported link type: %d", e.LinkType)
+}
+
+// Packet is a UDP datagram that carries GTP message.
+//
+// The transport protocol is always UDP, so Src and Dst makes the 5-tuple.
+type Packet struct {
+	Timestamp time.Time
+	Src, Dst  *net.UDPAddr
+
+	// Payload is the UDP payload, and Message is the GTP message decoded from
+	// it. Message is nil and Err is set if the payload cannot be decoded.
+	Payload []byte
+	Message gtp.Message
+	Err     error
+}
+
+// NewPacket creates a new Packet with a Message.
+//
+// The Payload is not set, as Writer serializes the Message when it is empty.
+func NewPacket(ts time.Time, src, dst *net.UDPAddr, msg gtp.Message) *Packet {
+	return &Packet{
+		Timestamp: ts,
+		Src:       src,
+		Dst:       dst,
+		Message:   msg,
+	}
+}
+
+// String returns the summary of the Packet.
+func (p *Packet) String() string {
+	name := "<nil>"
+	switch {
+	case p.Err != nil:
+		name = fmt.Sprintf("<%s>", p.Err)
+	case p.Message != nil:
+		name = p.Message.MessageTypeName()
+	}
+	return fmt.Sprintf("%s %s -> %s: %s", p.Timestamp.Format(time.RFC3339Nano), p.Src, p.Dst, name)
+}
@@ -0,0 +1,278 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package pcap_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp"
+	v0msg "github.com/wmnsk/go-gtp/gtpv0/message"
+	v1msg "github.com/wmnsk/go-gtp/gtpv1/message"
+	v2ie "github.com/wmnsk/go-gtp/gtpv2/ie"
+	v2msg "github.com/wmnsk/go-gtp/gtpv2/message"
+	"github.com/wmnsk/go-gtp/pcap"
+)
+
+var (
+	ts = time.Date(2021, 4, 1, 12, 34, 56, 123456789, time.UTC)
+
+	sgwC = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 112), Port: 2123}
+	pgwC = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 52), Port: 2123}
+	enbU = &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 2152}
+	sgwU = &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 2152}
+)
+
+// summary is the comparable part of pcap.Packet.
+type summary struct {
+	Timestamp time.Time
+	Src, Dst  string
+	Payload   []byte
+	Name      string
+}
+
+func summarize(p *pcap.Packet) summary {
+	s := summary{
+		Timestamp: p.Timestamp.UTC(),
+		Src:       p.Src.String(),
+		Dst:       p.Dst.String(),
+		Payload:   p.Payload,
+	}
+	if p.Message != nil {
+		s.Name = p.Message.MessageTypeName()
+	}
+	return s
+}
+
+func readAll(t *testing.T, b []byte) []summary {
+	t.Helper()
+
+	r, err := pcap.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []summary
+	for {
+		p, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return got
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Err != nil {
+			t.Fatal(p.Err)
+		}
+		got = append(got, summarize(p))
+	}
+}
+
+func mustMarshal(t *testing.T, m gtp.Message) []byte {
+	t.Helper()
+	b, err := gtp.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestWriteAndRead(t *testing.T) {
+	csReq := v2msg.NewCreateSessionRequest(0, 1, v2ie.NewIMSI("123451234567890"))
+	tpdu := v1msg.NewTPDU(0x11223344, []byte{0xde, 0xad, 0xbe, 0xef})
+	v0echo := v0msg.NewEchoRequest(1, 0, 0)
+
+	var buf bytes.Buffer
+	w, err := pcap.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteMessage(ts, sgwC, pgwC, csReq); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteMessage(ts.Add(time.Millisecond), enbU, sgwU, tpdu); err != nil {
+		t.Fatal(err)
+	}
+	// not on the GTP ports.
+	if err := w.WritePacket(&pcap.Packet{
+		Timestamp: ts,
+		Src:       &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53},
+		Dst:       &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 10053},
+		Payload:   []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePacket(pcap.NewPacket(
+		ts.Add(time.Second),
+		&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3386},
+		&net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 3386},
+		v0echo,
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []summary{
+		{ts, "127.0.0.112:2123", "127.0.0.52:2123", mustMarshal(t, csReq), "Create Session Request"},
+		{ts.Add(time.Millisecond), "[2001:db8::1]:2152", "[2001:db8::2]:2152", mustMarshal(t, tpdu), "T-PDU"},
+		{ts.Add(time.Second), "127.0.0.1:3386", "127.0.0.2:3386", mustMarshal(t, v0echo), "Echo Request"},
+	}
+	if diff := cmp.Diff(readAll(t, buf.Bytes()), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestReadPcapng(t *testing.T) {
+	echo := mustMarshal(t, v2msg.NewEchoRequest(1, v2ie.NewRecovery(1)))
+	ip := ipv4UDP(net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), 2123, 2123, echo)
+
+	// Linux cooked capture(SLL) with ARPHRD_ETHER and IPv4.
+	sll := append([]byte{0, 0, 0, 1, 0, 6, 0, 0, 0, 0, 0, 0, 0, 0, 0x08, 0x00}, ip...)
+
+	var b []byte
+	b = append(b, block(0x0a0d0d0a, []byte{
+		0x4d, 0x3c, 0x2b, 0x1a, // byte order magic
+		0x01, 0x00, 0x00, 0x00, // version
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // section length
+	})...)
+	// interface 0: SLL with the timestamps in nanoseconds.
+	b = append(b, block(0x00000001, []byte{
+		113, 0, 0, 0, 0, 0, 0, 0,
+		9, 0, 1, 0, 9, 0, 0, 0, // if_tsresol
+		0, 0, 0, 0, // opt_endofopt
+	})...)
+	// interface 1: unsupported link type.
+	b = append(b, block(0x00000001, []byte{0xff, 0xff, 0, 0, 0, 0, 0, 0})...)
+	b = append(b, epb(1, ts, sll)...)
+	b = append(b, epb(0, ts, sll)...)
+
+	want := []summary{
+		{ts, "10.0.0.1:2123", "10.0.0.2:2123", echo, "Echo Request"},
+	}
+	if diff := cmp.Diff(readAll(t, b), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestWriteLargePacket(t *testing.T) {
+	// the largest UDP payload over IPv4.
+	payload := make([]byte, 65507)
+	copy(payload, mustMarshal(t, v2msg.NewEchoRequest(1)))
+
+	var buf bytes.Buffer
+	w, err := pcap.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePacket(&pcap.Packet{Timestamp: ts, Src: sgwC, Dst: pgwC, Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	snapLen, capLen := binary.LittleEndian.Uint32(b[16:20]), binary.LittleEndian.Uint32(b[32:36])
+	if capLen > snapLen {
+		t.Errorf("caplen %d exceeds snaplen %d", capLen, snapLen)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := pcap.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteMessage(ts, sgwC, pgwC, v2msg.NewEchoRequest(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("invalid format", func(t *testing.T) {
+		if _, err := pcap.NewReader(bytes.NewReader([]byte("not a pcap file, but long enough"))); !errors.Is(err, pcap.ErrInvalidFormat) {
+			t.Errorf("got %v, want %v", err, pcap.ErrInvalidFormat)
+		}
+	})
+	t.Run("truncated", func(t *testing.T) {
+		r, err := pcap.NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Next(); !errors.Is(err, pcap.ErrTruncated) {
+			t.Errorf("got %v, want %v", err, pcap.ErrTruncated)
+		}
+	})
+	t.Run("unsupported link type", func(t *testing.T) {
+		b := append([]byte{}, buf.Bytes()...)
+		binary.LittleEndian.PutUint32(b[20:24], 0xffff)
+		var lerr *pcap.UnsupportedLinkTypeError
+		if _, err := pcap.NewReader(bytes.NewReader(b)); !errors.As(err, &lerr) {
+			t.Errorf("got %v, want UnsupportedLinkTypeError", err)
+		}
+	})
+}
+
+func TestWriteCapture(t *testing.T) {
+	echo := mustMarshal(t, v2msg.NewEchoRequest(1, v2ie.NewRecovery(1)))
+	c := gtp.NewCapture(gtp.DirectionReceived, sgwC, pgwC, echo)
+	c.Timestamp = ts
+
+	var buf bytes.Buffer
+	w, err := pcap.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteCapture(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// the received datagram is from the remote address.
+	want := []summary{
+		{ts, "127.0.0.52:2123", "127.0.0.112:2123", echo, "Echo Request"},
+	}
+	if diff := cmp.Diff(readAll(t, buf.Bytes()), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func ipv4UDP(src, dst net.IP, sport, dport uint16, payload []byte) []byte {
+	b := make([]byte, 28+len(payload))
+	b[0] = 0x45
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
+	b[8], b[9] = 64, 17
+	copy(b[12:16], src.To4())
+	copy(b[16:20], dst.To4())
+	binary.BigEndian.PutUint16(b[20:22], sport)
+	binary.BigEndian.PutUint16(b[22:24], dport)
+	binary.BigEndian.PutUint16(b[24:26], uint16(8+len(payload)))
+	copy(b[28:], payload)
+	return b
+}
+
+// block builds a block of pcapng in little endian.
+func block(typ uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	b := make([]byte, 8, 12+len(body))
+	binary.LittleEndian.PutUint32(b[0:4], typ)
+	binary.LittleEndian.PutUint32(b[4:8], uint32(12+len(body)))
+	b = append(b, body...)
+	return append(b, b[4:8]...)
+}
+
+func epb(id uint32, ts time.Time, frame []byte) []byte {
+	body := make([]byte, 20, 20+len(frame))
+	n := uint64(ts.UnixNano())
+	binary.LittleEndian.PutUint32(body[0:4], id)
+	binary.LittleEndian.PutUint32(body[4:8], uint32(n>>32))
+	binary.LittleEndian.PutUint32(body[8:12], uint32(n))
+	binary.LittleEndian.PutUint32(body[12:16], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(body[16:20], uint32(len(frame)))
+	return block(0x00000006, append(body, frame...))
+}
@@ -0,0 +1,384 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"time"
+
+	"github.com/wmnsk/go-gtp"
+)
+
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	magicNanoseconds  = 0xa1b23c4d
+
+	blockTypeSHB   = 0x0a0d0d0a
+	blockTypeIDB   = 0x00000001
+	blockTypeOPB   = 0x00000002
+	blockTypeSPB   = 0x00000003
+	blockTypeEPB   = 0x00000006
+	byteOrderMagic = 0x1a2b3c4d
+
+	optionEndOfOpt = 0
+	optionTSResol  = 9
+	optionTSOffset = 14
+
+	// maxRecordLen is the sanity limit of the length of a record or block, to avoid
+	// allocating a huge buffer for broken files.
+	maxRecordLen = 1 << 26
+)
+
+// iface is the interface described in the Interface Description Block of pcapng.
+type iface struct {
+	linkType uint16
+	snapLen  uint32
+
+	// unitsPerSec is the number of timestamp units per second, and offset is
+	// the seconds to be added to the timestamps.
+	unitsPerSec uint64
+	offset      int64
+}
+
+// Reader reads the GTP messages from pcap or pcapng file.
+type Reader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	ports map[uint16]bool
+
+	// ifaces is the list of interfaces in the current section of pcapng, or
+	// the single interface described in the file header of pcap.
+	ifaces []*iface
+	ng     bool
+}
+
+// NewReader creates a new Reader that reads from r. The format of the file, pcap or
+// pcapng, is detected automatically.
+//
+// It reads the UDP datagrams on the DefaultPorts. Use SetPorts to change them.
+func NewReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{r: bufio.NewReader(r)}
+	rd.SetPorts(DefaultPorts...)
+
+	head, err := rd.r.Peek(4)
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+
+	if binary.BigEndian.Uint32(head) == blockTypeSHB {
+		rd.ng = true
+		if _, _, err := rd.readBlock(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, ErrInvalidFormat
+			}
+			return nil, err
+		}
+		return rd, nil
+	}
+
+	if err := rd.readFileHeader(); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// SetPorts sets the UDP ports to look for the GTP messages. The datagram is decoded
+// if either the source or destination port is in ports. If no port is given, all
+// the UDP datagrams are decoded.
+func (r *Reader) SetPorts(ports ...uint16) {
+	r.ports = make(map[uint16]bool, len(ports))
+	for _, p := range ports {
+		r.ports[p] = true
+	}
+}
+
+// Next returns the next Packet that is sent over UDP on the ports to look for. The
+// other packets, including fragmented IP packets and the ones on the interface with
+// unsupported link type in pcapng, are skipped.
+//
+// The Packet is returned with Err set if the payload cannot be decoded as GTP.
+// It returns io.EOF at the end of the file.
+func (r *Reader) Next() (*Packet, error) {
+	for {
+		var (
+			ifc   *iface
+			ts    uint64
+			frame []byte
+			err   error
+		)
+		if r.ng {
+			ifc, ts, frame, err = r.nextBlockPacket()
+		} else {
+			ifc, ts, frame, err = r.nextRecord()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		d, ok := decodeLink(ifc.linkType, frame)
+		if !ok {
+			continue
+		}
+		if len(r.ports) > 0 && !r.ports[uint16(d.src.Port)] && !r.ports[uint16(d.dst.Port)] {
+			continue
+		}
+
+		p := &Packet{
+			Timestamp: ifc.time(ts),
+			Src:       d.src,
+			Dst:       d.dst,
+			Payload:   d.payload,
+		}
+		p.Message, p.Err = gtp.Parse(d.payload)
+		return p, nil
+	}
+}
+
+// time converts the timestamp in the units of the interface into time.Time.
+func (i *iface) time(ts uint64) time.Time {
+	sec, rem := ts/i.unitsPerSec, ts%i.unitsPerSec
+	hi, lo := bits.Mul64(rem, uint64(time.Second))
+	nsec, _ := bits.Div64(hi, lo, i.unitsPerSec)
+	return time.Unix(int64(sec)+i.offset, int64(nsec))
+}
+
+func (r *Reader) readFull(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrTruncated
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *Reader) readFileHeader() error {
+	b, err := r.readFull(24)
+	if err != nil {
+		return ErrInvalidFormat
+	}
+
+	ifc := &iface{}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(b[0:4]) {
+		case magicMicroseconds:
+			r.order, ifc.unitsPerSec = order, uint64(time.Second/time.Microsecond)
+		case magicNanoseconds:
+			r.order, ifc.unitsPerSec = order, uint64(time.Second)
+		}
+	}
+	if r.order == nil {
+		return ErrInvalidFormat
+	}
+
+	ifc.offset = int64(int32(r.order.Uint32(b[8:12])))
+	ifc.snapLen = r.order.Uint32(b[16:20])
+	// the upper bits are used for FCS information.
+	ifc.linkType = uint16(r.order.Uint32(b[20:24]))
+	if !isSupportedLinkType(ifc.linkType) {
+		return &UnsupportedLinkTypeError{LinkType: ifc.linkType}
+	}
+
+	r.ifaces = []*iface{ifc}
+	return nil
+}
+
+func (r *Reader) nextRecord() (*iface, uint64, []byte, error) {
+	hdr, err := r.readFull(16)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	capLen := r.order.Uint32(hdr[8:12])
+	if capLen > maxRecordLen {
+		return nil, 0, nil, ErrInvalidFormat
+	}
+	frame, err := r.readFull(int(capLen))
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, nil, ErrTruncated
+		}
+		return nil, 0, nil, err
+	}
+
+	ifc := r.ifaces[0]
+	ts := uint64(r.order.Uint32(hdr[0:4]))*ifc.unitsPerSec + uint64(r.order.Uint32(hdr[4:8]))
+	return ifc, ts, frame, nil
+}
+
+// readBlock reads a block of pcapng and returns its type and body. The Section
+// Header Block is handled here, as it changes the byte order and interfaces.
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	hdr, err := r.readFull(8)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	typ := binary.BigEndian.Uint32(hdr[0:4])
+	if typ == blockTypeSHB {
+		magic, err := r.readFull(4)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == byteOrderMagic:
+			r.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == byteOrderMagic:
+			r.order = binary.BigEndian
+		default:
+			return 0, nil, ErrInvalidFormat
+		}
+		r.ifaces = nil
+
+		total := r.order.Uint32(hdr[4:8])
+		if total < 28 || total%4 != 0 || total > maxRecordLen {
+			return 0, nil, ErrInvalidFormat
+		}
+		// the rest of SHB(version, section length and options) is not needed.
+		if _, err := r.readFull(int(total) - 12); err != nil {
+			return 0, nil, ErrTruncated
+		}
+		return typ, nil, nil
+	}
+	if r.order == nil {
+		return 0, nil, ErrInvalidFormat
+	}
+	typ = r.order.Uint32(hdr[0:4])
+
+	total := r.order.Uint32(hdr[4:8])
+	if total < 12 || total%4 != 0 || total > maxRecordLen {
+		return 0, nil, ErrInvalidFormat
+	}
+	b, err := r.readFull(int(total) - 8)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, ErrTruncated
+		}
+		return 0, nil, err
+	}
+	return typ, b[:len(b)-4], nil
+}
+
+func (r *Reader) nextBlockPacket() (*iface, uint64, []byte, error) {
+	for {
+		typ, b, err := r.readBlock()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+
+		switch typ {
+		case blockTypeIDB:
+			if err := r.readIDB(b); err != nil {
+				return nil, 0, nil, err
+			}
+		case blockTypeEPB, blockTypeOPB:
+			if len(b) < 20 {
+				return nil, 0, nil, ErrInvalidFormat
+			}
+			var id int
+			if typ == blockTypeEPB {
+				id = int(r.order.Uint32(b[0:4]))
+			} else {
+				id = int(r.order.Uint16(b[0:2]))
+			}
+			ts := uint64(r.order.Uint32(b[4:8]))<<32 | uint64(r.order.Uint32(b[8:12]))
+			capLen := r.order.Uint32(b[12:16])
+			if int(capLen) > len(b)-20 {
+				return nil, 0, nil, ErrInvalidFormat
+			}
+			if ifc := r.iface(id); ifc != nil {
+				return ifc, ts, b[20 : 20+capLen], nil
+			}
+		case blockTypeSPB:
+			if len(b) < 4 {
+				return nil, 0, nil, ErrInvalidFormat
+			}
+			ifc := r.iface(0)
+			if ifc == nil {
+				continue
+			}
+			frame := b[4:]
+			if l := r.order.Uint32(b[0:4]); int(l) < len(frame) {
+				frame = frame[:l]
+			}
+			if ifc.snapLen > 0 && int(ifc.snapLen) < len(frame) {
+				frame = frame[:ifc.snapLen]
+			}
+			return ifc, 0, frame, nil
+		}
+	}
+}
+
+// iface returns the interface of the id, or nil if it is not known or its link
+// type is not supported.
+func (r *Reader) iface(id int) *iface {
+	if id >= len(r.ifaces) || !isSupportedLinkType(r.ifaces[id].linkType) {
+		return nil
+	}
+	return r.ifaces[id]
+}
+
+func (r *Reader) readIDB(b []byte) error {
+	if len(b) < 8 {
+		return ErrInvalidFormat
+	}
+	ifc := &iface{
+		linkType:    r.order.Uint16(b[0:2]),
+		snapLen:     r.order.Uint32(b[4:8]),
+		unitsPerSec: uint64(time.Second / time.Microsecond),
+	}
+
+	opts := b[8:]
+	for len(opts) >= 4 {
+		code, l := r.order.Uint16(opts[0:2]), int(r.order.Uint16(opts[2:4]))
+		if code == optionEndOfOpt || len(opts) < 4+l {
+			break
+		}
+		v := opts[4 : 4+l]
+		switch {
+		case code == optionTSResol && l == 1:
+			exp := uint(v[0] & 0x7f)
+			if v[0]&0x80 != 0 {
+				if exp > 63 {
+					return ErrInvalidFormat
+				}
+				ifc.unitsPerSec = 1 << exp
+			} else {
+				if exp > 19 {
+					return ErrInvalidFormat
+				}
+				ifc.unitsPerSec = 1
+				for i := uint(0); i < exp; i++ {
+					ifc.unitsPerSec *= 10
+				}
+			}
+		case code == optionTSOffset && l == 8:
+			ifc.offset = int64(r.order.Uint64(v))
+		}
+		if n := 4 + (l+3)/4*4; n < len(opts) {
+			opts = opts[n:]
+		} else {
+			break
+		}
+	}
+
+	r.ifaces = append(r.ifaces, ifc)
+	return nil
+}
+
+func isSupportedLinkType(linkType uint16) bool {
+	switch linkType {
+	case LinkTypeNull, LinkTypeEthernet, LinkTypeRaw, LinkTypeLoop,
+		LinkTypeLinuxSLL, LinkTypeIPv4, LinkTypeIPv6, LinkTypeLinuxSLL2:
+		return true
+	default:
+		return false
+	}
+}
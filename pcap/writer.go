@@ -0,0 +1,130 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/wmnsk/go-gtp"
+)
+
+// defaultSnapLen is the maximum length of the records in the file, which is the
+// one of libpcap. It should be larger than 65535, as the largest UDP datagram
+// with Ethernet, IP and UDP headers exceeds it.
+const defaultSnapLen = 262144
+
+// Writer writes the GTP messages into pcap file.
+//
+// The messages are written in Ethernet frames with IPv4 or IPv6 and UDP headers,
+// with the timestamps in nanoseconds. It is safe for concurrent use, so that the
+// messages sent and received by multiple connections can be written into a file.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter creates a new Writer that writes to w, and writes the file header.
+func NewWriter(w io.Writer) (*Writer, error) {
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:4], magicNanoseconds)
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], defaultSnapLen)
+	binary.LittleEndian.PutUint32(hdr[20:24], uint32(LinkTypeEthernet))
+	if _, err := w.Write(hdr); err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w}, nil
+}
+
+// WritePacket writes a Packet. The Payload is written if it is not empty, and the
+// Message is serialized otherwise.
+func (w *Writer) WritePacket(p *Packet) error {
+	payload := p.Payload
+	if len(payload) == 0 {
+		if p.Message == nil {
+			return fmt.Errorf("packet has neither payload nor message")
+		}
+		var err error
+		payload, err = gtp.Marshal(p.Message)
+		if err != nil {
+			return err
+		}
+	}
+	if p.Src == nil || p.Dst == nil {
+		return fmt.Errorf("packet has no address")
+	}
+
+	frame, ok := encodeEthernet(p.Src, p.Dst, payload)
+	if !ok {
+		return fmt.Errorf("invalid address: %s -> %s", p.Src, p.Dst)
+	}
+
+	rec := make([]byte, 16, 16+len(frame))
+	ts := p.Timestamp.UnixNano()
+	binary.LittleEndian.PutUint32(rec[0:4], uint32(ts/int64(time.Second)))
+	binary.LittleEndian.PutUint32(rec[4:8], uint32(ts%int64(time.Second)))
+	binary.LittleEndian.PutUint32(rec[8:12], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(rec[12:16], uint32(len(frame)))
+	rec = append(rec, frame...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.w.Write(rec)
+	return err
+}
+
+// WriteMessage writes a Message sent from src to dst at ts.
+//
+// The addresses should be *net.UDPAddr, as the ones returned by LocalAddr and
+// RemoteAddr of the connections in this module.
+func (w *Writer) WriteMessage(ts time.Time, src, dst net.Addr, msg gtp.Message) error {
+	s, ok := src.(*net.UDPAddr)
+	if !ok {
+		return fmt.Errorf("got %T, want *net.UDPAddr", src)
+	}
+	d, ok := dst.(*net.UDPAddr)
+	if !ok {
+		return fmt.Errorf("got %T, want *net.UDPAddr", dst)
+	}
+	return w.WritePacket(NewPacket(ts, s, d, msg))
+}
+
+// WriteCapture writes a Capture taken on a connection, which is typically passed
+// to the tap of the connection as below.
+//
+//	conn.SetTap(func(c *gtp.Capture) {
+//		if err := w.WriteCapture(c); err != nil {
+//			log.Println(err)
+//		}
+//	})
+//
+// Note that the local address is the one the connection is bound to, which may be
+// unspecified, e.g., 0.0.0.0.
+func (w *Writer) WriteCapture(c *gtp.Capture) error {
+	src, ok := c.SrcAddr().(*net.UDPAddr)
+	if !ok {
+		return fmt.Errorf("got %T, want *net.UDPAddr", c.SrcAddr())
+	}
+	dst, ok := c.DstAddr().(*net.UDPAddr)
+	if !ok {
+		return fmt.Errorf("got %T, want *net.UDPAddr", c.DstAddr())
+	}
+
+	return w.WritePacket(&Packet{
+		Timestamp: c.Timestamp,
+		Src:       src,
+		Dst:       dst,
+		Payload:   c.Payload,
+		Message:   c.Message,
+		Err:       c.Err,
+	})
+}
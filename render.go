@@ -0,0 +1,257 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	v0ie "github.com/wmnsk/go-gtp/gtpv0/ie"
+	v0msg "github.com/wmnsk/go-gtp/gtpv0/message"
+	v1ie "github.com/wmnsk/go-gtp/gtpv1/ie"
+	v1msg "github.com/wmnsk/go-gtp/gtpv1/message"
+	v2ie "github.com/wmnsk/go-gtp/gtpv2/ie"
+	v2msg "github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+const msgTypeTPDU = 0xff
+
+// RenderedMessage is a Message rendered in human readable form.
+//
+// It can be encoded into JSON with encoding/json, and into indented text tree with
+// String. The IEs are in the order of the fields in the message struct, followed
+// by the AdditionalIEs.
+type RenderedMessage struct {
+	Version  int           `json:"version"`
+	Type     uint8         `json:"type"`
+	Name     string        `json:"name"`
+	TEID     uint32        `json:"teid,omitempty"`
+	TID      string        `json:"tid,omitempty"`
+	Sequence uint32        `json:"sequence"`
+	IEs      []*RenderedIE `json:"ies,omitempty"`
+
+	// Payload is the hex-encoded payload of T-PDU.
+	Payload string `json:"payload,omitempty"`
+}
+
+// RenderedIE is an IE rendered in human readable form.
+type RenderedIE struct {
+	// Field is the name of the field in the message struct that holds the IE.
+	Field    string `json:"field,omitempty"`
+	Name     string `json:"name"`
+	Type     uint8  `json:"type"`
+	Instance uint8  `json:"instance,omitempty"`
+	Length   uint16 `json:"length"`
+
+	// Value is the value decoded with the accessor of the IE type, and Payload is
+	// the hex-encoded payload that is set instead if it cannot be decoded.
+	Value   interface{} `json:"value,omitempty"`
+	Payload string      `json:"payload,omitempty"`
+	Error   string      `json:"error,omitempty"`
+
+	ChildIEs []*RenderedIE `json:"childIEs,omitempty"`
+}
+
+// Render renders any version of Message in human readable form.
+//
+// The IE payloads are decoded with the accessors of their types, e.g., F-TEID into
+// the interface type, TEID and IP addresses, and the grouped IEs are rendered with
+// their ChildIEs.
+func Render(m Message) (*RenderedMessage, error) {
+	r := &RenderedMessage{
+		Version: m.Version(),
+		Type:    m.MessageType(),
+		Name:    m.MessageTypeName(),
+	}
+
+	switch msg := m.(type) {
+	case v0msg.Message:
+		r.TID = msg.TID()
+	case v1msg.Message:
+		r.TEID = msg.TEID()
+		r.Sequence = uint32(msg.Sequence())
+	case v2msg.Message:
+		r.TEID = msg.TEID()
+		r.Sequence = msg.Sequence()
+	default:
+		return nil, ErrInvalidVersion
+	}
+
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot render %T", m)
+	}
+	v = v.Elem()
+
+	hdr := v
+	if f := v.FieldByName("Header"); f.IsValid() && f.Kind() == reflect.Ptr && !f.IsNil() {
+		hdr = f.Elem()
+	}
+	if r.Version == 0 {
+		if seq := hdr.FieldByName("SequenceNumber"); seq.IsValid() {
+			r.Sequence = uint32(seq.Uint())
+		}
+	}
+	if r.Version < 2 && r.Type == msgTypeTPDU {
+		if p := hdr.FieldByName("Payload"); p.IsValid() {
+			r.Payload = hex.EncodeToString(p.Bytes())
+		}
+		return r, nil
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous {
+			continue
+		}
+		r.IEs = append(r.IEs, renderField(field.Name, v.Field(i))...)
+	}
+	return r, nil
+}
+
+// renderField renders the IE(s) in a field of message struct. It returns nil if
+// the field does not hold IE.
+func renderField(name string, f reflect.Value) []*RenderedIE {
+	switch f.Kind() {
+	case reflect.Ptr:
+		if f.IsNil() {
+			return nil
+		}
+		if ri, err := RenderIE(f.Interface()); err == nil {
+			ri.Field = name
+			return []*RenderedIE{ri}
+		}
+	case reflect.Slice:
+		var ris []*RenderedIE
+		for i := 0; i < f.Len(); i++ {
+			ris = append(ris, renderField(name, f.Index(i))...)
+		}
+		return ris
+	}
+	return nil
+}
+
+// RenderIE renders any version of IE in human readable form. See Render for details.
+func RenderIE(i interface{}) (*RenderedIE, error) {
+	switch i := i.(type) {
+	case *v0ie.IE:
+		r := &RenderedIE{Name: i.Name(), Type: i.Type, Length: i.Length}
+		r.setValue(i.Value())
+		r.setPayload(i.Payload)
+		return r, nil
+	case *v1ie.IE:
+		r := &RenderedIE{Name: i.Name(), Type: i.Type, Length: i.Length}
+		r.setValue(i.Value())
+		r.setPayload(i.Payload)
+		return r, nil
+	case *v2ie.IE:
+		r := &RenderedIE{Name: i.Name(), Type: i.Type, Instance: i.Instance(), Length: i.Length}
+		if i.IsGrouped() {
+			for _, child := range i.ChildIEs {
+				ri, err := RenderIE(child)
+				if err != nil {
+					return nil, err
+				}
+				r.ChildIEs = append(r.ChildIEs, ri)
+			}
+			return r, nil
+		}
+		r.setValue(i.Value())
+		r.setPayload(i.Payload)
+		return r, nil
+	default:
+		return nil, fmt.Errorf("cannot render %T as IE", i)
+	}
+}
+
+func (r *RenderedIE) setValue(v interface{}, err error) {
+	if err != nil {
+		r.Error = err.Error()
+		return
+	}
+	r.Value = v
+}
+
+// setPayload sets the payload in hex if the value is not decoded.
+func (r *RenderedIE) setPayload(b []byte) {
+	if r.Value == nil {
+		r.Payload = hex.EncodeToString(b)
+	}
+}
+
+// RenderText renders Message into indented text tree.
+func RenderText(m Message) (string, error) {
+	r, err := Render(m)
+	if err != nil {
+		return "", err
+	}
+	return r.String(), nil
+}
+
+// RenderJSON renders Message into JSON.
+func RenderJSON(m Message) ([]byte, error) {
+	r, err := Render(m)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(r)
+}
+
+// String returns the RenderedMessage in indented text tree.
+func (r *RenderedMessage) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (Version: %d, Type: %d", r.Name, r.Version, r.Type)
+	if r.Version == 0 {
+		fmt.Fprintf(&b, ", TID: %s", r.TID)
+	} else {
+		fmt.Fprintf(&b, ", TEID: %#x", r.TEID)
+	}
+	fmt.Fprintf(&b, ", Sequence: %#x)\n", r.Sequence)
+
+	if r.Payload != "" {
+		fmt.Fprintf(&b, "  Payload: %s\n", r.Payload)
+	}
+	for _, ri := range r.IEs {
+		ri.writeTo(&b, 1)
+	}
+	return b.String()
+}
+
+// String returns the RenderedIE in indented text tree.
+func (r *RenderedIE) String() string {
+	var b strings.Builder
+	r.writeTo(&b, 0)
+	return b.String()
+}
+
+func (r *RenderedIE) writeTo(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	if r.Field != "" && r.Field != r.Name {
+		fmt.Fprintf(b, "%s: ", r.Field)
+	}
+	fmt.Fprintf(b, "%s (Type: %d, Instance: %d, Length: %d)", r.Name, r.Type, r.Instance, r.Length)
+
+	switch {
+	case r.Error != "":
+		fmt.Fprintf(b, ": <%s> %s", r.Error, r.Payload)
+	case r.Value != nil:
+		v, err := json.Marshal(r.Value)
+		if err != nil {
+			fmt.Fprintf(b, ": %v", r.Value)
+		} else {
+			fmt.Fprintf(b, ": %s", v)
+		}
+	case r.Payload != "":
+		fmt.Fprintf(b, ": %s", r.Payload)
+	}
+	b.WriteString("\n")
+
+	for _, child := range r.ChildIEs {
+		child.writeTo(b, depth+1)
+	}
+}
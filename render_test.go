@@ -0,0 +1,105 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	v0msg "github.com/wmnsk/go-gtp/gtpv0/message"
+	v1ie "github.com/wmnsk/go-gtp/gtpv1/ie"
+	v1msg "github.com/wmnsk/go-gtp/gtpv1/message"
+	v2ie "github.com/wmnsk/go-gtp/gtpv2/ie"
+	v2msg "github.com/wmnsk/go-gtp/gtpv2/message"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		description string
+		msg         Message
+		text        string
+		json        string
+	}{
+		{
+			"GTPv0 Echo Request",
+			v0msg.NewEchoRequest(v0flow.seq, v0flow.label, v0flow.tid),
+			"Echo Request (Version: 0, Type: 1, TID: 1234567890123455, Sequence: 0x1)\n",
+			`{"version":0,"type":1,"name":"Echo Request","tid":"1234567890123455","sequence":1}`,
+		}, {
+			"GTPv1 Create PDP Context Request",
+			v1msg.NewCreatePDPContextRequest(
+				0, 1,
+				v1ie.NewIMSI("123451234567890"),
+				v1ie.NewTEIDCPlane(0xdeadbeef),
+				v1ie.NewEndUserAddress("10.0.0.1"),
+			),
+			"Create PDP Context Request (Version: 1, Type: 16, TEID: 0x0, Sequence: 0x1)\n" +
+				"  IMSI (Type: 2, Instance: 0, Length: 0): \"123451234567890\"\n" +
+				"  TEIDCPlane (Type: 17, Instance: 0, Length: 0): 3735928559\n" +
+				"  EndUserAddress (Type: 128, Instance: 0, Length: 6): " +
+				`{"IPAddress":"10.0.0.1","PDPTypeNumber":33,"PDPTypeOrganization":241}` + "\n",
+			`{"version":1,"type":16,"name":"Create PDP Context Request","sequence":1,"ies":[` +
+				`{"field":"IMSI","name":"IMSI","type":2,"length":0,"value":"123451234567890"},` +
+				`{"field":"TEIDCPlane","name":"TEIDCPlane","type":17,"length":0,"value":3735928559},` +
+				`{"field":"EndUserAddress","name":"EndUserAddress","type":128,"length":6,` +
+				`"value":{"IPAddress":"10.0.0.1","PDPTypeNumber":33,"PDPTypeOrganization":241}}]}`,
+		}, {
+			"GTPv1 T-PDU",
+			v1msg.NewTPDU(0x11223344, []byte{0xde, 0xad, 0xbe, 0xef}),
+			"T-PDU (Version: 1, Type: 255, TEID: 0x11223344, Sequence: 0x0)\n  Payload: deadbeef\n",
+			`{"version":1,"type":255,"name":"T-PDU","teid":287454020,"sequence":0,"payload":"deadbeef"}`,
+		}, {
+			"GTPv2 Create Session Response",
+			v2msg.NewCreateSessionResponse(
+				0x11223344, 0x000001,
+				v2ie.NewCause(16, 0, 0, 0, nil),
+				v2ie.NewFullyQualifiedTEID(11, 0xffffffff, "1.1.1.1", "").WithInstance(0),
+				v2ie.NewBearerContext(
+					v2ie.NewEPSBearerID(5),
+					v2ie.NewChargingID(1),
+				),
+				v2ie.New(0xfa, 0, []byte{0x01}),
+			),
+			"Create Session Response (Version: 2, Type: 33, TEID: 0x11223344, Sequence: 0x1)\n" +
+				"  Cause (Type: 2, Instance: 0, Length: 2): " +
+				`{"BCE":false,"CS":false,"Cause":16,"PCE":false}` + "\n" +
+				"  SenderFTEIDC: FullyQualifiedTEID (Type: 87, Instance: 0, Length: 9): " +
+				`{"Flags":128,"InterfaceType":11,"TEIDGREKey":4294967295,"IPv4Address":"1.1.1.1","IPv6Address":""}` + "\n" +
+				"  BearerContextsCreated: BearerContext (Type: 93, Instance: 0, Length: 13)\n" +
+				"    EPSBearerID (Type: 73, Instance: 0, Length: 1): 5\n" +
+				"    ChargingID (Type: 94, Instance: 0, Length: 4): 1\n" +
+				"  AdditionalIEs: Unknown(250) (Type: 250, Instance: 0, Length: 1): 01\n",
+			`{"version":2,"type":33,"name":"Create Session Response","teid":287454020,"sequence":1,"ies":[` +
+				`{"field":"Cause","name":"Cause","type":2,"length":2,"value":{"BCE":false,"CS":false,"Cause":16,"PCE":false}},` +
+				`{"field":"SenderFTEIDC","name":"FullyQualifiedTEID","type":87,"length":9,` +
+				`"value":{"Flags":128,"InterfaceType":11,"TEIDGREKey":4294967295,"IPv4Address":"1.1.1.1","IPv6Address":""}},` +
+				`{"field":"BearerContextsCreated","name":"BearerContext","type":93,"length":13,"childIEs":[` +
+				`{"name":"EPSBearerID","type":73,"length":1,"value":5},` +
+				`{"name":"ChargingID","type":94,"length":4,"value":1}]},` +
+				`{"field":"AdditionalIEs","name":"Unknown(250)","type":250,"length":1,"payload":"01"}]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			text, err := RenderText(c.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(text, c.text); diff != "" {
+				t.Error(diff)
+			}
+
+			j, err := RenderJSON(c.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(string(j), c.json); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
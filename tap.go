@@ -0,0 +1,126 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gtp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Direction is the direction of the captured datagram.
+type Direction uint8
+
+// Direction definitions.
+const (
+	DirectionReceived Direction = iota + 1
+	DirectionSent
+)
+
+// String returns the name of Direction.
+func (d Direction) String() string {
+	switch d {
+	case DirectionReceived:
+		return "received"
+	case DirectionSent:
+		return "sent"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(d))
+	}
+}
+
+// Capture is a datagram sent or received on a connection, passed to TapFunc.
+type Capture struct {
+	Direction  Direction
+	Timestamp  time.Time
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+
+	// Payload is the datagram, and Message is the Message decoded from it.
+	// Message is nil and Err is set if it cannot be decoded.
+	Payload []byte
+	Message Message
+	Err     error
+}
+
+// NewCapture creates a new Capture of the datagram b, decoding it as Message.
+//
+// b is copied, so that the caller can reuse it.
+func NewCapture(dir Direction, laddr, raddr net.Addr, b []byte) *Capture {
+	c := &Capture{
+		Direction:  dir,
+		Timestamp:  time.Now(),
+		LocalAddr:  laddr,
+		RemoteAddr: raddr,
+		Payload:    make([]byte, len(b)),
+	}
+	copy(c.Payload, b)
+
+	msg, err := Parse(c.Payload)
+	if err != nil {
+		c.Err = err
+	} else {
+		c.Message = msg
+	}
+	return c
+}
+
+// SrcAddr returns the source address of the datagram.
+func (c *Capture) SrcAddr() net.Addr {
+	if c.Direction == DirectionSent {
+		return c.LocalAddr
+	}
+	return c.RemoteAddr
+}
+
+// DstAddr returns the destination address of the datagram.
+func (c *Capture) DstAddr() net.Addr {
+	if c.Direction == DirectionSent {
+		return c.RemoteAddr
+	}
+	return c.LocalAddr
+}
+
+// TapFunc is a function that receives the Captures of the datagrams sent and
+// received on a connection. It is called synchronously on the path of sending
+// or receiving, so it should return quickly.
+type TapFunc func(c *Capture)
+
+// Recorder keeps the Captures in memory. It is useful to check the messages
+// exchanged in tests, by passing its Tap method to the connections.
+type Recorder struct {
+	mu       sync.Mutex
+	captures []*Capture
+}
+
+// NewRecorder creates a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Tap records c. It can be used as TapFunc.
+func (r *Recorder) Tap(c *Capture) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.captures = append(r.captures, c)
+}
+
+// Captures returns the Captures recorded so far in the order of recording.
+func (r *Recorder) Captures() []*Capture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	captures := make([]*Capture, len(r.captures))
+	copy(captures, r.captures)
+	return captures
+}
+
+// Reset discards the Captures recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.captures = nil
+}
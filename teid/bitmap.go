@@ -0,0 +1,140 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package teid
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const (
+	// wordShift is the number of bits to get the index of word from TEID.
+	wordShift = 6
+
+	// blockShift is the number of bits to get the index of block, which is
+	// a group of 64 words, from TEID.
+	blockShift = wordShift + 6
+	blockWords = 1 << (blockShift - wordShift)
+)
+
+// bitmap is a sparse bitmap of the TEIDs in use.
+//
+// Only the words that have any TEID in use are kept, so that the memory usage is
+// proportional to the number of TEIDs in use rather than the size of the range.
+// The number of full words is counted for each block to skip the blocks with no
+// free TEID quickly.
+//
+// The values are handled as uint64 to avoid overflow at the end of the range.
+type bitmap struct {
+	first, last uint64
+	words       map[uint64]uint64
+	fullWords   map[uint64]int
+	count       uint64
+}
+
+func newBitmap(first, last uint32) (*bitmap, error) {
+	// TEID=0 is never allocated.
+	if first == 0 {
+		first = 1
+	}
+	if first > last {
+		return nil, fmt.Errorf("invalid TEID range: %#x-%#x", first, last)
+	}
+
+	return &bitmap{
+		first:     uint64(first),
+		last:      uint64(last),
+		words:     map[uint64]uint64{},
+		fullWords: map[uint64]int{},
+	}, nil
+}
+
+func (b *bitmap) size() uint64 {
+	return b.last - b.first + 1
+}
+
+// set marks v as used. It returns false if v is already used.
+func (b *bitmap) set(v uint64) bool {
+	idx := v >> wordShift
+	mask := uint64(1) << (v & 63)
+
+	w := b.words[idx]
+	if w&mask != 0 {
+		return false
+	}
+
+	w |= mask
+	b.words[idx] = w
+	if w == ^uint64(0) {
+		b.fullWords[v>>blockShift]++
+	}
+	b.count++
+	return true
+}
+
+// clear marks v as free. The word is removed when no TEID in it is used.
+func (b *bitmap) clear(v uint64) {
+	idx := v >> wordShift
+	mask := uint64(1) << (v & 63)
+
+	w, ok := b.words[idx]
+	if !ok || w&mask == 0 {
+		return
+	}
+
+	if w == ^uint64(0) {
+		block := v >> blockShift
+		if b.fullWords[block]--; b.fullWords[block] == 0 {
+			delete(b.fullWords, block)
+		}
+	}
+
+	w &^= mask
+	if w == 0 {
+		delete(b.words, idx)
+	} else {
+		b.words[idx] = w
+	}
+	b.count--
+}
+
+// findFree returns the first free value at or after from, wrapping around at the
+// end of the range.
+func (b *bitmap) findFree(from uint64) (uint64, bool) {
+	if b.count == b.size() {
+		return 0, false
+	}
+
+	if v, ok := b.nextFree(from); ok {
+		return v, true
+	}
+	return b.nextFree(b.first)
+}
+
+// nextFree returns the first free value in the range of from to the last.
+func (b *bitmap) nextFree(from uint64) (uint64, bool) {
+	for p := from; p <= b.last; {
+		// skip the block with no free TEID.
+		if b.fullWords[p>>blockShift] == blockWords {
+			p = (p>>blockShift + 1) << blockShift
+			continue
+		}
+
+		w, ok := b.words[p>>wordShift]
+		if !ok {
+			return p, true
+		}
+
+		if free := ^w >> (p & 63); free != 0 {
+			v := p + uint64(bits.TrailingZeros64(free))
+			if v > b.last {
+				return 0, false
+			}
+			return v, true
+		}
+		p = (p | 63) + 1
+	}
+	return 0, false
+}
@@ -0,0 +1,193 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+// Package teid provides the allocators of TEID(Tunnel Endpoint Identifier), which
+// is shared by GTPv1 and GTPv2.
+//
+// The used TEIDs are tracked with a sparse bitmap, so that allocation and release
+// are done in constant time in the usual cases, and the allocation never fails
+// as long as any TEID is available in the range.
+package teid
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+var (
+	// ErrExhausted indicates that no TEID is available in the range of Allocator.
+	ErrExhausted = errors.New("no TEID available")
+
+	// ErrInUse indicates that the TEID is already in use.
+	ErrInUse = errors.New("TEID is already in use")
+
+	// ErrOutOfRange indicates that the TEID is out of the range of Allocator.
+	ErrOutOfRange = errors.New("TEID is out of range")
+)
+
+// Allocator allocates TEIDs that are unique within the Allocator.
+//
+// TEID=0 is never allocated, as it has special meaning in GTP.
+// All the methods are safe for concurrent use.
+type Allocator interface {
+	// Allocate returns a new TEID that is not in use.
+	// It returns ErrExhausted if no TEID is available.
+	Allocate() (uint32, error)
+
+	// Reserve marks teid as in use, which is useful to let Allocator know the
+	// TEIDs allocated in the other way, e.g., the ones restored after restart.
+	// It returns ErrInUse if teid is already in use, and ErrOutOfRange if teid
+	// is out of the range of Allocator.
+	Reserve(teid uint32) error
+
+	// Release makes teid available again. It does nothing if teid is not in use.
+	Release(teid uint32)
+
+	// Count returns the number of TEIDs in use.
+	Count() int
+}
+
+// allocator is the Allocator with the strategy to choose the position to start
+// looking for a free TEID.
+type allocator struct {
+	mu sync.Mutex
+	*bitmap
+	start func() uint64
+	next  uint64
+}
+
+// NewRandomAllocator creates a new Allocator that allocates TEIDs randomly within
+// the range of first to last, both inclusive.
+//
+// The TEIDs are chosen with the pseudo-random generator initialized with seed, which
+// makes the sequence of the allocated TEIDs deterministic with the same seed.
+func NewRandomAllocator(first, last uint32, seed int64) (Allocator, error) {
+	b, err := newBitmap(first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	a := &allocator{bitmap: b}
+	a.start = func() uint64 {
+		return b.first + uint64(rnd.Int63n(int64(b.size())))
+	}
+	return a, nil
+}
+
+// NewDefaultAllocator creates a new Allocator that allocates TEIDs randomly from the
+// whole range of TEID, which is used by gtpv1.UPlaneConn and gtpv2.Conn by default.
+//
+// The TEIDs are chosen with crypto/rand so that they are not predictable by the
+// others. Use NewRandomAllocator for the deterministic allocation.
+func NewDefaultAllocator() Allocator {
+	// never fails with the whole range.
+	b, _ := newBitmap(1, 0xffffffff)
+
+	a := &allocator{bitmap: b, next: b.first}
+	a.start = func() uint64 {
+		var r [8]byte
+		if _, err := crand.Read(r[:]); err != nil {
+			// the next one to the last allocated is still available.
+			return a.next
+		}
+		return b.first + binary.BigEndian.Uint64(r[:])%b.size()
+	}
+	return a
+}
+
+// NewSequentialAllocator creates a new Allocator that allocates TEIDs sequentially
+// within the range of first to last, both inclusive.
+//
+// The allocation starts from first and wraps around at last, skipping the ones
+// in use. The released TEIDs are not reused until the allocation wraps around.
+func NewSequentialAllocator(first, last uint32) (Allocator, error) {
+	b, err := newBitmap(first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &allocator{bitmap: b, next: b.first}
+	a.start = func() uint64 {
+		return a.next
+	}
+	return a, nil
+}
+
+// Partition returns the range of TEIDs for the node specified by nodeID, with
+// the most significant nodeBits bits of TEID used to identify the node.
+//
+// This is useful in the deployments with multiple instances sharing the same
+// address, by giving each instance an Allocator with its own range to avoid the
+// conflicts without coordination.
+func Partition(nodeID uint32, nodeBits uint) (first, last uint32, err error) {
+	if nodeBits == 0 || nodeBits > 16 {
+		return 0, 0, fmt.Errorf("nodeBits should be between 1 and 16: %d", nodeBits)
+	}
+	if nodeID >= 1<<nodeBits {
+		return 0, 0, fmt.Errorf("nodeID %d does not fit in %d bits", nodeID, nodeBits)
+	}
+
+	shift := 32 - nodeBits
+	first = nodeID << shift
+	last = first | (1<<shift - 1)
+	return first, last, nil
+}
+
+// Allocate returns a new TEID that is not in use.
+func (a *allocator) Allocate() (uint32, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	v, ok := a.findFree(a.start())
+	if !ok {
+		return 0, ErrExhausted
+	}
+	a.set(v)
+
+	a.next = v + 1
+	if a.next > a.last {
+		a.next = a.first
+	}
+	return uint32(v), nil
+}
+
+// Reserve marks teid as in use.
+func (a *allocator) Reserve(teid uint32) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	v := uint64(teid)
+	if v < a.first || v > a.last {
+		return ErrOutOfRange
+	}
+	if !a.set(v) {
+		return ErrInUse
+	}
+	return nil
+}
+
+// Release makes teid available again.
+func (a *allocator) Release(teid uint32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	v := uint64(teid)
+	if v < a.first || v > a.last {
+		return
+	}
+	a.clear(v)
+}
+
+// Count returns the number of TEIDs in use.
+func (a *allocator) Count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return int(a.count)
+}
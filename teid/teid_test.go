@@ -0,0 +1,199 @@
+// Copyright 2019-2021 go-gtp authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package teid_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wmnsk/go-gtp/teid"
+)
+
+func allocateN(t *testing.T, a teid.Allocator, n int) []uint32 {
+	t.Helper()
+
+	teids := make([]uint32, n)
+	for i := range teids {
+		v, err := a.Allocate()
+		if err != nil {
+			t.Fatalf("failed to allocate %d-th TEID: %v", i, err)
+		}
+		teids[i] = v
+	}
+	return teids
+}
+
+func TestSequentialAllocator(t *testing.T) {
+	a, err := teid.NewSequentialAllocator(0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// TEID=0 is never allocated.
+	if diff := cmp.Diff(allocateN(t, a, 4), []uint32{1, 2, 3, 4}); diff != "" {
+		t.Error(diff)
+	}
+	if _, err := a.Allocate(); !errors.Is(err, teid.ErrExhausted) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	a.Release(2)
+	a.Release(3)
+	if got := a.Count(); got != 2 {
+		t.Errorf("wrong count: %d", got)
+	}
+	if diff := cmp.Diff(allocateN(t, a, 2), []uint32{2, 3}); diff != "" {
+		t.Error(diff)
+	}
+
+	if err := a.Reserve(1); !errors.Is(err, teid.ErrInUse) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := a.Reserve(5); !errors.Is(err, teid.ErrOutOfRange) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSequentialAllocatorAcrossBlocks(t *testing.T) {
+	const first, last = 0x1fff0, 0x30010
+	a, err := teid.NewSequentialAllocator(first, last)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for v := uint32(first); v <= last; v++ {
+		if v == 0x20000 {
+			continue
+		}
+		if err := a.Reserve(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the only free one is at the beginning of a block.
+	v, err := a.Allocate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 0x20000 {
+		t.Errorf("wrong TEID: %#x", v)
+	}
+	if _, err := a.Allocate(); !errors.Is(err, teid.ErrExhausted) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// skips the full blocks to find the one released.
+	a.Release(last)
+	if v, err := a.Allocate(); err != nil || v != last {
+		t.Errorf("wrong TEID: %#x, %v", v, err)
+	}
+}
+
+func TestDefaultAllocator(t *testing.T) {
+	// not deterministic, unlike NewRandomAllocator with the same seed.
+	teids := allocateN(t, teid.NewDefaultAllocator(), 1000)
+	if diff := cmp.Diff(teids, allocateN(t, teid.NewDefaultAllocator(), 1000)); diff == "" {
+		t.Error("got the same TEIDs from two allocators")
+	}
+
+	seen := map[uint32]bool{}
+	for _, v := range teids {
+		if v == 0 || seen[v] {
+			t.Fatalf("invalid or duplicated TEID: %#x", v)
+		}
+		seen[v] = true
+	}
+}
+
+func TestRandomAllocator(t *testing.T) {
+	a1, err := teid.NewRandomAllocator(1, 0xffffffff, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := teid.NewRandomAllocator(1, 0xffffffff, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deterministic with the same seed.
+	teids := allocateN(t, a1, 1000)
+	if diff := cmp.Diff(teids, allocateN(t, a2, 1000)); diff != "" {
+		t.Error(diff)
+	}
+
+	seen := map[uint32]bool{}
+	for _, v := range teids {
+		if v == 0 || seen[v] {
+			t.Fatalf("invalid or duplicated TEID: %#x", v)
+		}
+		seen[v] = true
+	}
+
+	// all the TEIDs are allocated in a small range.
+	small, err := teid.NewRandomAllocator(100, 199, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen = map[uint32]bool{}
+	for _, v := range allocateN(t, small, 100) {
+		if v < 100 || v > 199 || seen[v] {
+			t.Fatalf("invalid or duplicated TEID: %d", v)
+		}
+		seen[v] = true
+	}
+	if _, err := small.Allocate(); !errors.Is(err, teid.ErrExhausted) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	cases := []struct {
+		nodeID      uint32
+		nodeBits    uint
+		first, last uint32
+	}{
+		{0, 1, 0x00000000, 0x7fffffff},
+		{1, 1, 0x80000000, 0xffffffff},
+		{3, 4, 0x30000000, 0x3fffffff},
+		{0xff, 8, 0xff000000, 0xffffffff},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%d/%d", c.nodeID, c.nodeBits), func(t *testing.T) {
+			first, last, err := teid.Partition(c.nodeID, c.nodeBits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first != c.first || last != c.last {
+				t.Errorf("wrong range: %#x-%#x", first, last)
+			}
+		})
+	}
+
+	if _, _, err := teid.Partition(2, 1); err == nil {
+		t.Error("should fail with too large nodeID")
+	}
+}
+
+func BenchmarkRandomAllocator(b *testing.B) {
+	a := teid.NewDefaultAllocator()
+	for i := 0; i < 500000; i++ {
+		if _, err := a.Allocate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, err := a.Allocate()
+		if err != nil {
+			b.Fatal(err)
+		}
+		a.Release(v)
+	}
+}
@@ -8,6 +8,7 @@ package utils
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"io"
 )
 
 // StrToSwappedBytes returns swapped bits from a byte.
@@ -114,6 +115,10 @@ func EncodePLMN(mcc, mnc string) ([]byte, error) {
 
 // DecodePLMN decodes BCD-encoded bytes into MCC and MNC.
 func DecodePLMN(b []byte) (mcc, mnc string, err error) {
+	if len(b) < 3 {
+		return "", "", io.ErrUnexpectedEOF
+	}
+
 	raw := hex.EncodeToString(b)
 	mcc = string(raw[1]) + string(raw[0]) + string(raw[3])
 	mnc = string(raw[5]) + string(raw[4])